@@ -1,12 +1,45 @@
 package api
 
 import (
+	"time"
+
 	"github.com/gin-gonic/gin"
 	"github.com/varadekd/card-game/controller"
+	"github.com/varadekd/card-game/middleware"
 )
 
-func SetupDeckApi(r *gin.Engine) {
-	r.POST("/deck/new", controller.GeneratedDeck)
-	r.GET("/deck/:id", controller.OpenDeck)
-	r.PUT("/deck/:id/draw-cards", controller.DrawCardsFromDeck)
+// DeckOptions holds the limits and keys of the deck routes.
+// Deck creation and drawing are limited separately per client, a nil limiter disables the limit.
+// Both honour the Idempotency-Key header, a nil store disables it. Replayed responses do not
+// count against the limits. DealerKey is accepted on privileged routes, an empty key is never accepted.
+type DeckOptions struct {
+	CreateLimiter *middleware.RateLimiter
+	DrawLimiter   *middleware.RateLimiter
+	Idempotency   *middleware.IdempotencyStore
+	DealerKey     string
+}
+
+// V1Deprecated is the date the v1 deck routes were deprecated in favour of the /v2 routes.
+var V1Deprecated = time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC)
+
+// SetupDeckApi registers the deck routes, their latency is recorded in the metrics.
+// The v1 routes under /deck keep the JSON names of the first release and are deprecated,
+// the v2 routes under /v2/deck send the same decks with the corrected names, see model.DeckV1.
+// Both versions share the limits and the idempotency keys of the caller.
+func SetupDeckApi(r *gin.Engine, options DeckOptions) {
+	registerDeckRoutes(r.Group("/deck", middleware.APIVersion(1), middleware.Deprecated(V1Deprecated, "/v2"), middleware.RequestMetrics()), options)
+	registerDeckRoutes(r.Group("/v2/deck", middleware.APIVersion(2), middleware.RequestMetrics()), options)
+}
+
+func registerDeckRoutes(decks *gin.RouterGroup, options DeckOptions) {
+	decks.GET("", controller.ListDecks)
+	decks.DELETE("", controller.DeleteDecksByGame)
+	decks.POST("/new", middleware.Idempotency(options.Idempotency), middleware.RateLimit(options.CreateLimiter), controller.GeneratedDeck)
+	decks.GET("/:id", controller.OpenDeck)
+	decks.PUT("/:id/draw-cards", middleware.Idempotency(options.Idempotency), middleware.RateLimit(options.DrawLimiter), controller.DrawCardsFromDeck)
+	decks.PUT("/:id/discard-cards", controller.DiscardCardsFromDeck)
+	decks.DELETE("/:id", controller.DeleteDeck)
+	decks.POST("/:id/clone", middleware.RateLimit(options.CreateLimiter), controller.CloneDeck)
+	decks.POST("/:id/reset", controller.ResetDeck)
+	decks.GET("/:id/peek", middleware.RequireDealer(options.DealerKey), controller.PeekDeck)
 }
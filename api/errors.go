@@ -0,0 +1,11 @@
+package api
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/varadekd/card-game/controller"
+)
+
+// SetupErrorsApi registers the route exposing the error catalogue.
+func SetupErrorsApi(r *gin.Engine) {
+	r.GET("/errors", controller.ListErrors)
+}
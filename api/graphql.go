@@ -0,0 +1,19 @@
+package api
+
+import (
+	"github.com/gin-gonic/gin"
+	graphql "github.com/graph-gophers/graphql-go"
+	"github.com/varadekd/card-game/auth"
+	"github.com/varadekd/card-game/gqlapi"
+)
+
+// SetupGraphQLSubscriptions registers the WebSocket route of the GraphQL subscriptions. It must be
+// registered before the authentication middleware, the connection authenticates itself.
+func SetupGraphQLSubscriptions(r *gin.Engine, schema *graphql.Schema, keys *auth.KeyStore, signer *auth.JWTSigner) {
+	r.GET("/graphql", gqlapi.Subscriptions(schema, keys, signer))
+}
+
+// SetupGraphQLApi registers the route executing GraphQL queries and mutations.
+func SetupGraphQLApi(r *gin.Engine, schema *graphql.Schema) {
+	r.POST("/graphql", gqlapi.Handler(schema))
+}
@@ -0,0 +1,16 @@
+package api
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/varadekd/card-game/controller"
+	"github.com/varadekd/card-game/health"
+	"github.com/varadekd/card-game/metrics"
+)
+
+// SetupHealthApi registers the liveness, readiness, build info and metrics routes.
+func SetupHealthApi(r *gin.Engine, checks []health.Check) {
+	r.GET("/healthz", controller.Healthz)
+	r.GET("/readyz", controller.Readyz(checks))
+	r.GET("/version", controller.Version)
+	r.GET("/metrics", metrics.Handler(metrics.Default))
+}
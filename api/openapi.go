@@ -0,0 +1,317 @@
+package api
+
+import (
+	_ "embed"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	swaggerFiles "github.com/swaggo/files/v2"
+	"github.com/varadekd/card-game/buildinfo"
+	"github.com/varadekd/card-game/gqlapi"
+	"github.com/varadekd/card-game/health"
+	"github.com/varadekd/card-game/helper"
+	"github.com/varadekd/card-game/middleware"
+	"github.com/varadekd/card-game/model"
+	"github.com/varadekd/card-game/openapi"
+	"github.com/varadekd/card-game/store"
+)
+
+//go:embed docs.html
+var docsPage []byte
+
+// docsAssets are the Swagger UI files loaded by the documentation page.
+var docsAssets = map[string]bool{
+	"swagger-ui.css":       true,
+	"swagger-ui-bundle.js": true,
+}
+
+// authErrors can be sent by every route behind authentication.
+var authErrors = []helper.APIError{
+	helper.ErrAPIKeyMissing,
+	helper.ErrAPIKeyInvalid,
+	helper.ErrTokenMissing,
+	helper.ErrTokenInvalid,
+	helper.ErrTokenExpired,
+}
+
+// deckErrors can be sent by every route working on a single deck.
+var deckErrors = append([]helper.APIError{
+	helper.ErrDeckIDInvalid,
+	helper.ErrDeckForbidden,
+	helper.ErrDeckNotFound,
+	helper.ErrDeckExpired,
+	helper.ErrInternal,
+}, authErrors...)
+
+var maxIdempotencyKeyLength = middleware.MaxIdempotencyKeyLength
+
+// idempotencyKey is the header making creation and drawing safe to retry.
+var idempotencyKey = openapi.Parameter{
+	Name: middleware.IdempotencyKeyHeader, In: "header",
+	Description: "Sending the request again with the same key replays the first response instead of running it twice. " +
+		"Responses are kept for IDEMPOTENCY_TTL, reusing a key with another payload is rejected.",
+	Schema: &openapi.Schema{Type: "string", MaxLength: &maxIdempotencyKeyLength},
+}
+
+// ifMatch makes a deck mutation conditional on the version of the deck.
+var ifMatch = openapi.Parameter{
+	Name: "If-Match", In: "header",
+	Description: "ETag of the deck as returned when opening it, the request fails with 412 when the deck changed since.",
+	Schema:      &openapi.Schema{Type: "string"},
+}
+
+func withErrors(errs []helper.APIError, more ...helper.APIError) []helper.APIError {
+	return append(append([]helper.APIError{}, errs...), more...)
+}
+
+// Operations describes every route of the api, the OpenAPI document is generated from it.
+// A route registered without an entry here fails the tests.
+var Operations = concatOperations(serviceOperations, deckOperations(deckV1), deckOperations(deckV2), graphqlOperations)
+
+// serviceOperations describes the routes of the service itself: health, docs and tokens.
+var serviceOperations = []openapi.Operation{
+	{
+		ID: "ping", Method: http.MethodGet, Path: "/ping", Tags: []string{"health"},
+		Summary: "Checks that the server is up",
+		Status:  http.StatusOK, Raw: map[string]string{}, Public: true,
+	},
+	{
+		ID: "healthz", Method: http.MethodGet, Path: "/healthz", Tags: []string{"health"},
+		Summary: "Liveness probe, answers as long as the process serves requests",
+		Status:  http.StatusOK, Data: map[string]string{}, Public: true,
+	},
+	{
+		ID: "readyz", Method: http.MethodGet, Path: "/readyz", Tags: []string{"health"},
+		Summary:     "Readiness probe",
+		Description: "Checks that the default deck can be read, the deck store is writable and the sweeper is running. The report of every check is sent in data, also with 503.",
+		Status:      http.StatusOK, Data: health.Report{}, Public: true,
+		Errors: []helper.APIError{helper.ErrNotReady},
+	},
+	{
+		ID: "getVersion", Method: http.MethodGet, Path: "/version", Tags: []string{"health"},
+		Summary:     "Returns the build metadata",
+		Description: "Version, commit and date are injected at link time, see the buildinfo package.",
+		Status:      http.StatusOK, Data: buildinfo.Info{}, Public: true,
+	},
+	{
+		ID: "getMetrics", Method: http.MethodGet, Path: "/metrics", Tags: []string{"health"},
+		Summary:     "Returns the usage metrics in the Prometheus text format",
+		Description: "Request latency of the deck routes, decks created, cards drawn, draw conflicts, active decks and store latency.",
+		Status:      http.StatusOK, Raw: "", ContentType: "text/plain", Public: true,
+	},
+	{
+		ID: "listErrors", Method: http.MethodGet, Path: "/errors", Tags: []string{"errors"},
+		Summary: "Lists every error code the api can send",
+		Status:  http.StatusOK, Data: helper.ErrorCatalogue, Public: true,
+	},
+	{
+		ID: "getOpenApi", Method: http.MethodGet, Path: "/openapi.json", Tags: []string{"docs"},
+		Summary: "Returns this OpenAPI document",
+		Status:  http.StatusOK, Raw: map[string]interface{}{}, Public: true,
+	},
+	{
+		ID: "getDocs", Method: http.MethodGet, Path: "/docs", Tags: []string{"docs"},
+		Summary: "Browsable documentation of the api",
+		Status:  http.StatusOK, Raw: "", ContentType: "text/html", Public: true,
+	},
+	{
+		ID: "getDocsAsset", Method: http.MethodGet, Path: "/docs/assets/:file", Tags: []string{"docs"},
+		Summary:     "Serves the Swagger UI files of the documentation",
+		Description: "The files of Swagger UI 5.18.2 are embedded in the application, only swagger-ui.css and swagger-ui-bundle.js are served.",
+		Status:      http.StatusOK, Raw: "", ContentType: "application/javascript", Public: true,
+	},
+	{
+		ID: "issuePlayerToken", Method: http.MethodPost, Path: "/token", Tags: []string{"tokens"},
+		Summary:     "Issues a player token",
+		Description: "Only available to API key holders when JWT_SIGNING_KEY_FILE is set.",
+		Body:        model.IssueTokenPayload{},
+		Status:      http.StatusCreated, Data: model.PlayerToken{},
+		Errors: withErrors(authErrors, helper.ErrInvalidPayload, helper.ErrAPIKeyRequired, helper.ErrTokensDisabled, helper.ErrInternal),
+	},
+}
+
+// graphqlOperations describes the GraphQL endpoint.
+var graphqlOperations = []openapi.Operation{
+	{
+		ID: "graphql", Method: http.MethodPost, Path: "/graphql", Tags: []string{"graphql"},
+		Summary:     "Executes a GraphQL query or mutation",
+		Description: "Errors of the catalogue are sent in the errors of the response with their code in the extensions.",
+		Body:        gqlapi.Request{},
+		Status:      http.StatusOK, Raw: map[string]interface{}{},
+		Errors: withErrors(authErrors, helper.ErrInvalidPayload),
+	},
+	{
+		ID: "graphqlSubscriptions", Method: http.MethodGet, Path: "/graphql", Tags: []string{"graphql"},
+		Summary: "Opens a WebSocket for GraphQL subscriptions",
+		Description: "Speaks the graphql-transport-ws subprotocol, the credentials are sent in the connection_init payload " +
+			"with the Authorization and X-API-Key keys.",
+		Status: http.StatusSwitchingProtocols, Raw: "", ContentType: "text/plain", Public: true,
+		Errors: []helper.APIError{helper.ErrInvalidPayload},
+	},
+}
+
+// deckVersion holds what differs between the versions of the deck routes.
+type deckVersion struct {
+	prefix     string // prefix of the routes
+	idSuffix   string // keeps the operation IDs unique across versions
+	deck       interface{}
+	cards      interface{}
+	page       interface{}
+	deprecated bool
+}
+
+// The v1 routes keep the operation IDs of the first release so generated clients still build.
+var (
+	deckV1 = deckVersion{prefix: "/deck", deck: model.DeckV1{}, cards: []model.CardV1{}, page: store.DeckPageV1{}, deprecated: true}
+	deckV2 = deckVersion{prefix: "/v2/deck", idSuffix: "V2", deck: model.Deck{}, cards: []model.Card{}, page: store.DeckPage{}}
+)
+
+// deckOperations describes the deck routes of a version of the api.
+func deckOperations(v deckVersion) []openapi.Operation {
+	return []openapi.Operation{
+		{
+			ID: "listDecks" + v.idSuffix, Method: http.MethodGet, Path: v.prefix, Tags: []string{"decks"},
+			Summary:     "Lists the decks of the caller one page at a time",
+			Description: "Pass the nextCursor of a page as cursor to get the following page.",
+			Query:       model.ListDecksPayload{},
+			Status:      http.StatusOK, Data: v.page,
+			Errors:     withErrors(authErrors, helper.ErrInvalidQuery, helper.ErrDeckForbidden, helper.ErrInternal),
+			Deprecated: v.deprecated,
+		},
+		{
+			ID: "deleteDecksByGame" + v.idSuffix, Method: http.MethodDelete, Path: v.prefix, Tags: []string{"decks"},
+			Summary: "Deletes every deck of a game",
+			Query: struct {
+				GameID string `form:"gameID" binding:"required"`
+			}{},
+			Status: http.StatusOK, Data: model.DeleteDecksResult{},
+			Errors:     withErrors(authErrors, helper.ErrGameIDMissing, helper.ErrDeckForbidden, helper.ErrDealerOnly, helper.ErrInternal),
+			Deprecated: v.deprecated,
+		},
+		{
+			ID: "createDeck" + v.idSuffix, Method: http.MethodPost, Path: v.prefix + "/new", Tags: []string{"decks"},
+			Summary:     "Creates a deck",
+			Description: "The full 52 card deck is used unless specific cards are requested.",
+			Body:        model.GenerateDeckPayload{},
+			Headers:     []openapi.Parameter{idempotencyKey},
+			Status:      http.StatusCreated, Data: v.deck,
+			Errors: withErrors(authErrors, helper.ErrInvalidPayload, helper.ErrInvalidCardCode, helper.ErrDeckForbidden, helper.ErrDealerOnly,
+				helper.ErrRateLimited, helper.ErrDeckQuotaExceeded, helper.ErrDefaultDeckNotFound, helper.ErrInternal,
+				helper.ErrIdempotencyKeyInvalid, helper.ErrIdempotencyKeyReused),
+			Deprecated: v.deprecated,
+		},
+		{
+			ID: "openDeck" + v.idSuffix, Method: http.MethodGet, Path: v.prefix + "/:id", Tags: []string{"decks"},
+			Summary:     "Returns a deck",
+			Description: "The version of the deck is sent in the ETag header.",
+			Headers: []openapi.Parameter{{
+				Name: "If-None-Match", In: "header",
+				Description: "ETag of a previous response, an empty 304 is sent when the deck did not change since.",
+				Schema:      &openapi.Schema{Type: "string"},
+			}},
+			Status: http.StatusOK, Data: v.deck, EmptyStatuses: []int{http.StatusNotModified},
+			Errors:     withErrors(deckErrors, helper.ErrDeckIDMissing),
+			Deprecated: v.deprecated,
+		},
+		{
+			ID: "drawCards" + v.idSuffix, Method: http.MethodPut, Path: v.prefix + "/:id/draw-cards", Tags: []string{"decks"},
+			Summary:     "Draws cards from the top of a deck",
+			Description: "Players draw into their own hand, the dealer can draw for any player.",
+			Body:        model.DrawCardFromDeckPayload{},
+			Headers:     []openapi.Parameter{idempotencyKey, ifMatch},
+			Status:      http.StatusOK, Data: v.cards,
+			Errors: withErrors(deckErrors, helper.ErrDeckIDMissing, helper.ErrInvalidPayload, helper.ErrHandForbidden,
+				helper.ErrInsufficientCards, helper.ErrRateLimited, helper.ErrIdempotencyKeyInvalid, helper.ErrIdempotencyKeyReused,
+				helper.ErrDeckModified),
+			Deprecated: v.deprecated,
+		},
+		{
+			ID: "discardCards" + v.idSuffix, Method: http.MethodPut, Path: v.prefix + "/:id/discard-cards", Tags: []string{"decks"},
+			Summary:     "Moves cards from a hand to a pile of a deck",
+			Description: "Players discard from their own hand, the dealer can discard for any player. The pile is created by its first card.",
+			Body:        model.DiscardCardsPayload{},
+			Headers:     []openapi.Parameter{ifMatch},
+			Status:      http.StatusOK, Data: v.deck,
+			Errors: withErrors(deckErrors, helper.ErrDeckIDMissing, helper.ErrInvalidPayload, helper.ErrInvalidCardCode,
+				helper.ErrHandForbidden, helper.ErrCardNotInHand, helper.ErrDeckModified),
+			Deprecated: v.deprecated,
+		},
+		{
+			ID: "deleteDeck" + v.idSuffix, Method: http.MethodDelete, Path: v.prefix + "/:id", Tags: []string{"decks"},
+			Summary: "Deletes a deck",
+			Status:  http.StatusOK, Data: model.DeleteDecksResult{},
+			Errors:     withErrors(deckErrors, helper.ErrDealerOnly),
+			Deprecated: v.deprecated,
+		},
+		{
+			ID: "cloneDeck" + v.idSuffix, Method: http.MethodPost, Path: v.prefix + "/:id/clone", Tags: []string{"decks"},
+			Summary: "Creates a copy of a deck",
+			Body:    model.CloneDeckPayload{}, BodyOptional: true,
+			Status: http.StatusCreated, Data: v.deck,
+			Errors:     withErrors(deckErrors, helper.ErrInvalidPayload, helper.ErrDealerOnly, helper.ErrRateLimited, helper.ErrDeckQuotaExceeded),
+			Deprecated: v.deprecated,
+		},
+		{
+			ID: "resetDeck" + v.idSuffix, Method: http.MethodPost, Path: v.prefix + "/:id/reset", Tags: []string{"decks"},
+			Summary:     "Puts every card back in play",
+			Description: "Hands and piles are cleared and the round is incremented.",
+			Body:        model.ResetDeckPayload{}, BodyOptional: true,
+			Headers: []openapi.Parameter{ifMatch},
+			Status:  http.StatusOK, Data: v.deck,
+			Errors:     withErrors(deckErrors, helper.ErrInvalidPayload, helper.ErrDealerOnly, helper.ErrDeckModified),
+			Deprecated: v.deprecated,
+		},
+		{
+			ID: "peekDeck" + v.idSuffix, Method: http.MethodGet, Path: v.prefix + "/:id/peek", Tags: []string{"decks"},
+			Summary:     "Looks at cards without drawing them",
+			Description: "Only the dealer can peek, every peek is recorded in the audit trail of the deck.",
+			Query:       model.PeekDeckPayload{},
+			Status:      http.StatusOK, Data: v.cards,
+			Errors: withErrors(deckErrors, helper.ErrInvalidQuery, helper.ErrDealerOnly, helper.ErrInsufficientCards),
+			Dealer: true, Deprecated: v.deprecated,
+		},
+	}
+}
+
+func concatOperations(groups ...[]openapi.Operation) []openapi.Operation {
+	all := []openapi.Operation{}
+
+	for _, group := range groups {
+		all = append(all, group...)
+	}
+
+	return all
+}
+
+// OpenApiDocument generates the OpenAPI document describing the operations.
+func OpenApiDocument() *openapi.Document {
+	return openapi.Generate(openapi.Info{
+		Title:       "Card game paradise",
+		Description: "Backend api to play any card game with a deck of cards.",
+		Version:     "1.0.0",
+	}, Operations)
+}
+
+// SetupOpenApi registers the routes serving the OpenAPI document and a page to browse it,
+// the page loads the embedded Swagger UI from /docs/assets.
+func SetupOpenApi(r *gin.Engine) {
+	document := OpenApiDocument()
+
+	r.GET("/openapi.json", func(c *gin.Context) {
+		c.JSON(http.StatusOK, document)
+	})
+
+	r.GET("/docs", func(c *gin.Context) {
+		c.Data(http.StatusOK, "text/html; charset=utf-8", docsPage)
+	})
+
+	// The Swagger UI files are embedded in the binary so the page works without a CDN
+	r.GET("/docs/assets/:file", func(c *gin.Context) {
+		if !docsAssets[c.Param("file")] {
+			c.Status(http.StatusNotFound)
+			return
+		}
+
+		c.FileFromFS(c.Param("file"), http.FS(swaggerFiles.FS))
+	})
+}
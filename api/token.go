@@ -0,0 +1,12 @@
+package api
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/varadekd/card-game/controller"
+	"github.com/varadekd/card-game/middleware"
+)
+
+// SetupTokenApi registers the routes issuing player tokens, only API key holders can mint them.
+func SetupTokenApi(r *gin.Engine) {
+	r.POST("/token", middleware.RequireAPIKey(), controller.IssuePlayerToken)
+}
@@ -0,0 +1,273 @@
+// The auth package holds the credentials used to authenticate API callers.
+// API keys are only ever stored hashed, the plain key is shown once when minted.
+
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+)
+
+// APIKeyPrefix is prepended to every minted key so they are easy to recognise in logs and configs.
+const APIKeyPrefix = "cg_"
+
+var (
+	// ErrInvalidAPIKey is returned when a key is unknown or revoked.
+	ErrInvalidAPIKey = errors.New("invalid api key")
+
+	// ErrAPIKeyNotFound is returned when revoking a key ID that does not exist.
+	ErrAPIKeyNotFound = errors.New("api key not found")
+)
+
+// APIKey is a stored key, Hash is the hex encoded SHA-256 of the plain key.
+type APIKey struct {
+	ID        string     `json:"id"`
+	Name      string     `json:"name"`
+	Hash      string     `json:"hash"`
+	CreatedAt time.Time  `json:"createdAt"`
+	RevokedAt *time.Time `json:"revokedAt"`
+}
+
+// KeyStore keeps the API keys in a JSON file. The file is reloaded whenever it
+// changes on disk, so keys revoked with the CLI are rejected by a running server.
+type KeyStore struct {
+	path string
+
+	mu      sync.Mutex
+	keys    []APIKey
+	modTime time.Time
+	size    int64
+}
+
+// LoadKeyStore reads the keys from the file, a missing file is treated as an empty store.
+func LoadKeyStore(path string) (*KeyStore, error) {
+	if path == "" {
+		return nil, fmt.Errorf("api keys file path is empty")
+	}
+
+	ks := &KeyStore{path: path}
+
+	err := ks.reload()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return ks, nil
+}
+
+// Authenticate returns the ID of the active key matching the plain key.
+func (ks *KeyStore) Authenticate(plainKey string) (string, error) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	err := ks.reloadIfChanged()
+
+	if err != nil {
+		return "", err
+	}
+
+	hash := hashKey(plainKey)
+
+	for _, key := range ks.keys {
+		if subtle.ConstantTimeCompare([]byte(key.Hash), []byte(hash)) == 1 {
+			if key.RevokedAt != nil {
+				return "", ErrInvalidAPIKey
+			}
+			return key.ID, nil
+		}
+	}
+
+	return "", ErrInvalidAPIKey
+}
+
+// Mint generates a new key, stores its hash and returns the stored key along with the plain key.
+func (ks *KeyStore) Mint(name string) (APIKey, string, error) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	err := ks.reloadIfChanged()
+
+	if err != nil {
+		return APIKey{}, "", err
+	}
+
+	secret, err := randomHex(32)
+
+	if err != nil {
+		return APIKey{}, "", err
+	}
+
+	id, err := randomHex(4)
+
+	if err != nil {
+		return APIKey{}, "", err
+	}
+
+	plainKey := APIKeyPrefix + secret
+	key := APIKey{
+		ID:        id,
+		Name:      name,
+		Hash:      hashKey(plainKey),
+		CreatedAt: time.Now(),
+	}
+
+	ks.keys = append(ks.keys, key)
+
+	err = ks.save()
+
+	if err != nil {
+		return APIKey{}, "", err
+	}
+
+	return key, plainKey, nil
+}
+
+// Revoke marks the key as revoked, revoked keys are kept in the file for auditing.
+func (ks *KeyStore) Revoke(id string) error {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	err := ks.reloadIfChanged()
+
+	if err != nil {
+		return err
+	}
+
+	for index, key := range ks.keys {
+		if key.ID == id {
+			if key.RevokedAt == nil {
+				now := time.Now()
+				ks.keys[index].RevokedAt = &now
+			}
+			return ks.save()
+		}
+	}
+
+	return ErrAPIKeyNotFound
+}
+
+// Keys returns every stored key, including revoked ones.
+func (ks *KeyStore) Keys() ([]APIKey, error) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	err := ks.reloadIfChanged()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]APIKey(nil), ks.keys...), nil
+}
+
+func (ks *KeyStore) reloadIfChanged() error {
+	info, err := os.Stat(ks.path)
+
+	if os.IsNotExist(err) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if info.ModTime().Equal(ks.modTime) && info.Size() == ks.size {
+		return nil
+	}
+
+	return ks.reload()
+}
+
+func (ks *KeyStore) reload() error {
+	info, err := os.Stat(ks.path)
+
+	if os.IsNotExist(err) {
+		ks.keys = nil
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	data, err := ioutil.ReadFile(ks.path)
+
+	if err != nil {
+		return fmt.Errorf("we encountered an error '%s' while reading the api keys file", err.Error())
+	}
+
+	keys := []APIKey{}
+
+	if len(data) > 0 {
+		err = json.Unmarshal(data, &keys)
+
+		if err != nil {
+			return fmt.Errorf("we encountered an error '%s' while un marshalling the api keys file", err.Error())
+		}
+	}
+
+	ks.keys = keys
+	ks.modTime = info.ModTime()
+	ks.size = info.Size()
+	return nil
+}
+
+func (ks *KeyStore) save() error {
+	data, err := json.MarshalIndent(ks.keys, "", "  ")
+
+	if err != nil {
+		return err
+	}
+
+	// Writing to a temporary file first so a running server never reads a half written file
+	tmpPath := ks.path + ".tmp"
+
+	err = ioutil.WriteFile(tmpPath, data, 0600)
+
+	if err != nil {
+		return fmt.Errorf("we encountered an error '%s' while writing the api keys file", err.Error())
+	}
+
+	err = os.Rename(tmpPath, ks.path)
+
+	if err != nil {
+		return err
+	}
+
+	info, err := os.Stat(ks.path)
+
+	if err != nil {
+		return err
+	}
+
+	ks.modTime = info.ModTime()
+	ks.size = info.Size()
+	return nil
+}
+
+func hashKey(plainKey string) string {
+	sum := sha256.Sum256([]byte(plainKey))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomHex(size int) (string, error) {
+	buf := make([]byte, size)
+
+	_, err := rand.Read(buf)
+
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(buf), nil
+}
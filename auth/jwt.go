@@ -0,0 +1,234 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+)
+
+// Roles a player token can be issued for.
+const (
+	RoleDealer    = "dealer"
+	RolePlayer    = "player"
+	RoleSpectator = "spectator"
+)
+
+// Signing algorithms supported for player tokens.
+const (
+	AlgorithmHS256 = "HS256"
+	AlgorithmEdDSA = "EdDSA"
+)
+
+// DefaultTokenTTL is how long a player token stays valid when no TTL is requested,
+// MaxTokenTTL caps the requested TTL.
+const (
+	DefaultTokenTTL = 15 * time.Minute
+	MaxTokenTTL     = time.Hour
+)
+
+// minHMACKeySize is the minimum length of an HMAC secret, shorter secrets can be brute forced.
+const minHMACKeySize = 32
+
+var (
+	// ErrInvalidToken is returned for tokens that are malformed or carry a wrong signature.
+	ErrInvalidToken = errors.New("invalid token")
+
+	// ErrTokenExpired is returned for tokens used after their expiry time.
+	ErrTokenExpired = errors.New("token expired")
+)
+
+// Claims is the payload of a player token. It binds a player to a game and a role,
+// OwnerID is the ID of the API key that requested the token so the player can
+// only use decks owned by that key.
+type Claims struct {
+	PlayerID  string `json:"sub"`
+	GameID    string `json:"gameID"`
+	Role      string `json:"role"`
+	OwnerID   string `json:"owner"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// ValidRole reports whether the role is one a token can be issued for.
+func ValidRole(role string) bool {
+	return role == RoleDealer || role == RolePlayer || role == RoleSpectator
+}
+
+// JWTSigner issues and verifies player tokens signed either with an HMAC secret
+// or with an Ed25519 key.
+type JWTSigner struct {
+	algorithm  string
+	hmacKey    []byte
+	privateKey ed25519.PrivateKey
+	publicKey  ed25519.PublicKey
+
+	// Now returns the current time, it can be replaced in tests.
+	Now func() time.Time
+}
+
+// NewHMACSigner returns a signer using HS256 with the secret.
+func NewHMACSigner(secret []byte) (*JWTSigner, error) {
+	if len(secret) < minHMACKeySize {
+		return nil, fmt.Errorf("the hmac secret should be at least %d bytes long", minHMACKeySize)
+	}
+
+	return &JWTSigner{algorithm: AlgorithmHS256, hmacKey: secret, Now: time.Now}, nil
+}
+
+// NewEd25519Signer returns a signer using EdDSA with the private key.
+func NewEd25519Signer(privateKey ed25519.PrivateKey) *JWTSigner {
+	return &JWTSigner{
+		algorithm:  AlgorithmEdDSA,
+		privateKey: privateKey,
+		publicKey:  privateKey.Public().(ed25519.PublicKey),
+		Now:        time.Now,
+	}
+}
+
+// LoadSigner reads the signing key from the file. A PEM encoded PKCS #8 Ed25519
+// private key selects EdDSA, anything else is used as an HMAC secret.
+func LoadSigner(path string) (*JWTSigner, error) {
+	data, err := ioutil.ReadFile(path)
+
+	if err != nil {
+		return nil, fmt.Errorf("we encountered an error '%s' while reading the signing key", err.Error())
+	}
+
+	block, _ := pem.Decode(data)
+
+	if block == nil {
+		return NewHMACSigner([]byte(strings.TrimSpace(string(data))))
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+
+	if err != nil {
+		return nil, fmt.Errorf("we encountered an error '%s' while parsing the signing key", err.Error())
+	}
+
+	privateKey, ok := key.(ed25519.PrivateKey)
+
+	if !ok {
+		return nil, fmt.Errorf("the signing key should be an Ed25519 private key")
+	}
+
+	return NewEd25519Signer(privateKey), nil
+}
+
+// Algorithm returns the JWT algorithm used by the signer.
+func (s *JWTSigner) Algorithm() string {
+	return s.algorithm
+}
+
+// Issue signs a token for the claims valid for ttl, DefaultTokenTTL is used when ttl is zero.
+func (s *JWTSigner) Issue(claims Claims, ttl time.Duration) (string, Claims, error) {
+	if ttl <= 0 {
+		ttl = DefaultTokenTTL
+	}
+
+	if ttl > MaxTokenTTL {
+		ttl = MaxTokenTTL
+	}
+
+	now := s.Now()
+	claims.IssuedAt = now.Unix()
+	claims.ExpiresAt = now.Add(ttl).Unix()
+
+	header, err := json.Marshal(map[string]string{"alg": s.algorithm, "typ": "JWT"})
+
+	if err != nil {
+		return "", Claims{}, err
+	}
+
+	payload, err := json.Marshal(claims)
+
+	if err != nil {
+		return "", Claims{}, err
+	}
+
+	signingInput := encodeSegment(header) + "." + encodeSegment(payload)
+
+	return signingInput + "." + encodeSegment(s.sign([]byte(signingInput))), claims, nil
+}
+
+// Verify checks the signature and the expiry of the token and returns its claims.
+func (s *JWTSigner) Verify(token string) (Claims, error) {
+	parts := strings.Split(token, ".")
+
+	if len(parts) != 3 {
+		return Claims{}, ErrInvalidToken
+	}
+
+	headerJSON, err := decodeSegment(parts[0])
+
+	if err != nil {
+		return Claims{}, ErrInvalidToken
+	}
+
+	header := map[string]string{}
+
+	// The algorithm must match the configured key, tokens claiming "none" or
+	// another algorithm are rejected before looking at the signature.
+	if json.Unmarshal(headerJSON, &header) != nil || header["alg"] != s.algorithm {
+		return Claims{}, ErrInvalidToken
+	}
+
+	signature, err := decodeSegment(parts[2])
+
+	if err != nil || !s.verify([]byte(parts[0]+"."+parts[1]), signature) {
+		return Claims{}, ErrInvalidToken
+	}
+
+	payload, err := decodeSegment(parts[1])
+
+	if err != nil {
+		return Claims{}, ErrInvalidToken
+	}
+
+	claims := Claims{}
+
+	if json.Unmarshal(payload, &claims) != nil || !ValidRole(claims.Role) {
+		return Claims{}, ErrInvalidToken
+	}
+
+	if s.Now().Unix() >= claims.ExpiresAt {
+		return Claims{}, ErrTokenExpired
+	}
+
+	return claims, nil
+}
+
+func (s *JWTSigner) sign(input []byte) []byte {
+	if s.algorithm == AlgorithmEdDSA {
+		return ed25519.Sign(s.privateKey, input)
+	}
+
+	mac := hmac.New(sha256.New, s.hmacKey)
+	mac.Write(input)
+	return mac.Sum(nil)
+}
+
+func (s *JWTSigner) verify(input, signature []byte) bool {
+	if s.algorithm == AlgorithmEdDSA {
+		return ed25519.Verify(s.publicKey, input, signature)
+	}
+
+	return hmac.Equal(s.sign(input), signature)
+}
+
+func encodeSegment(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeSegment(segment string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(segment)
+}
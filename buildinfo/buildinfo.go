@@ -0,0 +1,47 @@
+// The buildinfo package holds the build metadata of the application. The values are
+// injected at link time, e.g.
+//
+//	go build -ldflags "-X github.com/varadekd/card-game/buildinfo.Version=1.4.0 \
+//	  -X github.com/varadekd/card-game/buildinfo.Commit=$(git rev-parse HEAD) \
+//	  -X github.com/varadekd/card-game/buildinfo.Date=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+
+package buildinfo
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+// Set with -ldflags -X at link time.
+var (
+	Version = "dev"
+	Commit  = ""
+	Date    = ""
+)
+
+// Info is the build metadata exposed at GET /version.
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	Date      string `json:"date"`
+	GoVersion string `json:"goVersion"`
+}
+
+// Get returns the build metadata. The commit and date recorded by the Go toolchain are
+// used when they were not injected at link time.
+func Get() Info {
+	info := Info{Version: Version, Commit: Commit, Date: Date, GoVersion: runtime.Version()}
+
+	if build, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range build.Settings {
+			switch {
+			case setting.Key == "vcs.revision" && info.Commit == "":
+				info.Commit = setting.Value
+			case setting.Key == "vcs.time" && info.Date == "":
+				info.Date = setting.Value
+			}
+		}
+	}
+
+	return info
+}
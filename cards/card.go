@@ -0,0 +1,119 @@
+package cards
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/varadekd/card-game/model"
+)
+
+// ErrInvalidCode is returned, wrapped, for codes, ranks and suits which do not name a card.
+var ErrInvalidCode = errors.New("invalid card code")
+
+// CodePattern is the regular expression matching every valid card code.
+const CodePattern = "^(A|[2-9]|10|J|Q|K)[SDCH]$"
+
+// Card is a playing card identified by its rank and suit.
+type Card struct {
+	Rank Rank
+	Suit Suit
+}
+
+// New returns the card of the rank and suit.
+func New(rank Rank, suit Suit) Card {
+	return Card{Rank: rank, Suit: suit}
+}
+
+// Standard returns the 52 cards of the default deck: every suit in DeckOrder, each from
+// Ace to King.
+func Standard() []Card {
+	deck := make([]Card, 0, len(Suits)*len(Ranks))
+
+	for _, suit := range Suits {
+		for _, rank := range Ranks {
+			deck = append(deck, New(rank, suit))
+		}
+	}
+
+	return deck
+}
+
+// ParseCode returns the card of a code made of the rank symbol and the suit letter, e.g.
+// "AS" for the ace of spades or "10H" for the ten of hearts. Codes are case sensitive.
+func ParseCode(code string) (Card, error) {
+	if len(code) < 2 {
+		return Card{}, fmt.Errorf("%w: %q", ErrInvalidCode, code)
+	}
+
+	rank, err := ParseRank(code[:len(code)-1])
+
+	if err != nil {
+		return Card{}, fmt.Errorf("%w: %q", ErrInvalidCode, code)
+	}
+
+	letter := code[len(code)-1:]
+
+	for _, suit := range Suits {
+		if suit.Letter() == letter {
+			return New(rank, suit), nil
+		}
+	}
+
+	return Card{}, fmt.Errorf("%w: %q", ErrInvalidCode, code)
+}
+
+// ValidCode reports whether the code names a card of the default deck, e.g. "AS" or "10H".
+func ValidCode(code string) bool {
+	_, err := ParseCode(code)
+	return err == nil
+}
+
+// Valid reports whether the rank and the suit of the card are valid.
+func (c Card) Valid() bool {
+	return c.Rank.Valid() && c.Suit.Valid()
+}
+
+// String returns the code of the card, e.g. "10H".
+func (c Card) String() string {
+	return c.Rank.String() + c.Suit.Letter()
+}
+
+// Colour returns the colour of the suit of the card.
+func (c Card) Colour() Colour {
+	return c.Suit.Colour()
+}
+
+// IsFace reports whether the card is a Jack, a Queen or a King.
+func (c Card) IsFace() bool {
+	return c.Rank.IsFace()
+}
+
+// Compare orders the cards by rank using the ranking, then by suit using the suit order
+// for cards of the same rank. It returns -1, 0 or 1 like Ranking.Compare.
+func (c Card) Compare(other Card, ranking Ranking, order SuitOrder) int {
+	if byRank := ranking.Compare(c.Rank, other.Rank); byRank != 0 {
+		return byRank
+	}
+
+	return order.Compare(c.Suit, other.Suit)
+}
+
+// Model returns the card as sent to clients.
+func (c Card) Model() model.Card {
+	return model.Card{Value: c.Rank.String(), Suit: c.Suit.String(), Code: c.String()}
+}
+
+// FromModel returns the card sent to clients, its code must name the card of its value and suit.
+func FromModel(card model.Card) (Card, error) {
+	parsed, err := ParseCode(card.Code)
+
+	if err != nil {
+		return Card{}, err
+	}
+
+	if card.Value != parsed.Rank.String() || card.Suit != parsed.Suit.String() {
+		return Card{}, fmt.Errorf("%w: %q does not match the value %q and the suit %q", ErrInvalidCode, card.Code, card.Value, card.Suit)
+	}
+
+	return parsed, nil
+}
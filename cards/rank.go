@@ -0,0 +1,107 @@
+// The cards package holds the semantics of playing cards: ranks, suits, colours and the
+// codes identifying a card such as "AS" or "10H". Codes are parsed and validated here only,
+// the deck generator and the game logic build on it.
+
+package cards
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Rank is the rank of a card, from Ace to King. The zero value is not a valid rank.
+type Rank int
+
+// The ranks of a standard deck, in the order of the default deck.
+const (
+	Ace Rank = iota + 1
+	Two
+	Three
+	Four
+	Five
+	Six
+	Seven
+	Eight
+	Nine
+	Ten
+	Jack
+	Queen
+	King
+)
+
+// Ranks lists every rank in the order of the default deck, Ace first.
+var Ranks = []Rank{Ace, Two, Three, Four, Five, Six, Seven, Eight, Nine, Ten, Jack, Queen, King}
+
+var rankSymbols = map[Rank]string{Ace: "A", Jack: "J", Queen: "Q", King: "K"}
+
+// Valid reports whether the rank is one of Ace to King.
+func (r Rank) Valid() bool {
+	return r >= Ace && r <= King
+}
+
+// String returns the symbol of the rank used in card codes and as the value of a card:
+// "A", "2" to "10", "J", "Q" or "K".
+func (r Rank) String() string {
+	if symbol, found := rankSymbols[r]; found {
+		return symbol
+	}
+
+	if r.Valid() {
+		return strconv.Itoa(int(r))
+	}
+
+	return fmt.Sprintf("Rank(%d)", int(r))
+}
+
+// IsFace reports whether the rank is a face card: Jack, Queen or King.
+func (r Rank) IsFace() bool {
+	return r == Jack || r == Queen || r == King
+}
+
+// ParseRank returns the rank of the symbol, as returned by Rank.String.
+func ParseRank(symbol string) (Rank, error) {
+	for _, rank := range Ranks {
+		if rank.String() == symbol {
+			return rank, nil
+		}
+	}
+
+	return 0, fmt.Errorf("%w: %q is not a rank", ErrInvalidCode, symbol)
+}
+
+// Ranking orders the ranks for a game, the ace is either the lowest or the highest rank.
+type Ranking struct {
+	AceHigh bool
+}
+
+// The rankings of most games.
+var (
+	AceLow  = Ranking{}
+	AceHigh = Ranking{AceHigh: true}
+)
+
+// Value returns the position of the rank in the ranking: the ace is worth 1 when low and
+// 14 when high, the other ranks are worth their number with 11 to 13 for the face cards.
+func (ranking Ranking) Value(r Rank) int {
+	if r == Ace && ranking.AceHigh {
+		return int(King) + 1
+	}
+
+	return int(r)
+}
+
+// Compare returns -1 when a ranks lower than b, 1 when it ranks higher and 0 for the same rank.
+func (ranking Ranking) Compare(a, b Rank) int {
+	return compare(ranking.Value(a), ranking.Value(b))
+}
+
+func compare(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+
+	return 0
+}
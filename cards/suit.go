@@ -0,0 +1,113 @@
+package cards
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Suit is the suit of a card. The zero value is not a valid suit.
+type Suit int
+
+// The suits of a standard deck, in the order of the default deck.
+const (
+	Spades Suit = iota + 1
+	Diamonds
+	Clubs
+	Hearts
+)
+
+// Suits lists every suit in the order of the default deck.
+var Suits = []Suit{Spades, Diamonds, Clubs, Hearts}
+
+var suitNames = map[Suit]string{Spades: "SPADES", Diamonds: "DIAMONDS", Clubs: "CLUBS", Hearts: "HEARTS"}
+
+// Valid reports whether the suit is one of the four suits.
+func (s Suit) Valid() bool {
+	_, found := suitNames[s]
+	return found
+}
+
+// String returns the name of the suit used as the suit of a card, e.g. "SPADES".
+func (s Suit) String() string {
+	if name, found := suitNames[s]; found {
+		return name
+	}
+
+	return fmt.Sprintf("Suit(%d)", int(s))
+}
+
+// Letter returns the letter of the suit used in card codes, e.g. "S" for Spades.
+func (s Suit) Letter() string {
+	if !s.Valid() {
+		return ""
+	}
+
+	return s.String()[:1]
+}
+
+// Colour returns the colour of the suit, spades and clubs are black.
+func (s Suit) Colour() Colour {
+	if s == Diamonds || s == Hearts {
+		return Red
+	}
+
+	return Black
+}
+
+// ParseSuit returns the suit of a name or a letter, e.g. "HEARTS" or "H". Case is ignored.
+func ParseSuit(text string) (Suit, error) {
+	for _, suit := range Suits {
+		if strings.EqualFold(text, suit.String()) || strings.EqualFold(text, suit.Letter()) {
+			return suit, nil
+		}
+	}
+
+	return 0, fmt.Errorf("%w: %q is not a suit", ErrInvalidCode, text)
+}
+
+// SuitOrder ranks the suits for a game, the first suit is the lowest.
+type SuitOrder []Suit
+
+// The suit orders of most games.
+var (
+	// DeckOrder is the order of the suits in the default deck.
+	DeckOrder = SuitOrder{Spades, Diamonds, Clubs, Hearts}
+	// BridgeOrder ranks clubs lowest and spades highest, as in bridge and poker tie breaks.
+	BridgeOrder = SuitOrder{Clubs, Diamonds, Hearts, Spades}
+)
+
+// Compare returns -1 when a ranks lower than b in the order, 1 when it ranks higher and 0
+// for the same suit. Suits missing from the order rank lowest.
+func (order SuitOrder) Compare(a, b Suit) int {
+	return compare(order.position(a), order.position(b))
+}
+
+func (order SuitOrder) position(s Suit) int {
+	for i, suit := range order {
+		if suit == s {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// Colour is the colour of a suit.
+type Colour int
+
+// The colours of the suits.
+const (
+	Black Colour = iota + 1
+	Red
+)
+
+func (c Colour) String() string {
+	switch c {
+	case Black:
+		return "BLACK"
+	case Red:
+		return "RED"
+	}
+
+	return fmt.Sprintf("Colour(%d)", int(c))
+}
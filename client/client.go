@@ -0,0 +1,263 @@
+// Package client is a typed Go client for the card game api. It only depends on the model
+// package of the server, so services using it do not build the server itself.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// IdempotencyKeyHeader is the header carrying the key generated for the calls that are
+// retried although they change a deck, see NewDeck and Draw.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// Defaults used by New.
+const (
+	DefaultMaxRetries = 3
+	DefaultRetryWait  = 200 * time.Millisecond
+)
+
+// Client calls the api of a card game server. The fields can be changed after New and
+// before the first call, a Client is safe for concurrent use afterwards.
+type Client struct {
+	baseURL string
+
+	// HTTPClient sends the requests, http.DefaultClient is used by default.
+	HTTPClient *http.Client
+
+	// APIKey is sent in the X-API-Key header, Token as a bearer player token
+	// and DealerKey in the X-Dealer-Key header. Empty values are not sent.
+	APIKey    string
+	Token     string
+	DealerKey string
+
+	// MaxRetries is how many times an idempotent call, or a call sent with an idempotency
+	// key, is retried after a network error, a 429 or a 502, 503 or 504. RetryWait is the wait before the first retry, it doubles
+	// on every retry unless the server sent a Retry-After header.
+	MaxRetries int
+	RetryWait  time.Duration
+}
+
+// New returns a client for the server at baseURL, e.g. http://localhost:8080.
+func New(baseURL string) *Client {
+	return &Client{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		HTTPClient: http.DefaultClient,
+		MaxRetries: DefaultMaxRetries,
+		RetryWait:  DefaultRetryWait,
+	}
+}
+
+// envelope is the standard response of the api with the data left to decode.
+type envelope struct {
+	Success      bool            `json:"success"`
+	Error        string          `json:"error"`
+	ErrorCode    string          `json:"errorCode"`
+	ErrorDetails []FieldError    `json:"errorDetails"`
+	Data         json.RawMessage `json:"data"`
+}
+
+// do sends the request and decodes the data of the response into out, which can be nil.
+// Idempotent methods are retried on transient failures.
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, payload, out interface{}) error {
+	return c.request(ctx, method, path, query, nil, payload, out, idempotent(method))
+}
+
+// doOnce sends the request like do with a new Idempotency-Key, every retry sends the same key
+// so the server replays the first response instead of acting twice.
+func (c *Client) doOnce(ctx context.Context, method, path string, payload, out interface{}) error {
+	headers := http.Header{}
+	headers.Set(IdempotencyKeyHeader, uuid.NewString())
+	return c.request(ctx, method, path, nil, headers, payload, out, true)
+}
+
+// request sends the request like do with the extra headers, it is only retried on transient
+// failures when retry is set.
+func (c *Client) request(ctx context.Context, method, path string, query url.Values, headers http.Header, payload, out interface{}, retry bool) error {
+	var body []byte
+
+	if payload != nil {
+		var err error
+		body, err = json.Marshal(payload)
+
+		if err != nil {
+			return fmt.Errorf("unable to encode the payload: %w", err)
+		}
+	}
+
+	target := c.baseURL + path
+
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+
+	retries := 0
+
+	if retry {
+		retries = c.MaxRetries
+	}
+
+	for attempt := 0; ; attempt++ {
+		var wait time.Duration
+		var retry bool
+
+		res, err := c.send(ctx, method, target, headers, body)
+
+		if err != nil {
+			// The request did not reach the server or the connection dropped, unless the context ended
+			wait, retry = c.backoff(attempt), ctx.Err() == nil
+		} else {
+			err = decode(res, out)
+			wait, retry = c.retryAfter(err, attempt)
+		}
+
+		// A previous attempt may have deleted the resource before its response was lost
+		if attempt > 0 && method == http.MethodDelete && errorStatus(err) == http.StatusNotFound {
+			return nil
+		}
+
+		if !retry || attempt >= retries {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+func (c *Client) send(ctx context.Context, method, target string, headers http.Header, body []byte) (*http.Response, error) {
+	var reader io.Reader
+
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, target, reader)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	req.Header.Set("Accept", "application/json")
+
+	for name, values := range headers {
+		req.Header[name] = values
+	}
+
+	if c.APIKey != "" {
+		req.Header.Set("X-API-Key", c.APIKey)
+	}
+
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+
+	if c.DealerKey != "" {
+		req.Header.Set("X-Dealer-Key", c.DealerKey)
+	}
+
+	httpClient := c.HTTPClient
+
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	return httpClient.Do(req)
+}
+
+func decode(res *http.Response, out interface{}) error {
+	defer res.Body.Close()
+
+	response := envelope{}
+	err := json.NewDecoder(res.Body).Decode(&response)
+
+	if res.StatusCode >= http.StatusBadRequest {
+		apiErr := &Error{
+			Code:    response.ErrorCode,
+			Status:  res.StatusCode,
+			Message: response.Error,
+			Details: response.ErrorDetails,
+		}
+
+		if apiErr.Message == "" {
+			apiErr.Message = http.StatusText(res.StatusCode)
+		}
+
+		if seconds, err := strconv.Atoi(res.Header.Get("Retry-After")); err == nil {
+			apiErr.RetryAfter = time.Duration(seconds) * time.Second
+		}
+
+		return apiErr
+	}
+
+	if err != nil {
+		return fmt.Errorf("unable to decode the response: %w", err)
+	}
+
+	if out == nil || len(response.Data) == 0 {
+		return nil
+	}
+
+	if err := json.Unmarshal(response.Data, out); err != nil {
+		return fmt.Errorf("unable to decode the response data: %w", err)
+	}
+
+	return nil
+}
+
+// retryAfter reports whether the error sent by the server is transient and how long to wait
+// before the next attempt.
+func (c *Client) retryAfter(err error, attempt int) (time.Duration, bool) {
+	apiErr, isAPIErr := err.(*Error)
+
+	if !isAPIErr {
+		return 0, false
+	}
+
+	switch apiErr.Status {
+	case http.StatusTooManyRequests:
+		if apiErr.RetryAfter > 0 {
+			return apiErr.RetryAfter, true
+		}
+		return c.backoff(attempt), true
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return c.backoff(attempt), true
+	}
+
+	return 0, false
+}
+
+// errorStatus returns the HTTP status of an error sent by the server, 0 for other errors.
+func errorStatus(err error) int {
+	if apiErr, isAPIErr := err.(*Error); isAPIErr {
+		return apiErr.Status
+	}
+
+	return 0
+}
+
+func (c *Client) backoff(attempt int) time.Duration {
+	return time.Duration(float64(c.RetryWait) * math.Pow(2, float64(attempt)))
+}
+
+func idempotent(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodDelete
+}
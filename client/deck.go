@@ -0,0 +1,135 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/varadekd/card-game/model"
+)
+
+// DeckPage is one page of a deck listing. NextCursor is empty on the last page.
+type DeckPage struct {
+	Decks      []model.Deck `json:"decks"`
+	NextCursor string       `json:"nextCursor"`
+}
+
+// NewDeck creates a deck, the full 52 card deck is used unless payload.Cards is set.
+// It is sent with an idempotency key so a retry never creates a second deck.
+func (c *Client) NewDeck(ctx context.Context, payload model.GenerateDeckPayload) (model.Deck, error) {
+	deck := model.Deck{}
+	err := c.doOnce(ctx, http.MethodPost, "/v2/deck/new", payload, &deck)
+	return deck, err
+}
+
+// OpenDeck returns the deck with the ID.
+func (c *Client) OpenDeck(ctx context.Context, deckID uuid.UUID) (model.Deck, error) {
+	deck := model.Deck{}
+	err := c.do(ctx, http.MethodGet, "/v2/deck/"+deckID.String(), nil, nil, &deck)
+	return deck, err
+}
+
+// Draw draws cards from the top of the deck. It is sent with an idempotency key so a retry
+// returns the cards of the first draw instead of drawing again.
+func (c *Client) Draw(ctx context.Context, deckID uuid.UUID, payload model.DrawCardFromDeckPayload) ([]model.Card, error) {
+	cards := []model.Card{}
+	err := c.doOnce(ctx, http.MethodPut, "/v2/deck/"+deckID.String()+"/draw-cards", payload, &cards)
+	return cards, err
+}
+
+// ListDecks returns a page of the decks matching the filters, pass the NextCursor of the
+// page as payload.Cursor to get the following one.
+func (c *Client) ListDecks(ctx context.Context, payload model.ListDecksPayload) (DeckPage, error) {
+	query := url.Values{}
+	setQuery(query, "gameID", payload.GameID)
+	setQuery(query, "status", payload.Status)
+	setQuery(query, "sort", payload.Sort)
+	setQuery(query, "cursor", payload.Cursor)
+	setTimeQuery(query, "createdAfter", payload.CreatedAfter)
+	setTimeQuery(query, "createdBefore", payload.CreatedBefore)
+	setTimeQuery(query, "lastUsedAfter", payload.LastUsedAfter)
+	setTimeQuery(query, "lastUsedBefore", payload.LastUsedBefore)
+
+	if payload.Shuffle != nil {
+		query.Set("shuffle", strconv.FormatBool(*payload.Shuffle))
+	}
+
+	if payload.Limit > 0 {
+		query.Set("limit", strconv.Itoa(payload.Limit))
+	}
+
+	page := DeckPage{}
+	err := c.do(ctx, http.MethodGet, "/v2/deck", query, nil, &page)
+	return page, err
+}
+
+// DeleteDeck removes the deck with the ID. A retry answered with a 404 counts as deleted,
+// the deck was removed by the attempt whose response was lost.
+func (c *Client) DeleteDeck(ctx context.Context, deckID uuid.UUID) error {
+	return c.do(ctx, http.MethodDelete, "/v2/deck/"+deckID.String(), nil, nil, nil)
+}
+
+// DeleteDecksByGame removes every deck of the game and returns how many were removed.
+func (c *Client) DeleteDecksByGame(ctx context.Context, gameID string) (int, error) {
+	result := model.DeleteDecksResult{}
+	err := c.do(ctx, http.MethodDelete, "/v2/deck", url.Values{"gameID": {gameID}}, nil, &result)
+	return result.Deleted, err
+}
+
+// CloneDeck creates a copy of the deck.
+func (c *Client) CloneDeck(ctx context.Context, deckID uuid.UUID, payload model.CloneDeckPayload) (model.Deck, error) {
+	deck := model.Deck{}
+	err := c.do(ctx, http.MethodPost, "/v2/deck/"+deckID.String()+"/clone", nil, payload, &deck)
+	return deck, err
+}
+
+// ResetDeck puts every card of the deck back in play, reshuffling them when payload.Shuffle is true.
+func (c *Client) ResetDeck(ctx context.Context, deckID uuid.UUID, payload model.ResetDeckPayload) (model.Deck, error) {
+	deck := model.Deck{}
+	err := c.do(ctx, http.MethodPost, "/v2/deck/"+deckID.String()+"/reset", nil, payload, &deck)
+	return deck, err
+}
+
+// PeekDeck looks at cards of the deck without drawing them, only the dealer is allowed to.
+// Every peek is recorded in the audit trail of the deck so it is never retried.
+func (c *Client) PeekDeck(ctx context.Context, deckID uuid.UUID, payload model.PeekDeckPayload) ([]model.Card, error) {
+	query := url.Values{}
+	setQuery(query, "from", payload.From)
+
+	if payload.Count > 0 {
+		query.Set("count", strconv.Itoa(payload.Count))
+	}
+
+	cards := []model.Card{}
+	err := c.request(ctx, http.MethodGet, "/v2/deck/"+deckID.String()+"/peek", query, nil, nil, &cards, false)
+	return cards, err
+}
+
+// IssuePlayerToken mints a player token, the client must use an API key.
+func (c *Client) IssuePlayerToken(ctx context.Context, payload model.IssueTokenPayload) (model.PlayerToken, error) {
+	token := model.PlayerToken{}
+	err := c.do(ctx, http.MethodPost, "/token", nil, payload, &token)
+	return token, err
+}
+
+// Errors returns the catalogue of the errors the server can send.
+func (c *Client) Errors(ctx context.Context) ([]Error, error) {
+	catalogue := []Error{}
+	err := c.do(ctx, http.MethodGet, "/errors", nil, nil, &catalogue)
+	return catalogue, err
+}
+
+func setQuery(query url.Values, key, value string) {
+	if value != "" {
+		query.Set(key, value)
+	}
+}
+
+func setTimeQuery(query url.Values, key string, value time.Time) {
+	if !value.IsZero() {
+		query.Set(key, value.Format(time.RFC3339Nano))
+	}
+}
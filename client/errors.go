@@ -0,0 +1,99 @@
+package client
+
+import (
+	"fmt"
+	"time"
+)
+
+// Error is returned when the api answers with an error. Code is one of the codes of the
+// error catalogue of the server, the catalogue is also served by Client.Errors.
+type Error struct {
+	Code    string       `json:"code"`
+	Status  int          `json:"status"`
+	Message string       `json:"message"`
+	Details []FieldError `json:"-"`
+
+	// RetryAfter is the wait asked by the server on 429 responses.
+	RetryAfter time.Duration `json:"-"`
+}
+
+// FieldError describes why a single field of a payload or query was rejected.
+type FieldError struct {
+	Field  string `json:"field"`
+	Reason string `json:"reason"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("card game api returned %d %s: %s", e.Status, e.Code, e.Message)
+}
+
+// Is reports whether the target is an error with the same code, so errors.Is(err,
+// client.ErrDeckNotFound) matches whatever the message or details are.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// The errors of the catalogue of the server, to be matched with errors.Is.
+var (
+	ErrInvalidPayload        = &Error{Code: "INVALID_PAYLOAD"}
+	ErrInvalidQuery          = &Error{Code: "INVALID_QUERY"}
+	ErrInvalidCardCode       = &Error{Code: "INVALID_CARD_CODE"}
+	ErrDeckIDMissing         = &Error{Code: "DECK_ID_MISSING"}
+	ErrDeckIDInvalid         = &Error{Code: "DECK_ID_INVALID"}
+	ErrGameIDMissing         = &Error{Code: "GAME_ID_MISSING"}
+	ErrIdempotencyKeyInvalid = &Error{Code: "IDEMPOTENCY_KEY_INVALID"}
+	ErrAPIKeyMissing         = &Error{Code: "API_KEY_MISSING"}
+	ErrAPIKeyInvalid         = &Error{Code: "API_KEY_INVALID"}
+	ErrTokenMissing          = &Error{Code: "TOKEN_MISSING"}
+	ErrTokenInvalid          = &Error{Code: "TOKEN_INVALID"}
+	ErrTokenExpired          = &Error{Code: "TOKEN_EXPIRED"}
+	ErrDeckForbidden         = &Error{Code: "DECK_FORBIDDEN"}
+	ErrHandForbidden         = &Error{Code: "HAND_FORBIDDEN"}
+	ErrDealerOnly            = &Error{Code: "DEALER_ONLY"}
+	ErrAPIKeyRequired        = &Error{Code: "API_KEY_REQUIRED"}
+	ErrDeckNotFound          = &Error{Code: "DECK_NOT_FOUND"}
+	ErrTokensDisabled        = &Error{Code: "TOKENS_DISABLED"}
+	ErrInsufficientCards     = &Error{Code: "INSUFFICIENT_CARDS"}
+	ErrCardNotInHand         = &Error{Code: "CARD_NOT_IN_HAND"}
+	ErrDeckExpired           = &Error{Code: "DECK_EXPIRED"}
+	ErrDeckModified          = &Error{Code: "DECK_MODIFIED"}
+	ErrIdempotencyKeyReused  = &Error{Code: "IDEMPOTENCY_KEY_REUSED"}
+	ErrRateLimited           = &Error{Code: "RATE_LIMITED"}
+	ErrDeckQuotaExceeded     = &Error{Code: "DECK_QUOTA_EXCEEDED"}
+	ErrDefaultDeckNotFound   = &Error{Code: "DEFAULT_DECK_UNAVAILABLE"}
+	ErrInternal              = &Error{Code: "INTERNAL_ERROR"}
+	ErrNotReady              = &Error{Code: "NOT_READY"}
+)
+
+// Catalogue lists the errors above, it is kept in sync with the catalogue of the server by the tests.
+var Catalogue = []*Error{
+	ErrInvalidPayload,
+	ErrInvalidQuery,
+	ErrInvalidCardCode,
+	ErrDeckIDMissing,
+	ErrDeckIDInvalid,
+	ErrGameIDMissing,
+	ErrIdempotencyKeyInvalid,
+	ErrAPIKeyMissing,
+	ErrAPIKeyInvalid,
+	ErrTokenMissing,
+	ErrTokenInvalid,
+	ErrTokenExpired,
+	ErrDeckForbidden,
+	ErrHandForbidden,
+	ErrDealerOnly,
+	ErrAPIKeyRequired,
+	ErrDeckNotFound,
+	ErrTokensDisabled,
+	ErrInsufficientCards,
+	ErrCardNotInHand,
+	ErrDeckExpired,
+	ErrDeckModified,
+	ErrIdempotencyKeyReused,
+	ErrRateLimited,
+	ErrDeckQuotaExceeded,
+	ErrDefaultDeckNotFound,
+	ErrInternal,
+	ErrNotReady,
+}
@@ -0,0 +1,494 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pelletier/go-toml/v2"
+	"github.com/varadekd/card-game/controller"
+	"github.com/varadekd/card-game/logging"
+	"github.com/varadekd/card-game/store"
+	"github.com/varadekd/card-game/tracing"
+	"gopkg.in/yaml.v3"
+)
+
+// Config holds every setting of the application. It is read by Load from the defaults,
+// then the config file, then the environment variables and finally the command line flags,
+// every source overriding the previous ones.
+//
+// The env tag names the environment variable of a setting and the flag tag its flag.
+type Config struct {
+	Server  ServerConfig  `yaml:"server" toml:"server"`
+	Storage StorageConfig `yaml:"storage" toml:"storage"`
+	Decks   DecksConfig   `yaml:"decks" toml:"decks"`
+	Limits  LimitsConfig  `yaml:"limits" toml:"limits"`
+	Auth    AuthConfig    `yaml:"auth" toml:"auth"`
+	Tracing TracingConfig `yaml:"tracing" toml:"tracing"`
+}
+
+// ServerConfig sets where the application listens. The gRPC service is only started
+// when GRPCPort is set. Mode is the Gin mode, one of debug, release or test.
+// ShutdownTimeout is how long the requests in flight have to finish on shutdown.
+// LogLevel is the lowest level logged, one of debug, info, warn or error.
+type ServerConfig struct {
+	Port            string   `yaml:"port" toml:"port" env:"APP_PORT" flag:"port" usage:"port of the REST api"`
+	GRPCPort        string   `yaml:"grpcPort" toml:"grpcPort" env:"GRPC_PORT" flag:"grpc-port" usage:"port of the gRPC service, it is disabled when empty"`
+	Mode            string   `yaml:"mode" toml:"mode" env:"GIN_MODE" flag:"mode" usage:"gin mode: debug, release or test"`
+	ShutdownTimeout Duration `yaml:"shutdownTimeout" toml:"shutdownTimeout" env:"SHUTDOWN_TIMEOUT" flag:"shutdown-timeout" usage:"how long requests in flight have to finish on shutdown"`
+	LogLevel        string   `yaml:"logLevel" toml:"logLevel" env:"LOG_LEVEL" flag:"log-level" usage:"lowest level logged: debug, info, warn or error"`
+}
+
+// StorageConfig selects where decks are kept, memory is the only backend for now.
+// The memory backend saves its decks to SnapshotFile on shutdown and restores them on
+// startup. It is empty by default, snapshots are off and decks are lost on restart.
+type StorageConfig struct {
+	Backend      string `yaml:"backend" toml:"backend" env:"STORAGE_BACKEND" flag:"storage" usage:"deck storage backend: memory"`
+	SnapshotFile string `yaml:"snapshotFile" toml:"snapshotFile" env:"STORAGE_SNAPSHOT_FILE" flag:"snapshot-file" usage:"file the decks are saved to on shutdown, snapshots are off when it is empty as by default"`
+}
+
+// DecksConfig holds the default deck file and the lifetime of decks, see store.ExpiryPolicy.
+// The cards file is loaded once, it is only read again when CardsReload is set.
+type DecksConfig struct {
+	CardsFile     string   `yaml:"cardsFile" toml:"cardsFile" env:"DEFAULT_CARDS_FILE_STORAGE" flag:"cards-file" usage:"file the default deck is written to"`
+	CardsReload   Duration `yaml:"cardsReload" toml:"cardsReload" env:"CARDS_RELOAD_INTERVAL" flag:"cards-reload" usage:"how often the cards file is checked for changes, 0 disables it"`
+	IdleTTL       Duration `yaml:"idleTTL" toml:"idleTTL" env:"DECK_IDLE_TTL" flag:"deck-idle-ttl" usage:"how long a deck can stay unused, 0 disables it"`
+	MaxAge        Duration `yaml:"maxAge" toml:"maxAge" env:"DECK_MAX_AGE" flag:"deck-max-age" usage:"lifetime of a deck, 0 disables it"`
+	GracePeriod   Duration `yaml:"gracePeriod" toml:"gracePeriod" env:"DECK_GRACE_PERIOD" flag:"deck-grace-period" usage:"how long expired decks answer 410 Gone"`
+	SweepInterval Duration `yaml:"sweepInterval" toml:"sweepInterval" env:"SWEEP_INTERVAL" flag:"sweep-interval" usage:"how often expired decks are removed"`
+}
+
+// LimitsConfig holds the limits applied per client. A per minute limit of 0 disables the
+// limit, as does a MaxActiveDecks or an IdempotencyTTL of 0.
+type LimitsConfig struct {
+	CreatePerMinute int      `yaml:"createPerMinute" toml:"createPerMinute" env:"RATE_LIMIT_CREATE_PER_MINUTE" flag:"create-per-minute" usage:"decks a client can create or clone per minute"`
+	CreateBurst     int      `yaml:"createBurst" toml:"createBurst" env:"RATE_LIMIT_CREATE_BURST" flag:"create-burst" usage:"decks a client can create or clone at once"`
+	DrawPerMinute   int      `yaml:"drawPerMinute" toml:"drawPerMinute" env:"RATE_LIMIT_DRAW_PER_MINUTE" flag:"draw-per-minute" usage:"draws a client can do per minute"`
+	DrawBurst       int      `yaml:"drawBurst" toml:"drawBurst" env:"RATE_LIMIT_DRAW_BURST" flag:"draw-burst" usage:"draws a client can do at once"`
+	MaxActiveDecks  int      `yaml:"maxActiveDecks" toml:"maxActiveDecks" env:"MAX_ACTIVE_DECKS_PER_CLIENT" flag:"max-active-decks" usage:"decks a client can keep at once"`
+	IdempotencyTTL  Duration `yaml:"idempotencyTTL" toml:"idempotencyTTL" env:"IDEMPOTENCY_TTL" flag:"idempotency-ttl" usage:"how long responses are kept for idempotency keys"`
+}
+
+// AuthConfig holds the credentials files. Authentication is disabled unless APIKeysFile
+// is set, player tokens need JWTSigningKeyFile too. The dealer key has no flag so it never
+// shows up in the process list.
+type AuthConfig struct {
+	APIKeysFile       string `yaml:"apiKeysFile" toml:"apiKeysFile" env:"API_KEYS_FILE" flag:"api-keys-file" usage:"file holding the API keys"`
+	JWTSigningKeyFile string `yaml:"jwtSigningKeyFile" toml:"jwtSigningKeyFile" env:"JWT_SIGNING_KEY_FILE" flag:"jwt-signing-key-file" usage:"file holding the player token signing key"`
+	DealerKey         string `yaml:"dealerKey" toml:"dealerKey" env:"DEALER_KEY"`
+}
+
+// TracingConfig selects where the OpenTelemetry spans are exported: none, stdout or otlp.
+// Endpoint is the base URL of the OTLP/HTTP collector used by the otlp exporter.
+type TracingConfig struct {
+	Exporter string `yaml:"exporter" toml:"exporter" env:"TRACING_EXPORTER" flag:"tracing-exporter" usage:"span exporter: none, stdout or otlp"`
+	Endpoint string `yaml:"endpoint" toml:"endpoint" env:"TRACING_OTLP_ENDPOINT" flag:"tracing-endpoint" usage:"base URL of the OTLP/HTTP collector"`
+}
+
+// Default returns the configuration used when nothing is set.
+func Default() Config {
+	return Config{
+		Server:  ServerConfig{Port: "8080", Mode: gin.DebugMode, ShutdownTimeout: Duration{30 * time.Second}, LogLevel: "info"},
+		Storage: StorageConfig{Backend: "memory"},
+		Decks: DecksConfig{
+			CardsFile:     "data/cards.json",
+			IdleTTL:       Duration{store.DefaultExpiryPolicy.IdleTTL},
+			MaxAge:        Duration{store.DefaultExpiryPolicy.MaxAge},
+			GracePeriod:   Duration{store.DefaultExpiryPolicy.GracePeriod},
+			SweepInterval: Duration{store.DefaultSweepInterval},
+		},
+		Limits: LimitsConfig{
+			CreatePerMinute: 60,
+			CreateBurst:     20,
+			DrawPerMinute:   600,
+			DrawBurst:       100,
+			MaxActiveDecks:  1000,
+			IdempotencyTTL:  Duration{24 * time.Hour},
+		},
+		Tracing: TracingConfig{Exporter: tracing.ExporterNone, Endpoint: "http://localhost:4318"},
+	}
+}
+
+// ConfigFileVariable is the environment variable holding the path of the config file,
+// the -config flag takes precedence over it.
+const ConfigFileVariable = "CONFIG_FILE"
+
+// Load builds the configuration from the defaults, the config file, the environment and
+// the flags in args, then validates it. The config file is YAML or TOML depending on its
+// extension, unknown keys are rejected so typos do not go unnoticed. flag.ErrHelp is
+// returned when the usage was requested.
+func Load(args []string) (Config, error) {
+	cfg := Default()
+
+	flags, configFile, setFlags := newFlagSet(&cfg)
+
+	if err := flags.Parse(args); err != nil {
+		return Config{}, err
+	}
+
+	if *configFile == "" {
+		*configFile = os.Getenv(ConfigFileVariable)
+	}
+
+	if *configFile != "" {
+		if err := loadFile(&cfg, *configFile); err != nil {
+			return Config{}, err
+		}
+	}
+
+	if err := applyEnv(&cfg); err != nil {
+		return Config{}, err
+	}
+
+	if err := applyDeprecatedEnv(&cfg); err != nil {
+		return Config{}, err
+	}
+
+	// Flags are applied last so they override the file and the environment
+	for _, set := range setFlags() {
+		if err := set(); err != nil {
+			return Config{}, err
+		}
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return Config{}, err
+	}
+
+	return cfg, nil
+}
+
+// Validate reports every invalid setting at once.
+func (cfg Config) Validate() error {
+	problems := []string{}
+
+	for name, port := range map[string]string{"server.port": cfg.Server.Port, "server.grpcPort": cfg.Server.GRPCPort} {
+		if port == "" && name == "server.grpcPort" {
+			continue
+		}
+
+		if number, err := strconv.Atoi(port); err != nil || number < 1 || number > 65535 {
+			problems = append(problems, fmt.Sprintf("%s should be a port between 1 and 65535 but found %q", name, port))
+		}
+	}
+
+	if cfg.Server.GRPCPort != "" && cfg.Server.GRPCPort == cfg.Server.Port {
+		problems = append(problems, "server.grpcPort should be different from server.port")
+	}
+
+	switch cfg.Server.Mode {
+	case gin.DebugMode, gin.ReleaseMode, gin.TestMode:
+	default:
+		problems = append(problems, fmt.Sprintf("server.mode should be one of debug, release or test but found %q", cfg.Server.Mode))
+	}
+
+	var level slog.Level
+
+	if err := level.UnmarshalText([]byte(cfg.Server.LogLevel)); err != nil {
+		problems = append(problems, fmt.Sprintf("server.logLevel should be one of debug, info, warn or error but found %q", cfg.Server.LogLevel))
+	}
+
+	if cfg.Storage.Backend != "memory" {
+		problems = append(problems, fmt.Sprintf("storage.backend should be memory but found %q", cfg.Storage.Backend))
+	}
+
+	if cfg.Decks.CardsFile == "" {
+		problems = append(problems, "decks.cardsFile can not be empty")
+	}
+
+	durations := map[string]Duration{
+		"server.shutdownTimeout": cfg.Server.ShutdownTimeout,
+		"decks.cardsReload":      cfg.Decks.CardsReload,
+		"decks.idleTTL":          cfg.Decks.IdleTTL,
+		"decks.maxAge":           cfg.Decks.MaxAge,
+		"decks.gracePeriod":      cfg.Decks.GracePeriod,
+		"decks.sweepInterval":    cfg.Decks.SweepInterval,
+		"limits.idempotencyTTL":  cfg.Limits.IdempotencyTTL,
+	}
+
+	for name, d := range durations {
+		if d.Duration < 0 {
+			problems = append(problems, fmt.Sprintf("%s can not be negative but found %s", name, d))
+		}
+	}
+
+	limits := map[string]int{
+		"limits.createPerMinute": cfg.Limits.CreatePerMinute,
+		"limits.createBurst":     cfg.Limits.CreateBurst,
+		"limits.drawPerMinute":   cfg.Limits.DrawPerMinute,
+		"limits.drawBurst":       cfg.Limits.DrawBurst,
+		"limits.maxActiveDecks":  cfg.Limits.MaxActiveDecks,
+	}
+
+	for name, limit := range limits {
+		if limit < 0 {
+			problems = append(problems, fmt.Sprintf("%s can not be negative but found %d", name, limit))
+		}
+	}
+
+	switch cfg.Tracing.Exporter {
+	case tracing.ExporterNone, tracing.ExporterStdout:
+	case tracing.ExporterOTLP:
+		if _, err := tracing.ParseEndpoint(cfg.Tracing.Endpoint); err != nil {
+			problems = append(problems, fmt.Sprintf("tracing.endpoint should be an http or https URL but found %q", cfg.Tracing.Endpoint))
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("tracing.exporter should be one of none, stdout or otlp but found %q", cfg.Tracing.Exporter))
+	}
+
+	if cfg.Auth.JWTSigningKeyFile != "" && cfg.Auth.APIKeysFile == "" {
+		problems = append(problems, "auth.jwtSigningKeyFile requires auth.apiKeysFile to be set, player tokens are minted by API key holders")
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
+
+	// Maps are iterated in random order, the problems are sorted so the message is stable
+	sort.Strings(problems)
+	return fmt.Errorf("invalid configuration:\n  - %s", strings.Join(problems, "\n  - "))
+}
+
+// NewLogger returns the JSON logger of the application writing to w.
+func (cfg Config) NewLogger(w io.Writer) *slog.Logger {
+	var level slog.Level
+
+	// Validate already rejected the invalid levels
+	level.UnmarshalText([]byte(cfg.Server.LogLevel))
+	return logging.New(w, level)
+}
+
+// SetupTracing installs the tracer provider exporting the spans as configured, the stdout
+// exporter writes to w. The returned function flushes the spans left on shutdown.
+func (cfg Config) SetupTracing(ctx context.Context, w io.Writer) (func(context.Context) error, error) {
+	return tracing.Setup(ctx, tracing.Options{
+		Exporter: cfg.Tracing.Exporter,
+		Endpoint: cfg.Tracing.Endpoint,
+		Stdout:   w,
+	})
+}
+
+// ExpiryPolicy returns the expiry policy of the decks.
+func (cfg Config) ExpiryPolicy() store.ExpiryPolicy {
+	return store.ExpiryPolicy{
+		IdleTTL:     cfg.Decks.IdleTTL.Duration,
+		MaxAge:      cfg.Decks.MaxAge.Duration,
+		GracePeriod: cfg.Decks.GracePeriod.Duration,
+	}
+}
+
+// NewStore returns the deck store selected by the configuration, the clients watching its
+// decks are told when they expire.
+func (cfg Config) NewStore() store.DeckStore {
+	return controller.NewMemoryStore(cfg.ExpiryPolicy())
+}
+
+func loadFile(cfg *Config, path string) error {
+	data, err := os.ReadFile(path)
+
+	if err != nil {
+		return fmt.Errorf("unable to read the config file: %w", err)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		err = decoder.Decode(cfg)
+
+		// An empty file is a valid configuration
+		if err == io.EOF {
+			err = nil
+		}
+	case ".toml":
+		err = toml.NewDecoder(bytes.NewReader(data)).DisallowUnknownFields().Decode(cfg)
+	default:
+		return fmt.Errorf("unable to read the config file %s, the extension should be .yaml, .yml or .toml", path)
+	}
+
+	if err != nil {
+		return fmt.Errorf("unable to parse the config file %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// applyEnv overrides the settings whose environment variable is set.
+func applyEnv(cfg *Config) error {
+	return eachSetting(cfg, func(field reflect.StructField, value reflect.Value) error {
+		variable := field.Tag.Get("env")
+
+		if variable == "" {
+			return nil
+		}
+
+		raw, found := os.LookupEnv(variable)
+
+		if !found || raw == "" {
+			return nil
+		}
+
+		if err := setValue(value, raw); err != nil {
+			return fmt.Errorf("the variable %s %s but found %s", variable, err.Error(), raw)
+		}
+
+		return nil
+	})
+}
+
+// DeprecatedIdempotencyTTLVariable is the name IDEMPOTENCY_TTL had before the settings were
+// typed, it only accepts a number of seconds and is ignored when IDEMPOTENCY_TTL is set.
+const DeprecatedIdempotencyTTLVariable = "IDEMPOTENCY_TTL_SECONDS"
+
+// applyDeprecatedEnv reads the variables that were renamed so existing deployments keep working.
+func applyDeprecatedEnv(cfg *Config) error {
+	raw := os.Getenv(DeprecatedIdempotencyTTLVariable)
+
+	if raw == "" || os.Getenv("IDEMPOTENCY_TTL") != "" {
+		return nil
+	}
+
+	seconds, err := strconv.Atoi(raw)
+
+	if err != nil {
+		return fmt.Errorf("the variable %s should be a number of seconds but found %s, use IDEMPOTENCY_TTL for durations like 24h", DeprecatedIdempotencyTTLVariable, raw)
+	}
+
+	cfg.Limits.IdempotencyTTL = Duration{time.Duration(seconds) * time.Second}
+	return nil
+}
+
+// newFlagSet registers a flag for every setting with a flag tag. The values are only
+// recorded while parsing, the returned function lists the setters to run once the file
+// and the environment are applied.
+func newFlagSet(cfg *Config) (*flag.FlagSet, *string, func() []func() error) {
+	flags := flag.NewFlagSet("card-game", flag.ContinueOnError)
+	configFile := flags.String("config", "", "YAML or TOML config file, the "+ConfigFileVariable+" variable is used when not set")
+	setters := []func() error{}
+
+	eachSetting(cfg, func(field reflect.StructField, value reflect.Value) error {
+		name := field.Tag.Get("flag")
+
+		if name == "" {
+			return nil
+		}
+
+		flags.Func(name, fmt.Sprintf("%s (default %s)", field.Tag.Get("usage"), formatValue(value)), func(raw string) error {
+			// Checking the value right away so parsing fails with the usage
+			if err := setValue(reflect.New(value.Type()).Elem(), raw); err != nil {
+				return err
+			}
+
+			setters = append(setters, func() error { return setValue(value, raw) })
+			return nil
+		})
+
+		return nil
+	})
+
+	return flags, configFile, func() []func() error { return setters }
+}
+
+// eachSetting calls fn for every setting of the configuration.
+func eachSetting(cfg *Config, fn func(field reflect.StructField, value reflect.Value) error) error {
+	sections := reflect.ValueOf(cfg).Elem()
+
+	for i := 0; i < sections.NumField(); i++ {
+		section := sections.Field(i)
+
+		for j := 0; j < section.NumField(); j++ {
+			if err := fn(section.Type().Field(j), section.Field(j)); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+var durationType = reflect.TypeOf(Duration{})
+
+// setValue parses raw into the setting, the error completes "the variable X ...".
+func setValue(value reflect.Value, raw string) error {
+	switch {
+	case value.Type() == durationType:
+		var d Duration
+
+		if err := d.UnmarshalText([]byte(raw)); err != nil {
+			return fmt.Errorf("should be a duration like 90s or 24h")
+		}
+
+		value.Set(reflect.ValueOf(d))
+	case value.Kind() == reflect.Int:
+		number, err := strconv.Atoi(raw)
+
+		if err != nil {
+			return fmt.Errorf("should be a number")
+		}
+
+		value.SetInt(int64(number))
+	default:
+		value.SetString(raw)
+	}
+
+	return nil
+}
+
+func formatValue(value reflect.Value) string {
+	if value.Type() == durationType {
+		return value.Interface().(Duration).String()
+	}
+
+	return fmt.Sprintf("%q", fmt.Sprint(value.Interface()))
+}
+
+// Duration is a time.Duration written as "90s" or "24h" in the config file and the
+// environment. A plain number is a number of seconds.
+type Duration struct {
+	time.Duration
+}
+
+func (d *Duration) UnmarshalText(text []byte) error {
+	if seconds, err := strconv.Atoi(string(text)); err == nil {
+		d.Duration = time.Duration(seconds) * time.Second
+		return nil
+	}
+
+	parsed, err := time.ParseDuration(string(text))
+
+	if err != nil {
+		return err
+	}
+
+	d.Duration = parsed
+	return nil
+}
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalYAML accepts both strings and numbers, which the YAML decoder would otherwise
+// read as nanoseconds.
+func (d *Duration) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind != yaml.ScalarNode {
+		return fmt.Errorf("line %d: should be a duration like 90s or 24h", node.Line)
+	}
+
+	if err := d.UnmarshalText([]byte(node.Value)); err != nil {
+		return fmt.Errorf("line %d: should be a duration like 90s or 24h but found %s", node.Line, node.Value)
+	}
+
+	return nil
+}
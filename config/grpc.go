@@ -0,0 +1,57 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+
+	"github.com/varadekd/card-game/grpcapi"
+	"github.com/varadekd/card-game/helper"
+	"google.golang.org/grpc"
+)
+
+// SetupGRPCServer builds the gRPC deck service, it accepts the same credentials as the router
+// and shares its rate limits, so NewRouter must be called first.
+func SetupGRPCServer(cfg Config) *grpc.Server {
+	if err := helper.RegisterValidators(); err != nil {
+		log.Fatalf("Unable to register the payload validators. Error: %s", err.Error())
+	}
+
+	keys, signer := loadCredentials(cfg.Auth)
+	return grpcapi.NewServer(keys, signer)
+}
+
+// StartGRPCServer serves the gRPC service on the specified port in the background.
+// The application is closed when the port cannot be listened on.
+func StartGRPCServer(s *grpc.Server, port string) {
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%s", port))
+
+	if err != nil {
+		log.Fatalf("Unable to start the gRPC server on port %s. Encountered an error %s while listening.", port, err.Error())
+	}
+
+	go func() {
+		if err := s.Serve(listener); err != nil {
+			log.Printf("The gRPC server stopped with the error %s", err.Error())
+		}
+	}()
+}
+
+// StopGRPCServer lets the running calls finish, they are cancelled once the context is done.
+func StopGRPCServer(ctx context.Context, s *grpc.Server) {
+	stopped := make(chan struct{})
+
+	go func() {
+		s.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		log.Println("The gRPC calls in flight did not finish in time, cancelling them.")
+		s.Stop()
+		<-stopped
+	}
+}
@@ -3,21 +3,42 @@ package config
 import (
 	"fmt"
 	"log"
+	"net"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/varadekd/card-game/api"
+	"github.com/varadekd/card-game/auth"
+	"github.com/varadekd/card-game/controller"
+	"github.com/varadekd/card-game/gqlapi"
+	"github.com/varadekd/card-game/helper"
+	"github.com/varadekd/card-game/middleware"
 )
 
-// SetupRouter is responsible for enabling HTTP requests using Gin for this application.
+// readHeaderTimeout bounds how long a client can take to send the request headers.
+const readHeaderTimeout = 10 * time.Second
+
+// SetupRouter builds the router from the configuration of the environment, see Load.
+// The application is closed when the configuration is invalid.
 func SetupRouter() *gin.Engine {
-	router := gin.Default()
+	cfg, err := Load(nil)
 
-	// TODO: Uncomment the code when the application supports this mode.
-	// If you wish to temporarily run this in release mode, you can use the command below.
-	// RUN: export GIN_MODE=release
+	if err != nil {
+		log.Fatalln(err)
+	}
 
-	// gin.SetMode(gin.ReleaseMode)
+	return NewRouter(cfg)
+}
+
+// NewRouter is responsible for enabling HTTP requests using Gin for this application.
+func NewRouter(cfg Config) *gin.Engine {
+	gin.SetMode(cfg.Server.Mode)
+	helper.UseCardsFile(cfg.Decks.CardsFile)
+	router := gin.New()
+
+	// Every request gets an ID and a span carried by its logger, the access log is written as JSON
+	router.Use(middleware.RequestID(), middleware.Trace(), middleware.AccessLog(), middleware.Recovery())
 
 	// Creating server ping
 	router.GET("/ping", func(ctx *gin.Context) {
@@ -26,14 +47,78 @@ func SetupRouter() *gin.Engine {
 		})
 	})
 
+	// Payloads are validated with the binding tags, card codes need a custom validator
+	if err := helper.RegisterValidators(); err != nil {
+		log.Fatalf("Unable to register the payload validators. Error: %s", err.Error())
+	}
+
+	// The probes, the error catalogue and the api documentation are public like the ping
+	api.SetupHealthApi(router, controller.ReadinessChecks(cfg.Storage.SnapshotFile))
+	api.SetupErrorsApi(router)
+	api.SetupOpenApi(router)
+
+	// Authentication is enabled when an API keys file or a token signing key is configured.
+	// The middleware is registered after /ping and /errors so they stay public. GraphQL
+	// subscriptions are registered before it because they authenticate on connection_init.
+	keys, signer := loadCredentials(cfg.Auth)
+	schema := gqlapi.NewSchema()
+	api.SetupGraphQLSubscriptions(router, schema, keys, signer)
+	router.Use(middleware.Authenticate(keys, signer))
+
+	if signer != nil {
+		controller.UseTokenSigner(signer)
+		api.SetupTokenApi(router)
+	}
+
 	// Calling all the apis
-	api.SetupDeckApi(router)
+	// The rate limiters are shared with gRPC and GraphQL so a client has one budget per server
+	createLimiter := middleware.NewRateLimiter(cfg.Limits.CreatePerMinute, cfg.Limits.CreateBurst)
+	drawLimiter := middleware.NewRateLimiter(cfg.Limits.DrawPerMinute, cfg.Limits.DrawBurst)
+	controller.SetMaxActiveDecks(cfg.Limits.MaxActiveDecks)
+	controller.UseRateLimiters(createLimiter, drawLimiter)
+	api.SetupDeckApi(router, api.DeckOptions{
+		CreateLimiter: createLimiter,
+		DrawLimiter:   drawLimiter,
+		Idempotency:   middleware.NewIdempotencyStore(cfg.Limits.IdempotencyTTL.Duration),
+		DealerKey:     cfg.Auth.DealerKey,
+	})
+	api.SetupGraphQLApi(router, schema)
 	return router
 }
 
-// StartServer will initiate the server on the specified port.
-// If the provided port is empty or if the router is nil, the application will be closed.
-func StartServer(r *gin.Engine, port string) {
+// loadCredentials reads the API keys and the player token signing key from the files of the
+// configuration. Either of them is nil when its file is not set.
+func loadCredentials(cfg AuthConfig) (*auth.KeyStore, *auth.JWTSigner) {
+	var keys *auth.KeyStore
+	var signer *auth.JWTSigner
+	var err error
+
+	if cfg.APIKeysFile != "" {
+		keys, err = auth.LoadKeyStore(cfg.APIKeysFile)
+
+		if err != nil {
+			log.Fatalf("Unable to load the api keys. Encountered an error %s while reading %s.", err.Error(), cfg.APIKeysFile)
+		}
+	} else {
+		log.Println("No API keys file is configured, API key authentication is disabled.")
+	}
+
+	// Validate already checked that the signing key comes with the keys file
+	if cfg.JWTSigningKeyFile != "" {
+		signer, err = auth.LoadSigner(cfg.JWTSigningKeyFile)
+
+		if err != nil {
+			log.Fatalf("Unable to load the token signing key. Encountered an error %s while reading %s.", err.Error(), cfg.JWTSigningKeyFile)
+		}
+	}
+
+	return keys, signer
+}
+
+// StartServer will initiate the server on the specified port in the background and return it,
+// so it can be shut down gracefully. If the provided port is empty, if the router is nil or if
+// the port cannot be listened on, the application will be closed.
+func StartServer(r *gin.Engine, port string) *http.Server {
 	if port == "" {
 		log.Fatalln("Unable to start the server because of missing port.")
 	}
@@ -42,9 +127,24 @@ func StartServer(r *gin.Engine, port string) {
 		log.Fatalln("Unable to start the server because of missing router.")
 	}
 
-	err := r.Run(fmt.Sprintf(":%s", port))
+	server := &http.Server{
+		Addr:              fmt.Sprintf(":%s", port),
+		Handler:           r,
+		ReadHeaderTimeout: readHeaderTimeout,
+	}
+
+	// Listening right away so a port in use is reported before the application reports it started
+	listener, err := net.Listen("tcp", server.Addr)
 
 	if err != nil {
 		log.Fatalf("Unable to start the application on port %s. Encountered an error %s while attempting to start the application.", port, err.Error())
 	}
+
+	go func() {
+		if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("The application stopped serving on port %s. Encountered an error %s.", port, err.Error())
+		}
+	}()
+
+	return server
 }
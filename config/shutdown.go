@@ -0,0 +1,75 @@
+package config
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"github.com/varadekd/card-game/controller"
+	"github.com/varadekd/card-game/gqlapi"
+	"github.com/varadekd/card-game/helper"
+	"github.com/varadekd/card-game/store"
+	"google.golang.org/grpc"
+)
+
+// Services are the parts of the application stopped on shutdown, the nil ones are skipped.
+type Services struct {
+	HTTP             *http.Server
+	GRPC             *grpc.Server
+	Sweeper          *store.Sweeper
+	CatalogueWatcher *helper.CatalogueWatcher
+	Store            store.DeckStore
+
+	// ShutdownTracing exports the spans left, it is returned by Config.SetupTracing.
+	ShutdownTracing func(context.Context) error
+}
+
+// Shutdown stops accepting requests and lets the ones in flight finish within the shutdown
+// timeout. The clients watching decks are told the server is going away, then the decks
+// are saved to the snapshot file and the spans left are exported.
+func Shutdown(cfg Config, services Services) {
+	slog.Info("Shutting down, waiting for the requests in flight to finish.", "timeout", cfg.Server.ShutdownTimeout.String())
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout.Duration)
+	defer cancel()
+
+	// The watchers are closed first, their streams would otherwise hold the servers until the timeout
+	controller.Events().Close()
+	gqlapi.CloseSessions()
+
+	if services.HTTP != nil {
+		if err := services.HTTP.Shutdown(ctx); err != nil {
+			slog.Error("The requests in flight did not finish in time.", "error", err)
+		}
+	}
+
+	if services.GRPC != nil {
+		StopGRPCServer(ctx, services.GRPC)
+	}
+
+	if services.Sweeper != nil {
+		services.Sweeper.Stop()
+	}
+
+	if services.CatalogueWatcher != nil {
+		services.CatalogueWatcher.Stop()
+	}
+
+	// The spans of the last requests are flushed even when the decks can not be saved
+	if services.ShutdownTracing != nil {
+		defer func() {
+			if err := services.ShutdownTracing(ctx); err != nil {
+				slog.Error("Unable to export the last spans.", "error", err)
+			}
+		}()
+	}
+
+	if snapshotter, ok := services.Store.(store.Snapshotter); ok && cfg.Storage.SnapshotFile != "" {
+		if err := store.SaveSnapshot(snapshotter, cfg.Storage.SnapshotFile); err != nil {
+			slog.Error("Unable to save the decks.", "snapshot_file", cfg.Storage.SnapshotFile, "error", err)
+			return
+		}
+
+		slog.Info("Saved the decks.", "snapshot_file", cfg.Storage.SnapshotFile)
+	}
+}
@@ -0,0 +1,149 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/gin-gonic/gin"
+	"github.com/varadekd/card-game/auth"
+	"github.com/varadekd/card-game/helper"
+	"github.com/varadekd/card-game/middleware"
+	"github.com/varadekd/card-game/model"
+	"github.com/varadekd/card-game/store"
+)
+
+// Caller is who a deck operation is performed for, whatever the transport it came from.
+// KeyID is the ID of the API key the caller acts for, it is empty when the API key
+// authentication is disabled. Claims are set for player tokens. ClientKey identifies
+// the client for quotas. Logger is the logger of the request, it carries the request ID.
+// Context is the context of the request, the spans of the operations are started from it.
+type Caller struct {
+	KeyID     string
+	Claims    *auth.Claims
+	ClientKey string
+	Logger    *slog.Logger
+	Context   context.Context
+}
+
+// CallerFromContext returns the caller of a request authenticated by middleware.Authenticate.
+func CallerFromContext(c *gin.Context) Caller {
+	caller := Caller{
+		KeyID:     c.GetString(middleware.APIKeyIDContextKey),
+		ClientKey: middleware.ClientKey(c),
+		Logger:    middleware.Logger(c),
+		Context:   c.Request.Context(),
+	}
+
+	if value, found := c.Get(middleware.ClaimsContextKey); found {
+		if claims, ok := value.(auth.Claims); ok {
+			caller.Claims = &claims
+		}
+	}
+
+	return caller
+}
+
+type callerContextKey struct{}
+
+// WithCaller returns a copy of the context carrying the caller, it is used by the
+// transports which do not go through gin.
+func WithCaller(ctx context.Context, caller Caller) context.Context {
+	return context.WithValue(ctx, callerContextKey{}, caller)
+}
+
+// CallerFrom returns the caller stored in the context by WithCaller, an anonymous caller otherwise.
+func CallerFrom(ctx context.Context) Caller {
+	caller, _ := ctx.Value(callerContextKey{}).(Caller)
+	return caller
+}
+
+// player returns the claims of the player token used by the caller, if any.
+func (caller Caller) player() (auth.Claims, bool) {
+	if caller.Claims == nil {
+		return auth.Claims{}, false
+	}
+
+	return *caller.Claims, true
+}
+
+// authorizeDeck returns errDeckForbidden when the caller is not allowed to perform the action
+// on the deck. Decks can only be used by the API key that created them, player tokens are
+// further limited to the decks of their game and to the actions allowed for their role:
+// errDealerOnly is returned for the actions of the dealer and errHandForbidden when a
+// spectator, who holds no hand, tries to draw.
+func authorizeDeck(caller Caller, deck model.Deck, action deckAction) error {
+	if deck.OwnerID != caller.KeyID {
+		return errDeckForbidden
+	}
+
+	claims, isPlayer := caller.player()
+
+	if !isPlayer {
+		return nil
+	}
+
+	if deck.GameID != claims.GameID {
+		return errDeckForbidden
+	}
+
+	switch action {
+	case deckActionDraw:
+		if claims.Role == auth.RoleSpectator {
+			return errHandForbidden
+		}
+	case deckActionManage:
+		if claims.Role != auth.RoleDealer {
+			return errDealerOnly
+		}
+	}
+
+	return nil
+}
+
+// storeNewDeck stores a deck created by the caller, it returns ErrDeckQuotaExceeded when the
+// client already keeps the maximum number of active decks. The store enforces the limit under
+// its lock so concurrent creations from one client can not exceed it.
+func storeNewDeck(caller Caller, deck model.Deck) error {
+	err := caller.store().CreateWithLimit(deck, maxActiveDecks)
+
+	if err == store.ErrQuotaExceeded {
+		return helper.ErrDeckQuotaExceeded.WithMessage(fmt.Sprintf("You have reached the limit of %d active decks, delete some before creating new ones", maxActiveDecks))
+	}
+
+	return err
+}
+
+// DeckError maps the error of a deck operation to the error of the catalogue sent to the client.
+// Expired decks are reported with 410 Gone during the grace period so clients can tell
+// them apart from IDs that never existed. Unknown errors are reported as ErrInternal, the
+// operations log them with Caller.failed.
+func DeckError(err error) helper.APIError {
+	if apiErr, ok := err.(helper.APIError); ok {
+		return apiErr
+	}
+
+	switch err {
+	case errDeckForbidden:
+		return helper.ErrDeckForbidden
+	case errHandForbidden:
+		return helper.ErrHandForbidden
+	case errDealerOnly:
+		return helper.ErrDealerOnly
+	case errInsufficientCards:
+		return helper.ErrInsufficientCards
+	case errDeckModified:
+		return helper.ErrDeckModified
+	case store.ErrDeckExpired:
+		return helper.ErrDeckExpired
+	case store.ErrDeckNotFound:
+		return helper.ErrDeckNotFound
+	}
+
+	return helper.ErrInternal
+}
+
+// deckLookupFailed sends the response for a deck operation that failed.
+func deckLookupFailed(c *gin.Context, err error) {
+	helper.SendError(c, DeckError(err))
+}
@@ -1,21 +1,47 @@
 package controller
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
-	"io/ioutil"
+	"io"
 	"math/rand"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/varadekd/card-game/auth"
 	"github.com/varadekd/card-game/helper"
+	"github.com/varadekd/card-game/metrics"
 	"github.com/varadekd/card-game/model"
-	"golang.org/x/exp/slices"
+	"github.com/varadekd/card-game/store"
 )
 
-var generatedDecks []model.Deck
+// deckStore holds every deck generated by the application.
+// TODO: Add database for handling such updating of data, currently the data is
+// stored in memory which will be vanished once the application is terminated.
+var deckStore store.DeckStore = NewMemoryStore(store.DefaultExpiryPolicy)
+
+// Store returns the store used by the deck handlers.
+func Store() store.DeckStore {
+	return deckStore
+}
+
+// UseStore replaces the store used by the deck handlers. It should be called
+// before the router starts serving requests.
+func UseStore(s store.DeckStore) {
+	deckStore = s
+}
+
+// maxActiveDecks caps how many decks a single client can keep at once, zero disables the cap.
+var maxActiveDecks = 0
+
+// SetMaxActiveDecks sets the cap on the decks a single client can keep at once.
+func SetMaxActiveDecks(max int) {
+	maxActiveDecks = max
+}
 
 func GeneratedDeck(c *gin.Context) {
 	payload := model.GenerateDeckPayload{}
@@ -24,70 +50,66 @@ func GeneratedDeck(c *gin.Context) {
 	err := c.ShouldBindJSON(&payload)
 
 	if err != nil {
-		fmt.Errorf("Got an error while parsing new deck payload. Error: %s", err.Error())
-		response.Success = false
-		response.Error = "User shared and invalid payload"
-		c.JSON(http.StatusBadRequest, response)
+		payloadRejected(c, err)
+		helper.SendError(c, helper.PayloadError(err))
 		return
 	}
 
-	defaultDeck := []model.Card{}
-
-	// Reading default deck from the file location
-	filePath, _ := helper.GetEnvVariable("DEFAULT_CARDS_FILE_STORAGE")
-
-	data, err := ioutil.ReadFile(filePath)
+	deck, err := CreateDeck(CallerFromContext(c), payload)
 
 	if err != nil {
-		fmt.Errorf("Got an error '%s' while reading the default deck", err.Error())
-		response.Success = false
-		response.Error = err.Error()
-		c.JSON(http.StatusInternalServerError, response)
+		deckLookupFailed(c, err)
 		return
 	}
 
-	err = json.Unmarshal(data, &defaultDeck)
+	response.Success = true
+	response.Data = versioned(c, deck)
+	c.JSON(http.StatusCreated, response)
+}
+
+// CreateDeck generates a new deck for the caller from the default deck. It is shared by every
+// transport, the payload is expected to be validated already.
+func CreateDeck(caller Caller, payload model.GenerateDeckPayload) (model.Deck, error) {
+	// The default deck is loaded once and kept in memory, the deck gets its own copy of the cards
+	catalogue, err := helper.DefaultCatalogue(caller.context())
 
 	if err != nil {
-		fmt.Errorf("Got an error '%s' while un marshalling the default deck", err.Error())
-		response.Success = false
-		response.Error = err.Error()
-		c.JSON(http.StatusInternalServerError, response)
-		return
+		caller.logger().Error("Unable to read the default deck", "game_id", payload.GameID, "error", err)
+		return model.Deck{}, helper.ErrDefaultDeckNotFound
 	}
 
-	if len(defaultDeck) <= 0 {
-		fmt.Errorf("Deck not found.")
-		response.Success = false
-		response.Error = "DeckID not found"
-		c.JSON(http.StatusInternalServerError, response)
-		return
+	// Player tokens can only create decks for their own game and only the dealer shuffles
+	if claims, isPlayer := caller.player(); isPlayer {
+		if claims.Role != auth.RoleDealer {
+			return model.Deck{}, caller.failed(errDealerOnly, "Unable to create the deck", "game_id", payload.GameID)
+		}
+
+		if payload.GameID != "" && payload.GameID != claims.GameID {
+			return model.Deck{}, caller.failed(errDeckForbidden, "Unable to create the deck", "game_id", payload.GameID)
+		}
+		payload.GameID = claims.GameID
 	}
 
 	newDeckID := uuid.New()
 
 	deck := model.Deck{
-		ID:      newDeckID,
-		Shuffle: payload.Shuffle,
-		GameID:  payload.GameID,
+		ID:        newDeckID,
+		Shuffle:   payload.Shuffle,
+		GameID:    payload.GameID,
+		OwnerID:   caller.KeyID,
+		CreatedBy: caller.ClientKey,
+		Version:   1,
 	}
 
 	if len(payload.Cards) > 0 {
-		for _, card := range defaultDeck {
-			if slices.Contains(payload.Cards, card.Code) {
-				deck.GeneratedDeck = append(deck.GeneratedDeck, card)
-			}
-		}
+		deck.GeneratedDeck = catalogue.Select(payload.Cards)
 	} else {
-		deck.GeneratedDeck = defaultDeck
+		deck.GeneratedDeck = catalogue.Cards()
 	}
 
 	// If shuffle is set to be true shuffling the generated cards using rand
 	if payload.Shuffle {
-		rand.Seed(time.Now().UnixNano())
-		rand.Shuffle(len(deck.GeneratedDeck), func(i, j int) {
-			deck.GeneratedDeck[i], deck.GeneratedDeck[j] = deck.GeneratedDeck[j], deck.GeneratedDeck[i]
-		})
+		shuffleCards(caller.context(), deck.GeneratedDeck)
 	}
 
 	// PlayingCards will have the value same as GeneratedCards since those cards are only been used by players.
@@ -96,11 +118,19 @@ func GeneratedDeck(c *gin.Context) {
 	deck.CardsRemaining = len(deck.PlayingCards)
 	deck.CreatedAt = time.Now()
 
-	generatedDecks = append(generatedDecks, deck)
+	err = storeNewDeck(caller, deck)
 
-	response.Success = true
-	response.Data = deck
-	c.JSON(http.StatusCreated, response)
+	if apiErr, ok := err.(helper.APIError); ok {
+		return model.Deck{}, caller.failed(apiErr, "Unable to create the deck", "game_id", payload.GameID)
+	}
+
+	if err != nil {
+		caller.logger().Error("Unable to store the deck", "deck_id", deck.ID, "game_id", deck.GameID, "error", err)
+		return model.Deck{}, helper.ErrInternal
+	}
+
+	metrics.DecksCreated.Inc(strconv.FormatBool(payload.Shuffle), deckCardsLabel(payload))
+	return deck, nil
 }
 
 func OpenDeck(c *gin.Context) {
@@ -108,104 +138,327 @@ func OpenDeck(c *gin.Context) {
 	deckID := c.Param("id")
 	response := helper.ResponseJSON{}
 
-	if deckID == "" {
-		response.Error = "DeckID is missing"
-		c.JSON(http.StatusBadRequest, response)
+	deck, err := FindDeck(CallerFromContext(c), deckID)
+
+	if err != nil {
+		deckLookupFailed(c, err)
 		return
 	}
 
-	_, err := uuid.Parse(deckID)
+	setDeckETag(c, deck)
 
-	if err != nil {
-		response.Error = "DeckID is invalid"
-		c.JSON(http.StatusBadRequest, response)
+	// Clients revalidating their copy of the deck get an empty 304 when it did not change
+	if ifNoneMatch := c.GetHeader("If-None-Match"); ifNoneMatch != "" && etagListContains(ifNoneMatch, DeckETag(deck), true) {
+		c.Status(http.StatusNotModified)
 		return
 	}
 
-	for _, deck := range generatedDecks {
-		if deckID == deck.ID.String() {
-			response.Success = true
-			response.Data = deck
-			c.JSON(http.StatusOK, response)
-			return
-		}
+	response.Success = true
+	response.Data = versioned(c, deck)
+	c.JSON(http.StatusOK, response)
+}
+
+// FindDeck returns the deck if the caller is allowed to look at it.
+func FindDeck(caller Caller, deckID string) (model.Deck, error) {
+	if err := checkDeckID(deckID); err != nil {
+		return model.Deck{}, caller.failed(err, "Unable to find the deck", "deck_id", deckID)
+	}
+
+	deck, err := caller.store().Get(deckID)
+
+	if err == nil {
+		err = authorizeDeck(caller, deck, deckActionView)
+	}
+
+	if err != nil {
+		return model.Deck{}, caller.failed(err, "Unable to find the deck", "deck_id", deckID, "game_id", deck.GameID)
 	}
 
-	response.Error = "DeckID not found"
-	c.JSON(http.StatusNotFound, response)
+	return deck, nil
 }
 
 func DrawCardsFromDeck(c *gin.Context) {
 	deckID := c.Param("id")
 	response := helper.ResponseJSON{}
 
-	if deckID == "" {
-		response.Error = "DeckID is missing"
-		c.JSON(http.StatusBadRequest, response)
+	if err := checkDeckID(deckID); err != nil {
+		deckLookupFailed(c, CallerFromContext(c).failed(err, "Unable to draw from the deck", "deck_id", deckID))
 		return
 	}
 
-	_, err := uuid.Parse(deckID)
+	// payload verification
+	payload := model.DrawCardFromDeckPayload{}
+
+	err := c.ShouldBindJSON(&payload)
 
 	if err != nil {
-		response.Error = "DeckID is invalid"
-		c.JSON(http.StatusBadRequest, response)
+		payloadRejected(c, err)
+		helper.SendError(c, helper.PayloadError(err))
 		return
 	}
 
-	// payload verification
-	payload := model.DrawCardFromDeckPayload{}
-
-	err = c.ShouldBindJSON(&payload)
+	drawnCards, deck, err := drawFromDeck(CallerFromContext(c), deckID, payload, c.GetHeader("If-Match"))
 
 	if err != nil {
-		fmt.Errorf("Got an error while parsing new deck payload. Error: %s", err.Error())
-		response.Error = "User shared and invalid payload"
-		c.JSON(http.StatusBadRequest, response)
+		deckLookupFailed(c, err)
 		return
 	}
 
-	deckFound := false
-	currentDeck := model.Deck{}
-	currentDeckIndex := 0
+	setDeckETag(c, deck)
+
+	response.Success = true
+	response.Data = versioned(c, drawnCards)
+	c.JSON(http.StatusOK, response)
+}
+
+// DrawFromDeck draws cards from the top of the deck into the hand of the player, players
+// holding a token always draw into their own hand while the dealer can deal into any hand.
+// The payload is expected to be validated already. When ifMatch is set the draw only happens
+// if it matches the entity tag of the deck.
+func DrawFromDeck(caller Caller, deckID string, payload model.DrawCardFromDeckPayload, ifMatch string) ([]model.Card, error) {
+	drawnCards, _, err := drawFromDeck(caller, deckID, payload, ifMatch)
+	return drawnCards, err
+}
+
+// drawFromDeck draws the cards and returns them with the updated deck. When ifMatch is set
+// the draw only happens if it matches the entity tag of the deck.
+func drawFromDeck(caller Caller, deckID string, payload model.DrawCardFromDeckPayload, ifMatch string) ([]model.Card, model.Deck, error) {
+	if err := checkDeckID(deckID); err != nil {
+		return nil, model.Deck{}, caller.failed(err, "Unable to draw from the deck", "deck_id", deckID)
+	}
+
+	drawnCards := []model.Card{}
+	hand, err := playerHand(caller, payload.PlayerID)
+
+	if err != nil {
+		claims, _ := caller.player()
+		return nil, model.Deck{}, caller.failed(err, "Unable to draw from the deck", "deck_id", deckID, "game_id", claims.GameID)
+	}
+
+	// The game of the deck is only known once it is loaded, it is logged when the draw fails
+	gameID := ""
+
+	deck, err := caller.store().Update(deckID, func(deck *model.Deck) error {
+		gameID = deck.GameID
+
+		if err := authorizeDeck(caller, *deck, deckActionDraw); err != nil {
+			return err
+		}
+
+		if err := checkIfMatch(ifMatch, *deck); err != nil {
+			return err
+		}
 
-	for index, deck := range generatedDecks {
-		if deckID == deck.ID.String() {
-			currentDeckIndex = index
-			currentDeck = deck
-			deckFound = true
-			break
+		if payload.CardsToBeDrawn > deck.CardsRemaining {
+			return errInsufficientCards
+		}
+
+		var remainingCards []model.Card
+		drawnCards, remainingCards = drawCards(deck.PlayingCards, payload.CardsToBeDrawn)
+
+		// Updating the current deck with remaining cards and updating count and time
+		deck.CardsRemaining = deck.CardsRemaining - payload.CardsToBeDrawn
+		deck.PlayingCards = remainingCards
+		deck.DeckLastUsed = time.Now()
+
+		if hand != "" {
+			if deck.Hands == nil {
+				deck.Hands = map[string][]model.Card{}
+			}
+			deck.Hands[hand] = append(deck.Hands[hand], drawnCards...)
 		}
+		return nil
+	})
+
+	if err == errInsufficientCards {
+		metrics.DrawConflicts.Inc()
+	}
+
+	if err != nil {
+		return nil, model.Deck{}, caller.failed(err, "Unable to draw from the deck", "deck_id", deckID, "game_id", gameID, "cards_requested", payload.CardsToBeDrawn)
 	}
 
-	if !deckFound {
-		fmt.Errorf("We where unable to find deck with the deck ID: %s", deckID)
-		response.Error = "DeckID not found"
-		c.JSON(http.StatusNotFound, response)
+	metrics.CardsDrawn.Add(float64(len(drawnCards)))
+
+	publishDeckUpdate(deck, model.DeckEvent{
+		Action: model.DeckActionDraw,
+		Round:  deck.Round,
+		At:     deck.DeckLastUsed,
+		Detail: fmt.Sprintf("%d cards drawn into hand %q", payload.CardsToBeDrawn, hand),
+	})
+
+	return drawnCards, deck, nil
+}
+
+// DiscardCardsFromDeck moves cards from the hand of a player to a pile of the deck and sends
+// the updated deck.
+func DiscardCardsFromDeck(c *gin.Context) {
+	deckID := c.Param("id")
+	response := helper.ResponseJSON{}
+
+	if err := checkDeckID(deckID); err != nil {
+		deckLookupFailed(c, CallerFromContext(c).failed(err, "Unable to discard the cards", "deck_id", deckID))
 		return
 	}
 
-	if payload.CardsToBeDrawn > currentDeck.CardsRemaining {
-		fmt.Errorf("Unable to draw cards from the deck, request %d cards but we only have %d remaining", payload.CardsToBeDrawn, currentDeck.CardsRemaining)
-		response.Error = "There are no more cards left to be drawn from the deck"
-		c.JSON(http.StatusConflict, response)
+	payload := model.DiscardCardsPayload{}
+
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		payloadRejected(c, err)
+		helper.SendError(c, helper.PayloadError(err))
 		return
 	}
 
-	drawnCards, remainingCards := drawCards(currentDeck.PlayingCards, payload.CardsToBeDrawn)
+	deck, err := DiscardCards(CallerFromContext(c), deckID, payload, c.GetHeader("If-Match"))
+
+	if err != nil {
+		deckLookupFailed(c, err)
+		return
+	}
 
-	// TODO: Add database for handling such updating of data, currently the data is
-	// stored in the variable which will be vanished once the application is terminated.
-	// Updating the current deck with remaining cards and updating count and time
-	generatedDecks[currentDeckIndex].CardsRemaining = currentDeck.CardsRemaining - payload.CardsToBeDrawn
-	generatedDecks[currentDeckIndex].PlayingCards = remainingCards
-	generatedDecks[currentDeckIndex].DeckLastUsed = time.Now()
+	setDeckETag(c, deck)
 
 	response.Success = true
-	response.Data = drawnCards
+	response.Data = versioned(c, deck)
 	c.JSON(http.StatusOK, response)
 }
 
+// DiscardCards moves the cards from the hand of the player to the pile of the deck and returns
+// the updated deck. Players holding a token can only discard from their own hand. When ifMatch
+// is set the cards are only discarded if it matches the entity tag of the deck.
+func DiscardCards(caller Caller, deckID string, payload model.DiscardCardsPayload, ifMatch string) (model.Deck, error) {
+	if err := checkDeckID(deckID); err != nil {
+		return model.Deck{}, caller.failed(err, "Unable to discard the cards", "deck_id", deckID)
+	}
+
+	hand, err := playerHand(caller, payload.PlayerID)
+
+	if err != nil {
+		claims, _ := caller.player()
+		return model.Deck{}, caller.failed(err, "Unable to discard the cards", "deck_id", deckID, "game_id", claims.GameID)
+	}
+
+	gameID := ""
+	now := time.Now()
+
+	deck, err := caller.store().Update(deckID, func(deck *model.Deck) error {
+		gameID = deck.GameID
+
+		if err := authorizeDeck(caller, *deck, deckActionDraw); err != nil {
+			return err
+		}
+
+		if err := checkIfMatch(ifMatch, *deck); err != nil {
+			return err
+		}
+
+		if _, ok := deck.Hands[hand]; !ok {
+			return helper.ErrCardNotInHand.WithMessage(fmt.Sprintf("Player %q holds no cards", hand))
+		}
+
+		held := append([]model.Card(nil), deck.Hands[hand]...)
+		discarded := make([]model.Card, 0, len(payload.Cards))
+
+		for _, code := range payload.Cards {
+			i := cardIndex(held, code)
+
+			if i < 0 {
+				return helper.ErrCardNotInHand.WithMessage(fmt.Sprintf("The card %s is not in the hand of player %q", code, hand))
+			}
+
+			discarded = append(discarded, held[i])
+			held = append(held[:i], held[i+1:]...)
+		}
+
+		if deck.Piles == nil {
+			deck.Piles = map[string][]model.Card{}
+		}
+
+		deck.Hands[hand] = held
+		deck.Piles[payload.Pile] = append(deck.Piles[payload.Pile], discarded...)
+		deck.DeckLastUsed = now
+		return nil
+	})
+
+	if err != nil {
+		return model.Deck{}, caller.failed(err, "Unable to discard the cards", "deck_id", deckID, "game_id", gameID, "pile", payload.Pile)
+	}
+
+	publishDeckUpdate(deck, model.DeckEvent{
+		Action: model.DeckActionDiscard,
+		Round:  deck.Round,
+		At:     now,
+		Detail: fmt.Sprintf("%d cards discarded from hand %q to pile %q", len(payload.Cards), hand, payload.Pile),
+	})
+
+	return deck, nil
+}
+
+// playerHand returns the hand the caller plays with, players holding a token other than the
+// dealer can only use their own hand.
+func playerHand(caller Caller, playerID string) (string, error) {
+	claims, isPlayer := caller.player()
+
+	if !isPlayer || claims.Role == auth.RoleDealer {
+		return playerID, nil
+	}
+
+	if playerID != "" && playerID != claims.PlayerID {
+		return "", errHandForbidden
+	}
+
+	return claims.PlayerID, nil
+}
+
+// cardIndex returns the position of the card with the code, or -1 when it is not in the cards.
+func cardIndex(cards []model.Card, code string) int {
+	for i, card := range cards {
+		if card.Code == code {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// checkDeckID returns an error of the catalogue when the deck ID is missing or is not a UUID.
+func checkDeckID(deckID string) error {
+	if deckID == "" {
+		return helper.ErrDeckIDMissing
+	}
+
+	if _, err := uuid.Parse(deckID); err != nil {
+		return helper.ErrDeckIDInvalid
+	}
+
+	return nil
+}
+
+// errInsufficientCards is returned from a deck update when the draw asks for more cards than remaining.
+var errInsufficientCards = errors.New("insufficient cards")
+
+// errDeckForbidden is returned when the caller is not the owner of the deck.
+var errDeckForbidden = errors.New("deck owned by another api key")
+
+// errHandForbidden is returned when a player tries to draw into the hand of another player.
+var errHandForbidden = errors.New("hand owned by another player")
+
+// errDealerOnly is returned when a player token without the dealer role tries an action
+// reserved to the dealer.
+var errDealerOnly = errors.New("action reserved to the dealer")
+
+// Deck operations checked against the role of a player token.
+type deckAction int
+
+const (
+	// deckActionView covers reading a deck, every role is allowed.
+	deckActionView deckAction = iota
+	// deckActionDraw covers drawing cards, spectators are not allowed.
+	deckActionDraw
+	// deckActionManage covers shuffling, resetting, cloning, peeking and deleting, only the dealer is allowed.
+	deckActionManage
+)
+
 // drawCards will allow us to fetch cards from the deck.
 // Currently this algorithm fetches the cards in array sequence.
 // The function returns the drawn cards and also returns the remaining cards left in deck.
@@ -220,3 +473,399 @@ func drawCards(cards []model.Card, cardsToBeDrawn int) ([]model.Card, []model.Ca
 
 	return drawnCards, remainingCards
 }
+
+// defaultListLimit is the page size used when listing decks without a limit,
+// the maximum of 100 is enforced by the binding tag of ListDecksPayload.
+const defaultListLimit = 20
+
+// ListDecks returns the decks matching the filters in the query string, one page at a time.
+func ListDecks(c *gin.Context) {
+	payload := model.ListDecksPayload{}
+	response := helper.ResponseJSON{}
+
+	err := c.ShouldBindQuery(&payload)
+
+	if err != nil {
+		payloadRejected(c, err)
+		helper.SendError(c, helper.QueryError(err))
+		return
+	}
+
+	page, err := FindDecks(CallerFromContext(c), payload)
+
+	if err != nil {
+		deckLookupFailed(c, err)
+		return
+	}
+
+	response.Success = true
+	response.Data = versioned(c, page)
+	c.JSON(http.StatusOK, response)
+}
+
+// FindDecks returns a page of the decks of the caller matching the filters. Player tokens only
+// see the decks of their own game. The payload is expected to be validated already.
+func FindDecks(caller Caller, payload model.ListDecksPayload) (store.DeckPage, error) {
+	query := store.DeckQuery{
+		OwnerID:        caller.KeyID,
+		GameID:         payload.GameID,
+		Shuffle:        payload.Shuffle,
+		CreatedAfter:   payload.CreatedAfter,
+		CreatedBefore:  payload.CreatedBefore,
+		LastUsedAfter:  payload.LastUsedAfter,
+		LastUsedBefore: payload.LastUsedBefore,
+		Status:         payload.Status,
+		SortBy:         strings.TrimPrefix(payload.Sort, "-"),
+		Descending:     strings.HasPrefix(payload.Sort, "-"),
+		Limit:          payload.Limit,
+		Cursor:         payload.Cursor,
+	}
+
+	if query.Limit == 0 {
+		query.Limit = defaultListLimit
+	}
+
+	if claims, isPlayer := caller.player(); isPlayer {
+		if query.GameID != "" && query.GameID != claims.GameID {
+			return store.DeckPage{}, caller.failed(errDeckForbidden, "Unable to list the decks", "game_id", query.GameID)
+		}
+		query.GameID = claims.GameID
+	}
+
+	page, err := caller.store().List(query)
+
+	if err == store.ErrInvalidCursor {
+		err = helper.ErrInvalidQuery.WithDetails(helper.FieldError{Field: "cursor", Reason: "is invalid"})
+	}
+
+	if err != nil {
+		return store.DeckPage{}, caller.failed(err, "Unable to list the decks", "game_id", query.GameID)
+	}
+
+	return page, nil
+}
+
+// DeleteDeck removes a single deck.
+func DeleteDeck(c *gin.Context) {
+	deckID := c.Param("id")
+	response := helper.ResponseJSON{}
+
+	caller := CallerFromContext(c)
+	_, err := uuid.Parse(deckID)
+
+	if err != nil {
+		deckLookupFailed(c, caller.failed(helper.ErrDeckIDInvalid, "Unable to delete the deck", "deck_id", deckID))
+		return
+	}
+
+	deck, err := caller.store().Get(deckID)
+
+	if err == nil {
+		err = authorizeDeck(caller, deck, deckActionManage)
+	}
+
+	if err == nil {
+		err = caller.store().Delete(deckID)
+	}
+
+	if err != nil {
+		deckLookupFailed(c, caller.failed(err, "Unable to delete the deck", "deck_id", deckID, "game_id", deck.GameID))
+		return
+	}
+
+	publishDeckUpdate(deck, model.DeckEvent{Action: model.DeckActionDelete, Round: deck.Round, At: time.Now()})
+
+	response.Success = true
+	response.Data = model.DeleteDecksResult{Deleted: 1}
+	c.JSON(http.StatusOK, response)
+}
+
+// DeleteDecksByGame removes every deck of the game passed in the gameID query parameter.
+// The gameID is mandatory so a bare DELETE /deck never wipes the whole store.
+func DeleteDecksByGame(c *gin.Context) {
+	gameID := c.Query("gameID")
+	response := helper.ResponseJSON{}
+	caller := CallerFromContext(c)
+
+	if gameID == "" {
+		deckLookupFailed(c, caller.failed(helper.ErrGameIDMissing, "Unable to delete the decks of the game"))
+		return
+	}
+
+	deleted, err := DeleteGame(caller, gameID)
+
+	if err != nil {
+		deckLookupFailed(c, err)
+		return
+	}
+
+	response.Success = true
+	response.Data = model.DeleteDecksResult{Deleted: deleted}
+	c.JSON(http.StatusOK, response)
+}
+
+// DeleteGame removes every deck of the game the caller owns and returns how many were removed.
+// The clients watching the decks are told they were deleted.
+func DeleteGame(caller Caller, gameID string) (int, error) {
+	if claims, isPlayer := caller.player(); isPlayer && claims.Role != auth.RoleDealer {
+		return 0, caller.failed(errDealerOnly, "Unable to delete the decks of the game", "game_id", gameID)
+	}
+
+	if claims, isPlayer := caller.player(); isPlayer && claims.GameID != gameID {
+		return 0, caller.failed(errDeckForbidden, "Unable to delete the decks of the game", "game_id", gameID)
+	}
+
+	deleted, err := caller.store().DeleteByGame(gameID, caller.KeyID)
+
+	if err != nil {
+		return 0, caller.failed(err, "Unable to delete the decks of the game", "game_id", gameID)
+	}
+
+	now := time.Now()
+
+	for _, deck := range deleted {
+		publishDeckUpdate(deck, model.DeckEvent{Action: model.DeckActionDelete, Round: deck.Round, At: now})
+	}
+
+	return len(deleted), nil
+}
+
+// CloneDeck creates a new deck with the same generated order as the source deck, so several
+// tables can play the identical shuffled deck.
+func CloneDeck(c *gin.Context) {
+	deckID := c.Param("id")
+	response := helper.ResponseJSON{}
+
+	caller := CallerFromContext(c)
+	_, err := uuid.Parse(deckID)
+
+	if err != nil {
+		deckLookupFailed(c, caller.failed(helper.ErrDeckIDInvalid, "Unable to clone the deck", "deck_id", deckID))
+		return
+	}
+
+	// The payload is optional, an empty body clones the deck with the defaults
+	payload := model.CloneDeckPayload{}
+
+	err = c.ShouldBindJSON(&payload)
+
+	if err != nil && err != io.EOF {
+		payloadRejected(c, err)
+		helper.SendError(c, helper.PayloadError(err))
+		return
+	}
+
+	source, err := caller.store().Get(deckID)
+
+	if err == nil {
+		err = authorizeDeck(caller, source, deckActionManage)
+	}
+
+	// A dealer token can only clone into its own game
+	if claims, isPlayer := caller.player(); err == nil && isPlayer && payload.GameID != "" && payload.GameID != claims.GameID {
+		err = errDeckForbidden
+	}
+
+	if err != nil {
+		deckLookupFailed(c, caller.failed(err, "Unable to clone the deck", "deck_id", deckID, "game_id", source.GameID))
+		return
+	}
+
+	clone := model.Deck{
+		ID:            uuid.New(),
+		GameID:        source.GameID,
+		Shuffle:       source.Shuffle,
+		GeneratedDeck: source.GeneratedDeck,
+		PlayingCards:  source.GeneratedDeck,
+		DeckSize:      source.DeckSize,
+		CreatedAt:     time.Now(),
+		SourceDeckID:  source.ID.String(),
+		OwnerID:       caller.KeyID,
+		CreatedBy:     caller.ClientKey,
+		Version:       1,
+	}
+
+	if payload.GameID != "" {
+		clone.GameID = payload.GameID
+	}
+
+	if payload.CopyState {
+		clone.PlayingCards = source.PlayingCards
+	}
+
+	clone.CardsRemaining = len(clone.PlayingCards)
+
+	err = storeNewDeck(caller, clone)
+
+	if apiErr, ok := err.(helper.APIError); ok {
+		deckLookupFailed(c, caller.failed(apiErr, "Unable to clone the deck", "deck_id", deckID, "game_id", clone.GameID))
+		return
+	}
+
+	if err != nil {
+		deckLookupFailed(c, caller.failed(err, "Unable to store the clone of the deck", "deck_id", deckID, "clone_id", clone.ID, "game_id", clone.GameID))
+		return
+	}
+
+	response.Success = true
+	response.Data = versioned(c, clone)
+	c.JSON(http.StatusCreated, response)
+}
+
+// ResetDeck puts every generated card back in play and empties the hands so a table can play
+// many hands on one deck. Each reset starts a new round and is recorded in the deck's audit trail.
+func ResetDeck(c *gin.Context) {
+	deckID := c.Param("id")
+	response := helper.ResponseJSON{}
+
+	caller := CallerFromContext(c)
+	_, err := uuid.Parse(deckID)
+
+	if err != nil {
+		deckLookupFailed(c, caller.failed(helper.ErrDeckIDInvalid, "Unable to reset the deck", "deck_id", deckID))
+		return
+	}
+
+	// The payload is optional, an empty body resets the deck to the generated order
+	payload := model.ResetDeckPayload{}
+
+	err = c.ShouldBindJSON(&payload)
+
+	if err != nil && err != io.EOF {
+		payloadRejected(c, err)
+		helper.SendError(c, helper.PayloadError(err))
+		return
+	}
+
+	ifMatch := c.GetHeader("If-Match")
+	gameID := ""
+
+	deck, err := caller.store().Update(deckID, func(deck *model.Deck) error {
+		gameID = deck.GameID
+
+		if err := authorizeDeck(caller, *deck, deckActionManage); err != nil {
+			return err
+		}
+
+		if err := checkIfMatch(ifMatch, *deck); err != nil {
+			return err
+		}
+
+		now := time.Now()
+
+		deck.PlayingCards = append([]model.Card(nil), deck.GeneratedDeck...)
+
+		if payload.Shuffle {
+			shuffleCards(caller.context(), deck.PlayingCards)
+		}
+
+		deck.CardsRemaining = len(deck.PlayingCards)
+		deck.Hands = nil
+		deck.Piles = nil
+		deck.Round++
+		deck.DeckLastUsed = now
+		deck.AuditTrail = append(deck.AuditTrail, model.DeckEvent{
+			Action: model.DeckActionReset,
+			Round:  deck.Round,
+			At:     now,
+			Detail: fmt.Sprintf("shuffle=%t", payload.Shuffle),
+		})
+		return nil
+	})
+
+	if err != nil {
+		deckLookupFailed(c, caller.failed(err, "Unable to reset the deck", "deck_id", deckID, "game_id", gameID))
+		return
+	}
+
+	publishDeckUpdate(deck, deck.AuditTrail[len(deck.AuditTrail)-1])
+	setDeckETag(c, deck)
+
+	response.Success = true
+	response.Data = versioned(c, deck)
+	c.JSON(http.StatusOK, response)
+}
+
+// Positions a deck can be peeked from, cards are drawn from the top.
+const (
+	peekFromTop    = "top"
+	peekFromBottom = "bottom"
+)
+
+// PeekDeck returns cards from the deck without drawing them. Only the dealer is
+// allowed to peek and every peek is recorded in the deck's audit trail.
+func PeekDeck(c *gin.Context) {
+	deckID := c.Param("id")
+	response := helper.ResponseJSON{}
+
+	caller := CallerFromContext(c)
+	_, err := uuid.Parse(deckID)
+
+	if err != nil {
+		deckLookupFailed(c, caller.failed(helper.ErrDeckIDInvalid, "Unable to peek at the deck", "deck_id", deckID))
+		return
+	}
+
+	payload := model.PeekDeckPayload{Count: 1, From: peekFromTop}
+
+	err = c.ShouldBindQuery(&payload)
+
+	if err != nil {
+		payloadRejected(c, err)
+		helper.SendError(c, helper.QueryError(err))
+		return
+	}
+
+	peekedCards := []model.Card{}
+	gameID := ""
+
+	// Peeking does not change the deck, only the audit trail records it so the ETag stays valid
+	_, err = caller.store().AppendAudit(deckID, func(deck model.Deck) (model.DeckEvent, error) {
+		gameID = deck.GameID
+
+		if err := authorizeDeck(caller, deck, deckActionManage); err != nil {
+			return model.DeckEvent{}, err
+		}
+
+		if payload.Count > deck.CardsRemaining {
+			return model.DeckEvent{}, errInsufficientCards
+		}
+
+		peekedCards = peekCards(deck.PlayingCards, payload.Count, payload.From)
+
+		return model.DeckEvent{
+			Action: model.DeckActionPeek,
+			Round:  deck.Round,
+			At:     time.Now(),
+			Detail: fmt.Sprintf("count=%d from=%s", payload.Count, payload.From),
+		}, nil
+	})
+
+	if err == errInsufficientCards {
+		err = helper.ErrInsufficientCards.WithMessage("There are not enough cards left in the deck to peek at")
+	}
+
+	if err != nil {
+		deckLookupFailed(c, caller.failed(err, "Unable to peek at the deck", "deck_id", deckID, "game_id", gameID))
+		return
+	}
+
+	response.Success = true
+	response.Data = versioned(c, peekedCards)
+	c.JSON(http.StatusOK, response)
+}
+
+// peekCards returns a copy of count cards from the top or the bottom of the deck.
+// Cards from the bottom are returned starting with the bottom-most card.
+func peekCards(cards []model.Card, count int, from string) []model.Card {
+	peeked := make([]model.Card, 0, count)
+
+	if from == peekFromBottom {
+		for i := len(cards) - 1; i >= len(cards)-count; i-- {
+			peeked = append(peeked, cards[i])
+		}
+		return peeked
+	}
+
+	return append(peeked, cards[:count]...)
+}
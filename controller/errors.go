@@ -0,0 +1,17 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/varadekd/card-game/helper"
+)
+
+// ListErrors returns the error catalogue so clients can map every error code the api sends.
+func ListErrors(c *gin.Context) {
+	response := helper.ResponseJSON{}
+
+	response.Success = true
+	response.Data = helper.ErrorCatalogue
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,65 @@
+package controller
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/varadekd/card-game/model"
+)
+
+// errDeckModified is returned from a deck update when the If-Match header of the request
+// does not match the current version of the deck.
+var errDeckModified = errors.New("deck modified since it was read")
+
+// DeckETag returns the entity tag of the deck, it changes with every version of the deck.
+func DeckETag(deck model.Deck) string {
+	return VersionETag(deck.Version)
+}
+
+// VersionETag returns the entity tag of a version of a deck, for callers that only know the
+// version such as gRPC clients.
+func VersionETag(version int) string {
+	return strconv.Quote(strconv.Itoa(version))
+}
+
+// setDeckETag sends the entity tag of the deck in the ETag header.
+func setDeckETag(c *gin.Context, deck model.Deck) {
+	c.Header("ETag", DeckETag(deck))
+}
+
+// checkIfMatch returns errDeckModified when the If-Match header is set and none of its
+// entity tags is the one of the deck. Weak tags never match, as required for If-Match.
+func checkIfMatch(ifMatch string, deck model.Deck) error {
+	if ifMatch == "" || etagListContains(ifMatch, DeckETag(deck), false) {
+		return nil
+	}
+
+	return errDeckModified
+}
+
+// etagListContains reports whether the comma separated list of entity tags of a conditional
+// header contains the tag, "*" matches any tag. Weak tags are only compared when weak is set.
+func etagListContains(list, etag string, weak bool) bool {
+	for _, candidate := range strings.Split(list, ",") {
+		candidate = strings.TrimSpace(candidate)
+
+		if candidate == "*" {
+			return true
+		}
+
+		if strings.HasPrefix(candidate, "W/") {
+			if !weak {
+				continue
+			}
+			candidate = strings.TrimPrefix(candidate, "W/")
+		}
+
+		if candidate == etag {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,67 @@
+package controller
+
+import (
+	"sort"
+	"time"
+
+	"github.com/varadekd/card-game/events"
+	"github.com/varadekd/card-game/model"
+	"github.com/varadekd/card-game/store"
+)
+
+// deckEvents fans out the changes of the decks to the clients watching them.
+var deckEvents = events.NewBroker()
+
+// Events returns the broker the deck changes are published to.
+func Events() *events.Broker {
+	return deckEvents
+}
+
+// publishDeckUpdate notifies the clients watching the deck that it changed.
+func publishDeckUpdate(deck model.Deck, event model.DeckEvent) {
+	deckEvents.Publish(model.DeckUpdate{DeckID: deck.ID, Event: event, CardsRemaining: deck.CardsRemaining})
+}
+
+// DeckHistory returns the audit trail of the deck with the draws and discards published for
+// it since the application started, oldest first. Only the latest events.HistorySize events
+// published are kept, the audit trail is stored with the deck.
+func DeckHistory(deck model.Deck) []model.DeckEvent {
+	history := append([]model.DeckEvent(nil), deck.AuditTrail...)
+
+	for _, event := range deckEvents.History(deck.ID.String()) {
+		if event.Action == model.DeckActionDraw || event.Action == model.DeckActionDiscard {
+			history = append(history, event)
+		}
+	}
+
+	sort.SliceStable(history, func(i, j int) bool { return history[i].At.Before(history[j].At) })
+	return history
+}
+
+// DeckExpired notifies the clients watching the deck that the expiry policy removed it. It is
+// the OnExpire hook of the stores returned by NewMemoryStore.
+func DeckExpired(deck model.Deck) {
+	publishDeckUpdate(deck, model.DeckEvent{Action: model.DeckActionExpire, Round: deck.Round, At: time.Now()})
+}
+
+// NewMemoryStore returns a memory store telling the clients watching its decks when they expire.
+func NewMemoryStore(policy store.ExpiryPolicy) *store.MemoryStore {
+	s := store.NewMemoryStore(policy)
+	s.OnExpire = DeckExpired
+	return s
+}
+
+// WatchDeck subscribes the caller to the changes of the deck once it is allowed to look at it.
+// The channel is closed when the returned function is called, the last update of a deck is
+// the delete or the expiry, see model.DeckUpdate.Last.
+func WatchDeck(caller Caller, deckID string) (<-chan model.DeckUpdate, func(), error) {
+	// Subscribing before the lookup so no change made in between is missed
+	updates, stop := deckEvents.Subscribe(deckID, events.DefaultBuffer)
+
+	if _, err := FindDeck(caller, deckID); err != nil {
+		stop()
+		return nil, nil, err
+	}
+
+	return updates, stop, nil
+}
@@ -0,0 +1,109 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/gin-gonic/gin"
+	"github.com/varadekd/card-game/buildinfo"
+	"github.com/varadekd/card-game/health"
+	"github.com/varadekd/card-game/helper"
+	"github.com/varadekd/card-game/store"
+)
+
+// deckSweeper removes the expired decks in the background, it is nil until UseSweeper is called.
+var deckSweeper *store.Sweeper
+
+// UseSweeper sets the sweeper whose state is reported by the readiness checks.
+func UseSweeper(s *store.Sweeper) {
+	deckSweeper = s
+}
+
+// Healthz tells the application is alive, it never looks at its dependencies.
+func Healthz(c *gin.Context) {
+	response := helper.ResponseJSON{}
+
+	response.Success = true
+	response.Data = gin.H{"status": health.StatusOK}
+	c.JSON(http.StatusOK, response)
+}
+
+// Readyz runs the readiness checks and answers 503 with the failing ones when the
+// application can not serve requests.
+func Readyz(checks []health.Check) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		report := health.Run(checks)
+
+		if !report.Ready() {
+			c.JSON(helper.ErrNotReady.Status, helper.ResponseJSON{
+				Success:   false,
+				Error:     helper.ErrNotReady.Message,
+				ErrorCode: helper.ErrNotReady.Code,
+				Data:      report,
+			})
+			return
+		}
+
+		response := helper.ResponseJSON{}
+
+		response.Success = true
+		response.Data = report
+		c.JSON(http.StatusOK, response)
+	}
+}
+
+// Version returns the build metadata of the application.
+func Version(c *gin.Context) {
+	response := helper.ResponseJSON{}
+
+	response.Success = true
+	response.Data = buildinfo.Get()
+	c.JSON(http.StatusOK, response)
+}
+
+// ReadinessChecks returns the checks run by Readyz: the default deck can be read, the deck
+// store answers and the snapshot file, if any, can be written, and the sweeper is running.
+func ReadinessChecks(snapshotFile string) []health.Check {
+	return []health.Check{
+		{Name: "defaultDeck", Run: checkDefaultDeck},
+		{Name: "storage", Run: func() error { return checkStorage(snapshotFile) }},
+		{Name: "sweeper", Run: checkSweeper},
+	}
+}
+
+func checkDefaultDeck() error {
+	_, err := helper.DefaultCatalogue(context.Background())
+	return err
+}
+
+func checkStorage(snapshotFile string) error {
+	if _, err := deckStore.Count(store.DeckQuery{}); err != nil {
+		return err
+	}
+
+	if snapshotFile == "" {
+		return nil
+	}
+
+	// The decks are saved next to the snapshot file first, see store.SaveSnapshot
+	probe, err := os.CreateTemp(filepath.Dir(snapshotFile), filepath.Base(snapshotFile)+".*.probe")
+
+	if err != nil {
+		return fmt.Errorf("the snapshot directory is not writable: %w", err)
+	}
+
+	probe.Close()
+	return os.Remove(probe.Name())
+}
+
+func checkSweeper() error {
+	if deckSweeper == nil || !deckSweeper.Running() {
+		return errors.New("the sweeper removing expired decks is not running")
+	}
+
+	return nil
+}
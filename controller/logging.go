@@ -0,0 +1,41 @@
+package controller
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/varadekd/card-game/middleware"
+)
+
+// logger returns the logger of the request made by the caller, the default logger for
+// callers built without one.
+func (caller Caller) logger() *slog.Logger {
+	if caller.Logger == nil {
+		return slog.Default()
+	}
+
+	return caller.Logger
+}
+
+// failed logs why a deck operation failed and returns err unchanged. The failures caused by
+// the client are logged at info level, the others at error level. attrs usually hold the
+// deck_id and game_id of the operation.
+func (caller Caller) failed(err error, msg string, attrs ...any) error {
+	apiErr := DeckError(err)
+	level := slog.LevelInfo
+
+	if apiErr.Status >= http.StatusInternalServerError {
+		level = slog.LevelError
+	}
+
+	attrs = append(attrs, "error_code", apiErr.Code, "error", err.Error(), "key_id", caller.KeyID)
+	caller.logger().Log(context.Background(), level, msg, attrs...)
+	return err
+}
+
+// payloadRejected logs why the payload or the query of a request was rejected.
+func payloadRejected(c *gin.Context, err error) {
+	middleware.Logger(c).Info("Rejected the request payload", "deck_id", c.Param("id"), "error", err.Error())
+}
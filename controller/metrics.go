@@ -0,0 +1,29 @@
+package controller
+
+import (
+	"github.com/varadekd/card-game/metrics"
+	"github.com/varadekd/card-game/model"
+	"github.com/varadekd/card-game/store"
+)
+
+func init() {
+	// Reading the store on every scrape so the gauge follows UseStore and the expiry policy
+	metrics.ActiveDecks.Set(func() float64 {
+		count, err := deckStore.Count(store.DeckQuery{})
+
+		if err != nil {
+			return 0
+		}
+
+		return float64(count)
+	})
+}
+
+// deckCardsLabel tells whether the client picked the cards of the deck or got the default deck.
+func deckCardsLabel(payload model.GenerateDeckPayload) string {
+	if len(payload.Cards) > 0 {
+		return "custom"
+	}
+
+	return "default"
+}
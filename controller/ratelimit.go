@@ -0,0 +1,46 @@
+package controller
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/varadekd/card-game/helper"
+	"github.com/varadekd/card-game/middleware"
+)
+
+// createLimiter and drawLimiter limit deck creation and drawing per client. The REST routes
+// apply them with middleware.RateLimit, the other transports with AllowCreate and AllowDraw.
+var createLimiter, drawLimiter *middleware.RateLimiter
+
+// UseRateLimiters sets the limiters of deck creation and drawing shared by every transport, so
+// a client gets the same budget whether it uses REST, gRPC or GraphQL. Nil limiters disable the limits.
+func UseRateLimiters(create, draw *middleware.RateLimiter) {
+	createLimiter, drawLimiter = create, draw
+}
+
+// AllowCreate takes a token from the creation bucket of the caller, it returns ErrRateLimited
+// when the bucket is empty.
+func AllowCreate(caller Caller) error {
+	return allow(createLimiter, caller)
+}
+
+// AllowDraw takes a token from the draw bucket of the caller, it returns ErrRateLimited when
+// the bucket is empty.
+func AllowDraw(caller Caller) error {
+	return allow(drawLimiter, caller)
+}
+
+func allow(limiter *middleware.RateLimiter, caller Caller) error {
+	if limiter == nil {
+		return nil
+	}
+
+	allowed, _, retryAfter := limiter.Allow(caller.ClientKey)
+
+	if allowed {
+		return nil
+	}
+
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	return caller.failed(helper.ErrRateLimited.WithMessage(fmt.Sprintf("Rate limit exceeded, retry in %d seconds", seconds)), "Rejected the call over the rate limit", "client", caller.ClientKey)
+}
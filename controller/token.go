@@ -0,0 +1,62 @@
+package controller
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/varadekd/card-game/auth"
+	"github.com/varadekd/card-game/helper"
+	"github.com/varadekd/card-game/middleware"
+	"github.com/varadekd/card-game/model"
+)
+
+// tokenSigner signs the player tokens, it is nil when player tokens are disabled.
+var tokenSigner *auth.JWTSigner
+
+// UseTokenSigner sets the signer used for minting player tokens.
+func UseTokenSigner(signer *auth.JWTSigner) {
+	tokenSigner = signer
+}
+
+// IssuePlayerToken mints a short-lived token binding a player to a game and a role.
+// The token acts on behalf of the API key that requested it.
+func IssuePlayerToken(c *gin.Context) {
+	payload := model.IssueTokenPayload{}
+	response := helper.ResponseJSON{}
+
+	err := c.ShouldBindJSON(&payload)
+
+	if err != nil {
+		middleware.Reject(c, helper.PayloadError(err), "Rejected the payload of a token request")
+		return
+	}
+
+	if tokenSigner == nil {
+		middleware.Reject(c, helper.ErrTokensDisabled, "Rejected a token request while player tokens are disabled")
+		return
+	}
+
+	token, claims, err := tokenSigner.Issue(auth.Claims{
+		PlayerID: payload.PlayerID,
+		GameID:   payload.GameID,
+		Role:     payload.Role,
+		OwnerID:  CallerFromContext(c).KeyID,
+	}, time.Duration(payload.TTLSeconds)*time.Second)
+
+	if err != nil {
+		middleware.Logger(c).Error("Unable to issue a player token", "player_id", payload.PlayerID, "game_id", payload.GameID, "error", err)
+		helper.SendError(c, helper.ErrInternal)
+		return
+	}
+
+	response.Success = true
+	response.Data = model.PlayerToken{
+		Token:     token,
+		PlayerID:  claims.PlayerID,
+		GameID:    claims.GameID,
+		Role:      claims.Role,
+		ExpiresAt: time.Unix(claims.ExpiresAt, 0),
+	}
+	c.JSON(http.StatusCreated, response)
+}
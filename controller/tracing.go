@@ -0,0 +1,38 @@
+package controller
+
+import (
+	"context"
+	"math/rand"
+	"time"
+
+	"github.com/varadekd/card-game/model"
+	"github.com/varadekd/card-game/store"
+	"github.com/varadekd/card-game/tracing"
+)
+
+// context returns the context of the request made by the caller, it holds the span of the
+// request. A background context is returned for callers built without one.
+func (caller Caller) context() context.Context {
+	if caller.Context == nil {
+		return context.Background()
+	}
+
+	return caller.Context
+}
+
+// store returns the deck store tracing its operations in the trace of the caller's request.
+func (caller Caller) store() store.DeckStore {
+	return tracing.Store(caller.context(), deckStore)
+}
+
+// shuffleCards shuffles the cards in place using rand, the shuffle is traced as a child of
+// the span held by ctx.
+func shuffleCards(ctx context.Context, cards []model.Card) {
+	_, span := tracing.Start(ctx, "cards.shuffle", tracing.CardCountKey.Int(len(cards)))
+	defer span.End()
+
+	rand.Seed(time.Now().UnixNano())
+	rand.Shuffle(len(cards), func(i, j int) {
+		cards[i], cards[j] = cards[j], cards[i]
+	})
+}
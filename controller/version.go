@@ -0,0 +1,27 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/varadekd/card-game/middleware"
+	"github.com/varadekd/card-game/model"
+	"github.com/varadekd/card-game/store"
+)
+
+// versioned returns the data of a response in the shape of the api version of the route,
+// decks, cards and pages keep their original JSON names on the v1 routes.
+func versioned(c *gin.Context, data interface{}) interface{} {
+	if middleware.Version(c) != 1 {
+		return data
+	}
+
+	switch value := data.(type) {
+	case model.Deck:
+		return model.NewDeckV1(value)
+	case []model.Card:
+		return model.NewCardsV1(value)
+	case store.DeckPage:
+		return value.V1()
+	}
+
+	return data
+}
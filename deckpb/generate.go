@@ -0,0 +1,4 @@
+// Package deckpb holds the protobuf messages and the gRPC service generated from proto/deck.proto.
+package deckpb
+
+//go:generate protoc -I ../proto --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative deck.proto
@@ -0,0 +1,150 @@
+package events
+
+import (
+	"sync"
+
+	"github.com/varadekd/card-game/model"
+)
+
+// DefaultBuffer is how many updates a subscriber can fall behind before it is dropped.
+const DefaultBuffer = 64
+
+// HistorySize is how many of the latest events of a deck are kept by the broker.
+const HistorySize = 50
+
+// Broker fans out the updates of a deck to the clients watching it. Publishing never
+// blocks, a subscriber that falls more than its buffer behind is dropped and its
+// channel closed so the client can reconnect and reload the deck. The latest events of
+// every deck are kept until its last update, see History.
+type Broker struct {
+	mu          sync.Mutex
+	subscribers map[string]map[chan model.DeckUpdate]struct{}
+	history     map[string][]model.DeckEvent
+	closed      bool
+}
+
+// NewBroker returns a broker without subscribers.
+func NewBroker() *Broker {
+	return &Broker{
+		subscribers: map[string]map[chan model.DeckUpdate]struct{}{},
+		history:     map[string][]model.DeckEvent{},
+	}
+}
+
+// Subscribe returns a channel receiving the updates of the deck and a function to
+// stop receiving them. The channel is closed once unsubscribed, it is closed right away
+// when the broker is closed.
+func (b *Broker) Subscribe(deckID string, buffer int) (<-chan model.DeckUpdate, func()) {
+	if buffer <= 0 {
+		buffer = DefaultBuffer
+	}
+
+	ch := make(chan model.DeckUpdate, buffer)
+
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		close(ch)
+		return ch, func() {}
+	}
+
+	if b.subscribers[deckID] == nil {
+		b.subscribers[deckID] = map[chan model.DeckUpdate]struct{}{}
+	}
+	b.subscribers[deckID][ch] = struct{}{}
+	b.mu.Unlock()
+
+	var once sync.Once
+
+	return ch, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			b.remove(deckID, ch)
+		})
+	}
+}
+
+// Publish sends the update to every subscriber of its deck and adds its event to the
+// history of the deck, the history is dropped with the last update of the deck.
+func (b *Broker) Publish(update model.DeckUpdate) {
+	deckID := update.DeckID.String()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if update.Last() {
+		delete(b.history, deckID)
+	} else {
+		history := append(b.history[deckID], update.Event)
+
+		if len(history) > HistorySize {
+			history = append([]model.DeckEvent(nil), history[len(history)-HistorySize:]...)
+		}
+
+		b.history[deckID] = history
+	}
+
+	for ch := range b.subscribers[deckID] {
+		select {
+		case ch <- update:
+		default:
+			b.remove(deckID, ch)
+		}
+	}
+}
+
+// Close closes the channel of every subscriber and of the ones subscribing later, it is
+// called when the application shuts down. Use Closed to tell it apart from a subscriber
+// dropped for falling behind.
+func (b *Broker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+
+	for deckID, subscribers := range b.subscribers {
+		for ch := range subscribers {
+			b.remove(deckID, ch)
+		}
+	}
+}
+
+// Closed reports whether Close was called.
+func (b *Broker) Closed() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.closed
+}
+
+// History returns the latest events published for the deck, oldest first. At most
+// HistorySize events are kept and they are lost when the application restarts.
+func (b *Broker) History(deckID string) []model.DeckEvent {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return append([]model.DeckEvent(nil), b.history[deckID]...)
+}
+
+// Subscribers returns how many clients are watching the deck.
+func (b *Broker) Subscribers(deckID string) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return len(b.subscribers[deckID])
+}
+
+// remove closes the channel of the subscriber if it is still subscribed. The caller must hold the lock.
+func (b *Broker) remove(deckID string, ch chan model.DeckUpdate) {
+	if _, found := b.subscribers[deckID][ch]; !found {
+		return
+	}
+
+	delete(b.subscribers[deckID], ch)
+	close(ch)
+
+	if len(b.subscribers[deckID]) == 0 {
+		delete(b.subscribers, deckID)
+	}
+}
@@ -0,0 +1,35 @@
+package gqlapi
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	graphql "github.com/graph-gophers/graphql-go"
+	"github.com/varadekd/card-game/controller"
+	"github.com/varadekd/card-game/helper"
+)
+
+// Request is the body of a GraphQL query or mutation sent over HTTP.
+type Request struct {
+	Query         string                 `json:"query" binding:"required"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// Handler executes the queries and mutations sent with POST. The caller authenticated by
+// middleware.Authenticate is handed to the resolvers, so the same ownership and role checks
+// apply as on the REST routes. The response follows the GraphQL specification, errors of
+// the catalogue carry their code in the extensions.
+func Handler(schema *graphql.Schema) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var request Request
+
+		if err := c.ShouldBindJSON(&request); err != nil {
+			helper.SendError(c, helper.PayloadError(err))
+			return
+		}
+
+		ctx := controller.WithCaller(c.Request.Context(), controller.CallerFromContext(c))
+		c.JSON(http.StatusOK, schema.Exec(ctx, request.Query, request.OperationName, request.Variables))
+	}
+}
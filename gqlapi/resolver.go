@@ -0,0 +1,455 @@
+package gqlapi
+
+import (
+	"context"
+	_ "embed"
+	"sort"
+
+	"github.com/gin-gonic/gin/binding"
+	graphql "github.com/graph-gophers/graphql-go"
+	"github.com/varadekd/card-game/controller"
+	"github.com/varadekd/card-game/helper"
+	"github.com/varadekd/card-game/model"
+	"github.com/varadekd/card-game/store"
+)
+
+//go:embed schema.graphql
+var schemaString string
+
+// maxGameDecks caps how many decks of a game are loaded for a single query.
+const maxGameDecks = 1000
+
+// NewSchema parses the schema and binds it to the resolvers, which share the controller
+// logic of the Gin handlers.
+func NewSchema() *graphql.Schema {
+	return graphql.MustParseSchema(schemaString, &resolver{}, graphql.MaxDepth(10))
+}
+
+// resolver is the root of the queries, mutations and subscriptions. The caller is read
+// from the context with controller.CallerFrom.
+type resolver struct{}
+
+func (r *resolver) Deck(ctx context.Context, args struct{ ID graphql.ID }) (*deckResolver, error) {
+	deck, err := controller.FindDeck(controller.CallerFrom(ctx), string(args.ID))
+
+	if err != nil {
+		return nil, queryError(controller.DeckError(err))
+	}
+
+	return &deckResolver{deck}, nil
+}
+
+func (r *resolver) Decks(ctx context.Context, args struct {
+	GameID *string
+	Status *string
+	Sort   *string
+	Limit  *int32
+	Cursor *string
+}) (*deckPageResolver, error) {
+	payload := model.ListDecksPayload{
+		GameID: value(args.GameID),
+		Status: value(args.Status),
+		Sort:   value(args.Sort),
+		Cursor: value(args.Cursor),
+	}
+
+	if args.Limit != nil {
+		payload.Limit = int(*args.Limit)
+	}
+
+	if err := binding.Validator.ValidateStruct(&payload); err != nil {
+		return nil, queryError(helper.QueryError(err))
+	}
+
+	page, err := controller.FindDecks(controller.CallerFrom(ctx), payload)
+
+	if err != nil {
+		return nil, queryError(controller.DeckError(err))
+	}
+
+	return &deckPageResolver{page}, nil
+}
+
+func (r *resolver) Game(ctx context.Context, args struct{ ID string }) (*gameResolver, error) {
+	caller := controller.CallerFrom(ctx)
+	game := &gameResolver{id: args.ID}
+	payload := model.ListDecksPayload{GameID: args.ID, Limit: 100}
+
+	for len(game.decks) < maxGameDecks {
+		page, err := controller.FindDecks(caller, payload)
+
+		if err != nil {
+			return nil, queryError(controller.DeckError(err))
+		}
+
+		game.decks = append(game.decks, page.Decks...)
+
+		if page.NextCursor == "" {
+			break
+		}
+		payload.Cursor = page.NextCursor
+	}
+
+	return game, nil
+}
+
+func (r *resolver) NewDeck(ctx context.Context, args struct{ Input newDeckInput }) (*deckResolver, error) {
+	caller := controller.CallerFrom(ctx)
+
+	if err := controller.AllowCreate(caller); err != nil {
+		return nil, queryError(controller.DeckError(err))
+	}
+
+	payload := model.GenerateDeckPayload{GameID: value(args.Input.GameID)}
+
+	if args.Input.Shuffle != nil {
+		payload.Shuffle = *args.Input.Shuffle
+	}
+
+	if args.Input.Cards != nil {
+		payload.Cards = *args.Input.Cards
+	}
+
+	if err := binding.Validator.ValidateStruct(&payload); err != nil {
+		return nil, queryError(helper.PayloadError(err))
+	}
+
+	deck, err := controller.CreateDeck(caller, payload)
+
+	if err != nil {
+		return nil, queryError(controller.DeckError(err))
+	}
+
+	return &deckResolver{deck}, nil
+}
+
+func (r *resolver) DrawCards(ctx context.Context, args struct {
+	DeckID   graphql.ID
+	Count    int32
+	PlayerID *string
+}) ([]*cardResolver, error) {
+	caller := controller.CallerFrom(ctx)
+
+	if err := controller.AllowDraw(caller); err != nil {
+		return nil, queryError(controller.DeckError(err))
+	}
+
+	payload := model.DrawCardFromDeckPayload{CardsToBeDrawn: int(args.Count), PlayerID: value(args.PlayerID)}
+
+	if err := binding.Validator.ValidateStruct(&payload); err != nil {
+		return nil, queryError(helper.PayloadError(err))
+	}
+
+	cards, err := controller.DrawFromDeck(caller, string(args.DeckID), payload, "")
+
+	if err != nil {
+		return nil, queryError(controller.DeckError(err))
+	}
+
+	return cardResolvers(cards), nil
+}
+
+func (r *resolver) DiscardCards(ctx context.Context, args struct {
+	DeckID   graphql.ID
+	Cards    []string
+	Pile     string
+	PlayerID *string
+}) (*deckResolver, error) {
+	payload := model.DiscardCardsPayload{PlayerID: value(args.PlayerID), Cards: args.Cards, Pile: args.Pile}
+
+	if err := binding.Validator.ValidateStruct(&payload); err != nil {
+		return nil, queryError(helper.PayloadError(err))
+	}
+
+	deck, err := controller.DiscardCards(controller.CallerFrom(ctx), string(args.DeckID), payload, "")
+
+	if err != nil {
+		return nil, queryError(controller.DeckError(err))
+	}
+
+	return &deckResolver{deck}, nil
+}
+
+func (r *resolver) DeckChanged(ctx context.Context, args struct{ DeckID graphql.ID }) (<-chan *deckUpdateResolver, error) {
+	updates, stop, err := controller.WatchDeck(controller.CallerFrom(ctx), string(args.DeckID))
+
+	if err != nil {
+		return nil, queryError(controller.DeckError(err))
+	}
+
+	out := make(chan *deckUpdateResolver)
+
+	go func() {
+		defer close(out)
+		defer stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case update, open := <-updates:
+				if !open {
+					return
+				}
+
+				select {
+				case out <- &deckUpdateResolver{update}:
+				case <-ctx.Done():
+					return
+				}
+
+				if update.Last() {
+					return
+				}
+			}
+		}
+	}()
+
+	return out, nil
+}
+
+type newDeckInput struct {
+	GameID  *string
+	Shuffle *bool
+	Cards   *[]string
+}
+
+type deckResolver struct {
+	deck model.Deck
+}
+
+func (r *deckResolver) ID() graphql.ID          { return graphql.ID(r.deck.ID.String()) }
+func (r *deckResolver) GameID() string          { return r.deck.GameID }
+func (r *deckResolver) Shuffle() bool           { return r.deck.Shuffle }
+func (r *deckResolver) DeckSize() int32         { return int32(r.deck.DeckSize) }
+func (r *deckResolver) CardsRemaining() int32   { return int32(r.deck.CardsRemaining) }
+func (r *deckResolver) Round() int32            { return int32(r.deck.Round) }
+func (r *deckResolver) Version() int32          { return int32(r.deck.Version) }
+func (r *deckResolver) CreatedAt() graphql.Time { return graphql.Time{Time: r.deck.CreatedAt} }
+
+func (r *deckResolver) GeneratedDeck() []*cardResolver { return cardResolvers(r.deck.GeneratedDeck) }
+func (r *deckResolver) PlayingCards() []*cardResolver  { return cardResolvers(r.deck.PlayingCards) }
+
+func (r *deckResolver) LastUsedAt() *graphql.Time {
+	if r.deck.DeckLastUsed.IsZero() {
+		return nil
+	}
+
+	return &graphql.Time{Time: r.deck.DeckLastUsed}
+}
+
+func (r *deckResolver) SourceDeckID() *string {
+	if r.deck.SourceDeckID == "" {
+		return nil
+	}
+
+	return &r.deck.SourceDeckID
+}
+
+func (r *deckResolver) Hands() []*handResolver {
+	hands := make([]*handResolver, 0, len(r.deck.Hands))
+
+	for player, cards := range r.deck.Hands {
+		hands = append(hands, &handResolver{player, cards})
+	}
+
+	// Map order is random, players are listed by ID so responses are stable
+	sort.Slice(hands, func(i, j int) bool { return hands[i].playerID < hands[j].playerID })
+	return hands
+}
+
+func (r *deckResolver) Piles() []*pileResolver {
+	piles := make([]*pileResolver, 0, len(r.deck.Piles))
+
+	for name, cards := range r.deck.Piles {
+		piles = append(piles, &pileResolver{name, cards})
+	}
+
+	// Piles are listed by name so responses are stable
+	sort.Slice(piles, func(i, j int) bool { return piles[i].name < piles[j].name })
+	return piles
+}
+
+func (r *deckResolver) Pile(args struct{ Name string }) *pileResolver {
+	cards, ok := r.deck.Piles[args.Name]
+
+	if !ok {
+		return nil
+	}
+
+	return &pileResolver{args.Name, cards}
+}
+
+func (r *deckResolver) AuditTrail() []*eventResolver {
+	events := make([]*eventResolver, 0, len(r.deck.AuditTrail))
+
+	for _, event := range r.deck.AuditTrail {
+		events = append(events, &eventResolver{event})
+	}
+
+	return events
+}
+
+type cardResolver struct {
+	card model.Card
+}
+
+func (r *cardResolver) Value() string { return r.card.Value }
+func (r *cardResolver) Code() string  { return r.card.Code }
+func (r *cardResolver) Suit() string  { return r.card.Suit }
+
+func cardResolvers(cards []model.Card) []*cardResolver {
+	resolvers := make([]*cardResolver, 0, len(cards))
+
+	for _, card := range cards {
+		resolvers = append(resolvers, &cardResolver{card})
+	}
+
+	return resolvers
+}
+
+type handResolver struct {
+	playerID string
+	cards    []model.Card
+}
+
+func (r *handResolver) PlayerID() string       { return r.playerID }
+func (r *handResolver) Cards() []*cardResolver { return cardResolvers(r.cards) }
+
+type pileResolver struct {
+	name  string
+	cards []model.Card
+}
+
+func (r *pileResolver) Name() string           { return r.name }
+func (r *pileResolver) Cards() []*cardResolver { return cardResolvers(r.cards) }
+
+type eventResolver struct {
+	event model.DeckEvent
+}
+
+func (r *eventResolver) Action() string   { return r.event.Action }
+func (r *eventResolver) Round() int32     { return int32(r.event.Round) }
+func (r *eventResolver) At() graphql.Time { return graphql.Time{Time: r.event.At} }
+func (r *eventResolver) Detail() string   { return r.event.Detail }
+
+type deckPageResolver struct {
+	page store.DeckPage
+}
+
+func (r *deckPageResolver) Decks() []*deckResolver {
+	return deckResolvers(r.page.Decks)
+}
+
+func (r *deckPageResolver) NextCursor() *string {
+	if r.page.NextCursor == "" {
+		return nil
+	}
+
+	return &r.page.NextCursor
+}
+
+func deckResolvers(decks []model.Deck) []*deckResolver {
+	resolvers := make([]*deckResolver, 0, len(decks))
+
+	for _, deck := range decks {
+		resolvers = append(resolvers, &deckResolver{deck})
+	}
+
+	return resolvers
+}
+
+type gameResolver struct {
+	id    string
+	decks []model.Deck
+}
+
+func (r *gameResolver) ID() string             { return r.id }
+func (r *gameResolver) Decks() []*deckResolver { return deckResolvers(r.decks) }
+
+func (r *gameResolver) Piles() []*gamePileResolver {
+	piles := []*gamePileResolver{}
+
+	// Decks are listed in creation order, their piles by name
+	for _, deck := range r.decks {
+		for _, pile := range (&deckResolver{deck}).Piles() {
+			piles = append(piles, &gamePileResolver{deck.ID.String(), pile})
+		}
+	}
+
+	return piles
+}
+
+func (r *gameResolver) RecentEvents(args struct{ Limit int32 }) []*gameEventResolver {
+	events := []*gameEventResolver{}
+
+	for _, deck := range r.decks {
+		for _, event := range controller.DeckHistory(deck) {
+			events = append(events, &gameEventResolver{deck.ID.String(), event})
+		}
+	}
+
+	sort.SliceStable(events, func(i, j int) bool { return events[i].event.At.After(events[j].event.At) })
+
+	if args.Limit >= 0 && int(args.Limit) < len(events) {
+		events = events[:args.Limit]
+	}
+
+	return events
+}
+
+type gameEventResolver struct {
+	deckID string
+	event  model.DeckEvent
+}
+
+func (r *gameEventResolver) DeckID() graphql.ID    { return graphql.ID(r.deckID) }
+func (r *gameEventResolver) Event() *eventResolver { return &eventResolver{r.event} }
+
+type gamePileResolver struct {
+	deckID string
+	pile   *pileResolver
+}
+
+func (r *gamePileResolver) DeckID() graphql.ID  { return graphql.ID(r.deckID) }
+func (r *gamePileResolver) Pile() *pileResolver { return r.pile }
+
+type deckUpdateResolver struct {
+	update model.DeckUpdate
+}
+
+func (r *deckUpdateResolver) DeckID() graphql.ID    { return graphql.ID(r.update.DeckID.String()) }
+func (r *deckUpdateResolver) Event() *eventResolver { return &eventResolver{r.update.Event} }
+func (r *deckUpdateResolver) CardsRemaining() int32 { return int32(r.update.CardsRemaining) }
+
+// apiError is an error of the catalogue sent in the GraphQL errors, the code and the
+// rejected fields are added to the extensions.
+type apiError struct {
+	helper.APIError
+}
+
+func (e apiError) Error() string {
+	return e.Message
+}
+
+func (e apiError) Extensions() map[string]interface{} {
+	extensions := map[string]interface{}{"code": e.Code, "status": e.Status}
+
+	if len(e.Details) > 0 {
+		extensions["details"] = e.Details
+	}
+
+	return extensions
+}
+
+func queryError(e helper.APIError) error {
+	return apiError{e}
+}
+
+func value(s *string) string {
+	if s == nil {
+		return ""
+	}
+
+	return *s
+}
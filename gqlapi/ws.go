@@ -0,0 +1,383 @@
+package gqlapi
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	graphql "github.com/graph-gophers/graphql-go"
+	"github.com/varadekd/card-game/auth"
+	"github.com/varadekd/card-game/controller"
+	"github.com/varadekd/card-game/helper"
+	"github.com/varadekd/card-game/middleware"
+)
+
+// Subprotocol is the WebSocket subprotocol spoken on GET /graphql, see
+// https://github.com/enisdenjo/graphql-ws/blob/master/PROTOCOL.md
+const Subprotocol = "graphql-transport-ws"
+
+// Message types of the graphql-transport-ws protocol.
+const (
+	messageConnectionInit = "connection_init"
+	messageConnectionAck  = "connection_ack"
+	messagePing           = "ping"
+	messagePong           = "pong"
+	messageSubscribe      = "subscribe"
+	messageNext           = "next"
+	messageError          = "error"
+	messageComplete       = "complete"
+)
+
+// Close codes of the graphql-transport-ws protocol.
+const (
+	closeBadRequest        = 4400
+	closeUnauthorized      = 4401
+	closeForbidden         = 4403
+	closeInitTimeout       = 4408
+	closeSubscriberExists  = 4409
+	closeTooManyInitialise = 4429
+)
+
+// initTimeout is how long a client has to send connection_init after connecting.
+const initTimeout = 10 * time.Second
+
+// writeTimeout bounds every write, a client that stops reading is disconnected.
+const writeTimeout = 10 * time.Second
+
+// shutdownReason is sent with the Going Away close code when the application shuts down.
+const shutdownReason = "Server is shutting down"
+
+// sessions holds the open connections so they can be closed when the application shuts down.
+var sessions = struct {
+	sync.Mutex
+	open    map[*session]struct{}
+	closing bool
+}{open: map[*session]struct{}{}}
+
+type message struct {
+	ID      string          `json:"id,omitempty"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// Subscriptions accepts WebSocket connections speaking graphql-transport-ws. Browsers can
+// not set headers on WebSockets, so the route is registered before the authentication
+// middleware and the credentials are read from the connection_init payload instead
+// ("Authorization" and "X-API-Key" keys), falling back to the headers of the upgrade request.
+// A nil keys or signer disables that kind of credential like on the REST routes.
+func Subscriptions(schema *graphql.Schema, keys *auth.KeyStore, signer *auth.JWTSigner) gin.HandlerFunc {
+	upgrader := websocket.Upgrader{
+		Subprotocols: []string{Subprotocol},
+		// Credentials are never read from cookies, so pages of other origins gain nothing
+		// by opening a connection
+		CheckOrigin: func(r *http.Request) bool { return true },
+	}
+
+	return func(c *gin.Context) {
+		if !websocket.IsWebSocketUpgrade(c.Request) {
+			helper.SendError(c, helper.ErrInvalidPayload.WithMessage("GET /graphql only accepts WebSocket connections, send queries and mutations with POST"))
+			return
+		}
+
+		conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+
+		if err != nil {
+			// The upgrader already replied to the client
+			return
+		}
+
+		s := &session{
+			conn:       conn,
+			schema:     schema,
+			keys:       keys,
+			signer:     signer,
+			request:    c.Request,
+			clientIP:   c.ClientIP(),
+			logger:     middleware.Logger(c),
+			operations: map[string]*operation{},
+		}
+
+		if conn.Subprotocol() != Subprotocol {
+			s.close(closeBadRequest, "Subprotocol "+Subprotocol+" is required")
+			return
+		}
+
+		if !s.track() {
+			s.close(websocket.CloseGoingAway, shutdownReason)
+			return
+		}
+
+		defer s.untrack()
+		s.run()
+	}
+}
+
+// CloseSessions ends every open connection with the Going Away close code, the running
+// operations are cancelled. Connections opened afterwards are closed right away.
+func CloseSessions() {
+	sessions.Lock()
+	defer sessions.Unlock()
+
+	sessions.closing = true
+
+	for s := range sessions.open {
+		s.close(websocket.CloseGoingAway, shutdownReason)
+	}
+}
+
+// session is a WebSocket connection, it can run several operations at once.
+type session struct {
+	conn     *websocket.Conn
+	schema   *graphql.Schema
+	keys     *auth.KeyStore
+	signer   *auth.JWTSigner
+	request  *http.Request
+	clientIP string
+	logger   *slog.Logger
+
+	// ctx carries the caller once the connection is acknowledged
+	ctx context.Context
+
+	writeLock  sync.Mutex
+	lock       sync.Mutex
+	operations map[string]*operation
+}
+
+type operation struct {
+	cancel context.CancelFunc
+}
+
+// track adds the session to the open ones, it returns false once the sessions are closing.
+func (s *session) track() bool {
+	sessions.Lock()
+	defer sessions.Unlock()
+
+	if sessions.closing {
+		return false
+	}
+
+	sessions.open[s] = struct{}{}
+	return true
+}
+
+func (s *session) untrack() {
+	sessions.Lock()
+	defer sessions.Unlock()
+
+	delete(sessions.open, s)
+}
+
+func (s *session) run() {
+	ctx, cancel := context.WithCancel(s.request.Context())
+	defer cancel()
+	defer s.conn.Close()
+
+	s.conn.SetReadDeadline(time.Now().Add(initTimeout))
+
+	for {
+		var msg message
+
+		if err := s.conn.ReadJSON(&msg); err != nil {
+			if netErr, ok := err.(interface{ Timeout() bool }); ok && netErr.Timeout() && s.ctx == nil {
+				s.close(closeInitTimeout, "Connection initialisation timeout")
+			}
+			return
+		}
+
+		switch msg.Type {
+		case messageConnectionInit:
+			if s.ctx != nil {
+				s.close(closeTooManyInitialise, "Too many initialisation requests")
+				return
+			}
+
+			caller, err := s.authenticate(msg.Payload)
+
+			if err != nil {
+				s.logger.Info("Rejected the credentials of a GraphQL connection", "error", err.Error())
+				s.close(closeForbidden, err.Error())
+				return
+			}
+
+			s.ctx = controller.WithCaller(ctx, caller)
+			s.conn.SetReadDeadline(time.Time{})
+			s.send(message{Type: messageConnectionAck})
+		case messagePing:
+			s.send(message{Type: messagePong, Payload: msg.Payload})
+		case messagePong:
+		case messageSubscribe:
+			if s.ctx == nil {
+				s.close(closeUnauthorized, "Unauthorized")
+				return
+			}
+
+			var request Request
+
+			if msg.ID == "" || json.Unmarshal(msg.Payload, &request) != nil || request.Query == "" {
+				s.close(closeBadRequest, "Invalid subscribe message")
+				return
+			}
+
+			if !s.start(msg.ID, request) {
+				s.close(closeSubscriberExists, "Subscriber for "+msg.ID+" already exists")
+				return
+			}
+		case messageComplete:
+			s.stop(msg.ID)
+		default:
+			s.close(closeBadRequest, "Invalid message type "+msg.Type)
+			return
+		}
+	}
+}
+
+// authenticate resolves the credentials of the connection like middleware.Authenticate does.
+func (s *session) authenticate(payload json.RawMessage) (controller.Caller, error) {
+	params := map[string]interface{}{}
+
+	if len(payload) > 0 && string(payload) != "null" {
+		if err := json.Unmarshal(payload, &params); err != nil {
+			return controller.Caller{}, helper.ErrInvalidPayload
+		}
+	}
+
+	credential := func(name string) string {
+		for key, value := range params {
+			if text, ok := value.(string); ok && strings.EqualFold(key, name) {
+				return text
+			}
+		}
+
+		return s.request.Header.Get(name)
+	}
+
+	identity, err := middleware.Identify(s.keys, s.signer, credential("Authorization"), credential(middleware.APIKeyHeader))
+
+	if err != nil {
+		return controller.Caller{}, err
+	}
+
+	return controller.Caller{
+		KeyID:     identity.KeyID,
+		Claims:    identity.Claims,
+		ClientKey: middleware.ClientKeyFor(identity.KeyID, s.clientIP),
+		Logger:    s.logger,
+		Context:   s.request.Context(),
+	}, nil
+}
+
+// start runs the operation in the background, it returns false when the ID is already in use.
+func (s *session) start(id string, request Request) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if _, found := s.operations[id]; found {
+		return false
+	}
+
+	ctx, cancel := context.WithCancel(s.ctx)
+	op := &operation{cancel: cancel}
+	s.operations[id] = op
+
+	go s.execute(ctx, id, op, request)
+	return true
+}
+
+// stop cancels an operation on request of the client, no complete message is sent back.
+func (s *session) stop(id string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if op, found := s.operations[id]; found {
+		op.cancel()
+		delete(s.operations, id)
+	}
+}
+
+func (s *session) execute(ctx context.Context, id string, op *operation, request Request) {
+	defer s.finish(id, op)
+
+	responses, err := s.schema.Subscribe(ctx, request.Query, request.OperationName, request.Variables)
+
+	if err != nil {
+		s.logger.Error("Unable to start a GraphQL subscription", "operation_id", id, "error", err)
+		s.sendPayload(id, messageError, []map[string]string{{"message": helper.ErrInternal.Message}})
+		return
+	}
+
+	first := true
+
+	for value := range responses {
+		if ctx.Err() != nil {
+			return
+		}
+
+		response, ok := value.(*graphql.Response)
+
+		if !ok {
+			continue
+		}
+
+		// Errors raised before anything was resolved, like an unknown deck, end the operation
+		if first && response.Data == nil && len(response.Errors) > 0 {
+			s.sendPayload(id, messageError, response.Errors)
+			return
+		}
+
+		first = false
+		s.sendPayload(id, messageNext, response)
+	}
+
+	if ctx.Err() == nil {
+		s.send(message{ID: id, Type: messageComplete})
+	}
+}
+
+// finish forgets an operation which ended, unless the ID was already reused by the client.
+func (s *session) finish(id string, op *operation) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	op.cancel()
+
+	if s.operations[id] == op {
+		delete(s.operations, id)
+	}
+}
+
+func (s *session) sendPayload(id, messageType string, payload interface{}) {
+	encoded, err := json.Marshal(payload)
+
+	if err != nil {
+		s.logger.Error("Unable to encode a GraphQL response", "operation_id", id, "error", err)
+		return
+	}
+
+	s.send(message{ID: id, Type: messageType, Payload: encoded})
+}
+
+func (s *session) send(msg message) {
+	s.writeLock.Lock()
+	defer s.writeLock.Unlock()
+
+	s.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+
+	if err := s.conn.WriteJSON(msg); err != nil {
+		// The read loop notices the broken connection and cancels the operations
+		s.conn.Close()
+	}
+}
+
+func (s *session) close(code int, reason string) {
+	s.writeLock.Lock()
+	defer s.writeLock.Unlock()
+
+	s.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(writeTimeout))
+	s.conn.Close()
+}
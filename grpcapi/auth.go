@@ -0,0 +1,117 @@
+package grpcapi
+
+import (
+	"context"
+	"log/slog"
+	"net"
+
+	"github.com/google/uuid"
+	"github.com/varadekd/card-game/auth"
+	"github.com/varadekd/card-game/controller"
+	"github.com/varadekd/card-game/helper"
+	"github.com/varadekd/card-game/logging"
+	"github.com/varadekd/card-game/middleware"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+// APIKeyMetadata is the metadata key callers send their API key in, player tokens are sent
+// in the "authorization" metadata as "Bearer <token>".
+const APIKeyMetadata = "x-api-key"
+
+// RequestIDMetadata carries the ID of the call like the X-Request-ID header of the REST api,
+// it is generated when the caller does not send one and always sent back in the headers.
+const RequestIDMetadata = "x-request-id"
+
+// authenticate resolves the credentials of the call and stores the caller in the context,
+// with the logger of the call carrying its request ID and trace ID.
+func authenticate(ctx context.Context, keys *auth.KeyStore, signer *auth.JWTSigner) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	requestID := firstValue(md, RequestIDMetadata)
+
+	if !middleware.ValidRequestID(requestID) {
+		requestID = uuid.NewString()
+	}
+
+	grpc.SetHeader(ctx, metadata.Pairs(RequestIDMetadata, requestID))
+	logger := slog.Default().With("request_id", requestID)
+
+	if spanContext := trace.SpanContextFromContext(ctx); spanContext.IsValid() {
+		logger = logger.With("trace_id", spanContext.TraceID().String(), "span_id", spanContext.SpanID().String())
+	}
+
+	ctx = logging.WithLogger(ctx, logger)
+
+	identity, err := middleware.Identify(keys, signer, firstValue(md, "authorization"), firstValue(md, APIKeyMetadata))
+
+	if err != nil {
+		middleware.LogRejection(logger, err.(helper.APIError), "Rejected the credentials of a gRPC call", "")
+		return nil, statusError(err.(helper.APIError))
+	}
+
+	caller := controller.Caller{KeyID: identity.KeyID, Claims: identity.Claims, Logger: logger, Context: ctx}
+	host := ""
+
+	if p, found := peer.FromContext(ctx); found {
+		host, _, err = net.SplitHostPort(p.Addr.String())
+
+		if err != nil {
+			host = p.Addr.String()
+		}
+	}
+
+	caller.ClientKey = middleware.ClientKeyFor(identity.KeyID, host)
+
+	return controller.WithCaller(ctx, caller), nil
+}
+
+func unaryAuthenticate(keys *auth.KeyStore, signer *auth.JWTSigner) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (response interface{}, err error) {
+		ctx, span := startCall(ctx, info.FullMethod)
+		defer func() { endCall(span, err) }()
+
+		ctx, err = authenticate(ctx, keys, signer)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+func streamAuthenticate(keys *auth.KeyStore, signer *auth.JWTSigner) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		ctx, span := startCall(stream.Context(), info.FullMethod)
+		defer func() { endCall(span, err) }()
+
+		ctx, err = authenticate(ctx, keys, signer)
+
+		if err != nil {
+			return err
+		}
+
+		return handler(srv, &authenticatedStream{ServerStream: stream, ctx: ctx})
+	}
+}
+
+// authenticatedStream is a server stream carrying the context with the caller.
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authenticatedStream) Context() context.Context {
+	return s.ctx
+}
+
+func firstValue(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+
+	return ""
+}
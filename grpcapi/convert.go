@@ -0,0 +1,78 @@
+package grpcapi
+
+import (
+	"time"
+
+	"github.com/varadekd/card-game/deckpb"
+	"github.com/varadekd/card-game/model"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// deckMessage converts a deck to its protobuf message.
+func deckMessage(deck model.Deck) *deckpb.Deck {
+	message := &deckpb.Deck{
+		Id:             deck.ID.String(),
+		GameId:         deck.GameID,
+		Shuffle:        deck.Shuffle,
+		GeneratedDeck:  cardMessages(deck.GeneratedDeck),
+		PlayingCards:   cardMessages(deck.PlayingCards),
+		DeckSize:       int32(deck.DeckSize),
+		CardsRemaining: int32(deck.CardsRemaining),
+		DeckLastUsed:   timestamp(deck.DeckLastUsed),
+		CreatedAt:      timestamp(deck.CreatedAt),
+		SourceDeckId:   deck.SourceDeckID,
+		Round:          int32(deck.Round),
+		OwnerId:        deck.OwnerID,
+		Version:        int32(deck.Version),
+	}
+
+	for _, event := range deck.AuditTrail {
+		message.AuditTrail = append(message.AuditTrail, eventMessage(event))
+	}
+
+	if len(deck.Hands) > 0 {
+		message.Hands = map[string]*deckpb.Hand{}
+
+		for player, cards := range deck.Hands {
+			message.Hands[player] = &deckpb.Hand{Cards: cardMessages(cards)}
+		}
+	}
+
+	return message
+}
+
+func cardMessages(cards []model.Card) []*deckpb.Card {
+	messages := make([]*deckpb.Card, 0, len(cards))
+
+	for _, card := range cards {
+		messages = append(messages, &deckpb.Card{Value: card.Value, Code: card.Code, Suit: card.Suit})
+	}
+
+	return messages
+}
+
+func eventMessage(event model.DeckEvent) *deckpb.DeckEvent {
+	return &deckpb.DeckEvent{
+		Action: event.Action,
+		Round:  int32(event.Round),
+		At:     timestamp(event.At),
+		Detail: event.Detail,
+	}
+}
+
+func updateMessage(update model.DeckUpdate) *deckpb.DeckUpdate {
+	return &deckpb.DeckUpdate{
+		DeckId:         update.DeckID.String(),
+		Event:          eventMessage(update.Event),
+		CardsRemaining: int32(update.CardsRemaining),
+	}
+}
+
+// timestamp converts the time, the zero time is left unset.
+func timestamp(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+
+	return timestamppb.New(t)
+}
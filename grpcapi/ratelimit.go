@@ -0,0 +1,29 @@
+package grpcapi
+
+import (
+	"context"
+
+	"github.com/varadekd/card-game/controller"
+	"github.com/varadekd/card-game/deckpb"
+	"google.golang.org/grpc"
+)
+
+// limitedMethods are the calls sharing the rate limits of the matching REST routes.
+var limitedMethods = map[string]func(controller.Caller) error{
+	deckpb.DeckService_CreateDeck_FullMethodName: controller.AllowCreate,
+	deckpb.DeckService_DrawCards_FullMethodName:  controller.AllowDraw,
+}
+
+// unaryRateLimit rejects the calls over the rate limit of the caller with ResourceExhausted,
+// it runs after unaryAuthenticate so the limits are kept per client.
+func unaryRateLimit() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if allow, ok := limitedMethods[info.FullMethod]; ok {
+			if err := allow(controller.CallerFrom(ctx)); err != nil {
+				return nil, statusError(controller.DeckError(err))
+			}
+		}
+
+		return handler(ctx, req)
+	}
+}
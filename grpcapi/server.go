@@ -0,0 +1,156 @@
+package grpcapi
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/varadekd/card-game/auth"
+	"github.com/varadekd/card-game/controller"
+	"github.com/varadekd/card-game/deckpb"
+	"github.com/varadekd/card-game/helper"
+	"github.com/varadekd/card-game/model"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// NewServer returns a gRPC server exposing the deck service. It authenticates the calls
+// like the REST api does, a nil keys or signer disables that kind of credential. Deck creation
+// and drawing share the rate limits set with controller.UseRateLimiters.
+func NewServer(keys *auth.KeyStore, signer *auth.JWTSigner) *grpc.Server {
+	server := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(unaryAuthenticate(keys, signer), unaryRateLimit()),
+		grpc.StreamInterceptor(streamAuthenticate(keys, signer)),
+	)
+
+	deckpb.RegisterDeckServiceServer(server, &deckServer{})
+	return server
+}
+
+// deckServer implements the deck service on top of the same controller logic as the Gin handlers.
+type deckServer struct {
+	deckpb.UnimplementedDeckServiceServer
+}
+
+func (s *deckServer) CreateDeck(ctx context.Context, req *deckpb.CreateDeckRequest) (*deckpb.Deck, error) {
+	payload := model.GenerateDeckPayload{
+		GameID:  req.GetGameId(),
+		Shuffle: req.GetShuffle(),
+		Cards:   req.GetCards(),
+	}
+
+	if err := binding.Validator.ValidateStruct(&payload); err != nil {
+		return nil, statusError(helper.PayloadError(err))
+	}
+
+	deck, err := controller.CreateDeck(controller.CallerFrom(ctx), payload)
+
+	if err != nil {
+		return nil, statusError(controller.DeckError(err))
+	}
+
+	return deckMessage(deck), nil
+}
+
+func (s *deckServer) OpenDeck(ctx context.Context, req *deckpb.OpenDeckRequest) (*deckpb.Deck, error) {
+	deck, err := controller.FindDeck(controller.CallerFrom(ctx), req.GetDeckId())
+
+	if err != nil {
+		return nil, statusError(controller.DeckError(err))
+	}
+
+	return deckMessage(deck), nil
+}
+
+func (s *deckServer) DrawCards(ctx context.Context, req *deckpb.DrawCardsRequest) (*deckpb.DrawCardsResponse, error) {
+	payload := model.DrawCardFromDeckPayload{
+		CardsToBeDrawn: int(req.GetCardsToBeDrawn()),
+		PlayerID:       req.GetPlayerId(),
+	}
+
+	if err := binding.Validator.ValidateStruct(&payload); err != nil {
+		return nil, statusError(helper.PayloadError(err))
+	}
+
+	// The cards are only drawn from the expected version of the deck, like with If-Match
+	ifMatch := ""
+
+	if req.GetExpectedVersion() > 0 {
+		ifMatch = controller.VersionETag(int(req.GetExpectedVersion()))
+	}
+
+	cards, err := controller.DrawFromDeck(controller.CallerFrom(ctx), req.GetDeckId(), payload, ifMatch)
+
+	if err != nil {
+		return nil, statusError(controller.DeckError(err))
+	}
+
+	return &deckpb.DrawCardsResponse{Cards: cardMessages(cards)}, nil
+}
+
+func (s *deckServer) WatchDeck(req *deckpb.WatchDeckRequest, stream deckpb.DeckService_WatchDeckServer) error {
+	updates, stop, err := controller.WatchDeck(controller.CallerFrom(stream.Context()), req.GetDeckId())
+
+	if err != nil {
+		return statusError(controller.DeckError(err))
+	}
+
+	defer stop()
+
+	// Sending the headers tells the client it is subscribed and will not miss any change
+	if err := stream.SendHeader(metadata.MD{}); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case update, open := <-updates:
+			if !open && controller.Events().Closed() {
+				return status.Error(codes.Unavailable, "The server is shutting down, watch the deck again once it is back")
+			}
+
+			if !open {
+				return status.Error(codes.Unavailable, "The watcher fell behind the deck updates, reload the deck and watch again")
+			}
+
+			if err := stream.Send(updateMessage(update)); err != nil {
+				return err
+			}
+
+			if update.Last() {
+				return nil
+			}
+		}
+	}
+}
+
+// statusCodes maps the HTTP statuses of the error catalogue to gRPC codes.
+var statusCodes = map[int]codes.Code{
+	http.StatusBadRequest:          codes.InvalidArgument,
+	http.StatusUnauthorized:        codes.Unauthenticated,
+	http.StatusForbidden:           codes.PermissionDenied,
+	http.StatusNotFound:            codes.NotFound,
+	http.StatusConflict:            codes.FailedPrecondition,
+	http.StatusGone:                codes.NotFound,
+	http.StatusPreconditionFailed:  codes.FailedPrecondition,
+	http.StatusUnprocessableEntity: codes.InvalidArgument,
+	http.StatusTooManyRequests:     codes.ResourceExhausted,
+	http.StatusInternalServerError: codes.Internal,
+	http.StatusServiceUnavailable:  codes.Unavailable,
+}
+
+// statusError turns an error of the catalogue into a gRPC status, the message starts with
+// the stable code of the error so clients can switch on it.
+func statusError(e helper.APIError) error {
+	code, found := statusCodes[e.Status]
+
+	if !found {
+		code = codes.Unknown
+	}
+
+	return status.Error(code, e.Error())
+}
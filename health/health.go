@@ -0,0 +1,59 @@
+// The health package runs the readiness checks of the application and reports their outcome.
+
+package health
+
+import (
+	"time"
+)
+
+// Status of a check or of the whole report.
+const (
+	StatusOK      = "ok"
+	StatusFailing = "failing"
+)
+
+// Check is a single readiness check, Run returns why the application is not ready.
+type Check struct {
+	Name string
+	Run  func() error
+}
+
+// Result is the outcome of a check, Error is only set when it failed.
+type Result struct {
+	Name     string `json:"name"`
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+	Duration string `json:"duration"`
+}
+
+// Report is the outcome of every check, its status is failing when any check failed.
+type Report struct {
+	Status string   `json:"status"`
+	Checks []Result `json:"checks"`
+}
+
+// Ready reports whether every check passed.
+func (r Report) Ready() bool {
+	return r.Status == StatusOK
+}
+
+// Run runs the checks in order and reports their outcome.
+func Run(checks []Check) Report {
+	report := Report{Status: StatusOK, Checks: []Result{}}
+
+	for _, check := range checks {
+		started := time.Now()
+		err := check.Run()
+		result := Result{Name: check.Name, Status: StatusOK, Duration: time.Since(started).String()}
+
+		if err != nil {
+			result.Status = StatusFailing
+			result.Error = err.Error()
+			report.Status = StatusFailing
+		}
+
+		report.Checks = append(report.Checks, result)
+	}
+
+	return report
+}
@@ -0,0 +1,290 @@
+package helper
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/varadekd/card-game/cards"
+	"github.com/varadekd/card-game/model"
+	"github.com/varadekd/card-game/tracing"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Catalogue holds the cards of the default deck loaded from the cards file. It is never
+// modified once loaded, Cards and Select return copies so decks can shuffle and draw
+// their cards freely.
+type Catalogue struct {
+	cards   []model.Card
+	index   map[string]int
+	path    string
+	modTime time.Time
+	size    int64
+}
+
+// catalogue is the catalogue used to generate decks, it is loaded once and replaced on reload.
+var catalogue atomic.Pointer[Catalogue]
+
+// catalogueLoad serialises the loads so concurrent requests do not all read the file.
+var catalogueLoad sync.Mutex
+
+// storedCard is a card of the cards file. Files written before the JSON name of the card code
+// was fixed hold it under "code:", they are still accepted.
+type storedCard struct {
+	model.Card
+	LegacyCode string `json:"code:"`
+}
+
+// LoadCatalogue reads the cards file at path. The read is traced as a child of the span held by ctx.
+func LoadCatalogue(ctx context.Context, path string) (c *Catalogue, err error) {
+	_, span := tracing.Start(ctx, "cards.loadCatalogue", attribute.String("file.path", path))
+
+	defer func() {
+		if err != nil {
+			tracing.Fail(span, err)
+		}
+
+		span.End()
+	}()
+
+	info, err := os.Stat(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	stored := []storedCard{}
+
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return nil, fmt.Errorf("the default deck in %s is corrupted: %w", path, err)
+	}
+
+	loaded := make([]model.Card, len(stored))
+
+	for i, card := range stored {
+		loaded[i] = card.Card
+
+		if loaded[i].Code == "" {
+			loaded[i].Code = card.LegacyCode
+		}
+	}
+
+	if len(loaded) == 0 {
+		return nil, fmt.Errorf("the default deck in %s is empty", path)
+	}
+
+	index := make(map[string]int, len(loaded))
+
+	for i, card := range loaded {
+		if _, err := cards.FromModel(card); err != nil {
+			return nil, fmt.Errorf("the default deck in %s holds an invalid card: %w", path, err)
+		}
+
+		if _, found := index[card.Code]; found {
+			return nil, fmt.Errorf("the default deck in %s holds the card %s twice", path, card.Code)
+		}
+
+		index[card.Code] = i
+	}
+
+	span.SetAttributes(tracing.CardCountKey.Int(len(loaded)))
+	return &Catalogue{cards: loaded, index: index, path: path, modTime: info.ModTime(), size: info.Size()}, nil
+}
+
+// Len returns the number of cards in the catalogue.
+func (c *Catalogue) Len() int {
+	return len(c.cards)
+}
+
+// Cards returns a copy of every card of the catalogue, in the order of the file.
+func (c *Catalogue) Cards() []model.Card {
+	return append(make([]model.Card, 0, len(c.cards)), c.cards...)
+}
+
+// Select returns a copy of the cards whose code is listed, in the order of the file.
+// Unknown codes are ignored.
+func (c *Catalogue) Select(codes []string) []model.Card {
+	selected := make([]bool, len(c.cards))
+
+	for _, code := range codes {
+		if i, found := c.index[code]; found {
+			selected[i] = true
+		}
+	}
+
+	selection := make([]model.Card, 0, len(codes))
+
+	for i, card := range c.cards {
+		if selected[i] {
+			selection = append(selection, card)
+		}
+	}
+
+	return selection
+}
+
+// changed reports whether the file the catalogue was loaded from was modified since.
+func (c *Catalogue) changed() bool {
+	info, err := os.Stat(c.path)
+
+	return err != nil || !info.ModTime().Equal(c.modTime) || info.Size() != c.size
+}
+
+// DefaultCatalogue returns the catalogue used to generate decks. It is loaded from the
+// cards file on first use and kept in memory, see ReloadCatalogue.
+func DefaultCatalogue(ctx context.Context) (*Catalogue, error) {
+	if c := catalogue.Load(); c != nil {
+		return c, nil
+	}
+
+	catalogueLoad.Lock()
+	defer catalogueLoad.Unlock()
+
+	// Another request may have loaded it while this one was waiting
+	if c := catalogue.Load(); c != nil {
+		return c, nil
+	}
+
+	return reloadCatalogue(ctx)
+}
+
+// ReloadCatalogue reads the cards file again and replaces the catalogue used to generate
+// decks. The catalogue in use is kept when the file can not be read.
+func ReloadCatalogue(ctx context.Context) (*Catalogue, error) {
+	catalogueLoad.Lock()
+	defer catalogueLoad.Unlock()
+
+	return reloadCatalogue(ctx)
+}
+
+// reloadCatalogue loads the cards file into the catalogue, the caller must hold catalogueLoad.
+func reloadCatalogue(ctx context.Context) (*Catalogue, error) {
+	path, err := CardsFile()
+
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := LoadCatalogue(ctx, path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	catalogue.Store(c)
+	return c, nil
+}
+
+// forgetCatalogue drops the catalogue in memory, the cards file is read again on next use.
+func forgetCatalogue() {
+	catalogue.Store(nil)
+}
+
+// CatalogueWatcher reloads the catalogue in the background whenever the cards file changes,
+// so the default deck can be edited without restarting the application. An invalid file is
+// logged and ignored, decks keep being generated from the previous catalogue.
+type CatalogueWatcher struct {
+	interval time.Duration
+
+	mu      sync.Mutex
+	stop    chan struct{}
+	done    chan struct{}
+	running bool
+}
+
+// NewCatalogueWatcher returns a watcher checking the cards file every interval, it does
+// nothing until Start is called.
+func NewCatalogueWatcher(interval time.Duration) *CatalogueWatcher {
+	return &CatalogueWatcher{interval: interval}
+}
+
+// Start launches the background goroutine. Calling Start on a running watcher does nothing.
+func (w *CatalogueWatcher) Start() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.running {
+		return
+	}
+
+	w.stop = make(chan struct{})
+	w.done = make(chan struct{})
+	w.running = true
+
+	go w.run(w.stop, w.done)
+}
+
+// Stop signals the goroutine to finish and waits until the reload in progress, if any, is over.
+func (w *CatalogueWatcher) Stop() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if !w.running {
+		return
+	}
+
+	close(w.stop)
+	<-w.done
+	w.running = false
+}
+
+func (w *CatalogueWatcher) run(stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	// A file failing to load is only reported once until it changes again
+	failedState := ""
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if current := catalogue.Load(); current != nil && !current.changed() {
+				continue
+			}
+
+			path, _ := CardsFile()
+			state := fileState(path)
+
+			if state == failedState {
+				continue
+			}
+
+			reloaded, err := ReloadCatalogue(context.Background())
+
+			if err != nil {
+				failedState = state
+				slog.Error("Unable to reload the default deck, the previous cards are kept.", "cards_file", path, "error", err)
+				continue
+			}
+
+			failedState = ""
+			slog.Info("Reloaded the default deck.", "cards_file", path, "cards", reloaded.Len())
+		}
+	}
+}
+
+// fileState identifies the version of the file at path by its modification time and size.
+func fileState(path string) string {
+	info, err := os.Stat(path)
+
+	if err != nil {
+		return "missing"
+	}
+
+	return fmt.Sprintf("%d/%d", info.ModTime().UnixNano(), info.Size())
+}
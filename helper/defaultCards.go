@@ -9,51 +9,62 @@ import (
 	"io/ioutil"
 	"os"
 
+	"github.com/varadekd/card-game/cards"
 	"github.com/varadekd/card-game/model"
 )
 
-var DEFAULT_SUIT_SEQUENCE = []string{"SPADES", "DIAMONDS", "CLUBS", "HEARTS"}
-var CARD_SEQUENCE = []string{"A", "2", "3", "4", "5", "6", "7", "8", "9", "10", "J", "Q", "K"}
+// cardsFile is the file the default deck is stored in, set with UseCardsFile.
+var cardsFile string
+
+// UseCardsFile sets the file the default deck is written to and read from, the catalogue
+// loaded from a previous file is dropped.
+func UseCardsFile(path string) {
+	if path == cardsFile {
+		return
+	}
+
+	cardsFile = path
+	forgetCatalogue()
+}
+
+// CardsFile returns the file set with UseCardsFile, the configuration sets it on startup.
+func CardsFile() (string, error) {
+	if cardsFile == "" {
+		return "", fmt.Errorf("no file was configured for the default deck")
+	}
+
+	return cardsFile, nil
+}
 
 func GenerateDefaultDeck() error {
-	// fetching the file location from env variable
-	filePath, err := GetEnvVariable("DEFAULT_CARDS_FILE_STORAGE")
+	filePath, err := CardsFile()
 
 	if err != nil {
 		return err
 	}
 
-	cards := []model.Card{}
-
-	for _, suit := range DEFAULT_SUIT_SEQUENCE {
-		for _, value := range CARD_SEQUENCE {
-			card := model.Card{
-				Value: value,
-				Suit:  suit,
-				Code:  fmt.Sprintf("%s%s", value, string(suit[0])),
-			}
-			cards = append(cards, card)
-		}
+	defaultDeck := []model.Card{}
+
+	for _, card := range cards.Standard() {
+		defaultDeck = append(defaultDeck, card.Model())
 	}
 
 	// Marshalling the generated cards to store it in a file
-	cardsToStrings, err := json.Marshal(cards)
+	cardsToStrings, err := json.Marshal(defaultDeck)
 
 	if err != nil {
-		fmt.Errorf("we encountered an error '%s' while marshalling the generated cards\n", err.Error())
-		return err
+		return fmt.Errorf("we encountered an error while marshalling the generated cards: %w", err)
 	}
 
 	// Writing the generate string to the file.
 	err = ioutil.WriteFile(filePath, cardsToStrings, 0)
 
 	if err != nil {
-		fmt.Errorf("we encountered an error '%s' while writing cards to the file\n", err.Error())
-		return err
+		return fmt.Errorf("we encountered an error while writing cards to the file %s: %w", filePath, err)
 	}
 
-	fmt.Println("Default set of cards generated successfully.")
-
+	// The catalogue is read from the new file on next use
+	forgetCatalogue()
 	return nil
 }
 
@@ -0,0 +1,137 @@
+package helper
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// APIError is a machine-readable error sent by the api. Code is stable and safe for clients
+// to switch on, Message is meant for humans and may change.
+type APIError struct {
+	Code    string       `json:"code"`
+	Status  int          `json:"status"`
+	Message string       `json:"message"`
+	Details []FieldError `json:"-"`
+}
+
+// FieldError describes why a single field of a payload or query was rejected.
+type FieldError struct {
+	Field  string `json:"field"`
+	Reason string `json:"reason"`
+}
+
+// The error catalogue, every error sent by the api is one of these.
+var (
+	ErrInvalidPayload        = APIError{Code: "INVALID_PAYLOAD", Status: http.StatusBadRequest, Message: "User shared and invalid payload"}
+	ErrInvalidQuery          = APIError{Code: "INVALID_QUERY", Status: http.StatusBadRequest, Message: "User shared and invalid query"}
+	ErrInvalidCardCode       = APIError{Code: "INVALID_CARD_CODE", Status: http.StatusBadRequest, Message: "User shared an invalid card code"}
+	ErrDeckIDMissing         = APIError{Code: "DECK_ID_MISSING", Status: http.StatusBadRequest, Message: "DeckID is missing"}
+	ErrDeckIDInvalid         = APIError{Code: "DECK_ID_INVALID", Status: http.StatusBadRequest, Message: "DeckID is invalid"}
+	ErrGameIDMissing         = APIError{Code: "GAME_ID_MISSING", Status: http.StatusBadRequest, Message: "GameID is missing"}
+	ErrIdempotencyKeyInvalid = APIError{Code: "IDEMPOTENCY_KEY_INVALID", Status: http.StatusBadRequest, Message: "Idempotency-Key is invalid"}
+	ErrAPIKeyMissing         = APIError{Code: "API_KEY_MISSING", Status: http.StatusUnauthorized, Message: "API key is missing"}
+	ErrAPIKeyInvalid         = APIError{Code: "API_KEY_INVALID", Status: http.StatusUnauthorized, Message: "API key is invalid"}
+	ErrTokenMissing          = APIError{Code: "TOKEN_MISSING", Status: http.StatusUnauthorized, Message: "Token is missing"}
+	ErrTokenInvalid          = APIError{Code: "TOKEN_INVALID", Status: http.StatusUnauthorized, Message: "Token is invalid"}
+	ErrTokenExpired          = APIError{Code: "TOKEN_EXPIRED", Status: http.StatusUnauthorized, Message: "Token has expired"}
+	ErrDeckForbidden         = APIError{Code: "DECK_FORBIDDEN", Status: http.StatusForbidden, Message: "You are not allowed to access this deck"}
+	ErrHandForbidden         = APIError{Code: "HAND_FORBIDDEN", Status: http.StatusForbidden, Message: "Players can only draw into their own hand"}
+	ErrDealerOnly            = APIError{Code: "DEALER_ONLY", Status: http.StatusForbidden, Message: "Only the dealer is allowed to perform this operation"}
+	ErrAPIKeyRequired        = APIError{Code: "API_KEY_REQUIRED", Status: http.StatusForbidden, Message: "Only API key holders are allowed to perform this operation"}
+	ErrDeckNotFound          = APIError{Code: "DECK_NOT_FOUND", Status: http.StatusNotFound, Message: "DeckID not found"}
+	ErrTokensDisabled        = APIError{Code: "TOKENS_DISABLED", Status: http.StatusNotFound, Message: "Player tokens are not enabled"}
+	ErrInsufficientCards     = APIError{Code: "INSUFFICIENT_CARDS", Status: http.StatusConflict, Message: "There are no more cards left to be drawn from the deck"}
+	ErrCardNotInHand         = APIError{Code: "CARD_NOT_IN_HAND", Status: http.StatusConflict, Message: "The card is not in the hand of the player"}
+	ErrDeckExpired           = APIError{Code: "DECK_EXPIRED", Status: http.StatusGone, Message: "DeckID has expired"}
+	ErrDeckModified          = APIError{Code: "DECK_MODIFIED", Status: http.StatusPreconditionFailed, Message: "The deck was modified since it was read, reload it and try again"}
+	ErrIdempotencyKeyReused  = APIError{Code: "IDEMPOTENCY_KEY_REUSED", Status: http.StatusUnprocessableEntity, Message: "Idempotency-Key was already used with a different request"}
+	ErrRateLimited           = APIError{Code: "RATE_LIMITED", Status: http.StatusTooManyRequests, Message: "Rate limit exceeded"}
+	ErrDeckQuotaExceeded     = APIError{Code: "DECK_QUOTA_EXCEEDED", Status: http.StatusTooManyRequests, Message: "You have reached the limit of active decks"}
+	ErrDefaultDeckNotFound   = APIError{Code: "DEFAULT_DECK_UNAVAILABLE", Status: http.StatusInternalServerError, Message: "The default deck could not be loaded"}
+	ErrInternal              = APIError{Code: "INTERNAL_ERROR", Status: http.StatusInternalServerError, Message: "Something went wrong on our side"}
+	ErrNotReady              = APIError{Code: "NOT_READY", Status: http.StatusServiceUnavailable, Message: "The application is not ready to serve requests"}
+)
+
+// ErrorCatalogue lists every error the api can send, it is exposed at GET /errors.
+var ErrorCatalogue = []APIError{
+	ErrInvalidPayload,
+	ErrInvalidQuery,
+	ErrInvalidCardCode,
+	ErrDeckIDMissing,
+	ErrDeckIDInvalid,
+	ErrGameIDMissing,
+	ErrIdempotencyKeyInvalid,
+	ErrAPIKeyMissing,
+	ErrAPIKeyInvalid,
+	ErrTokenMissing,
+	ErrTokenInvalid,
+	ErrTokenExpired,
+	ErrDeckForbidden,
+	ErrHandForbidden,
+	ErrDealerOnly,
+	ErrAPIKeyRequired,
+	ErrDeckNotFound,
+	ErrTokensDisabled,
+	ErrInsufficientCards,
+	ErrCardNotInHand,
+	ErrDeckExpired,
+	ErrDeckModified,
+	ErrIdempotencyKeyReused,
+	ErrRateLimited,
+	ErrDeckQuotaExceeded,
+	ErrDefaultDeckNotFound,
+	ErrInternal,
+	ErrNotReady,
+}
+
+func (e APIError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+// Is reports whether the target is an error of the catalogue with the same code, so
+// errors.Is(err, helper.ErrDeckNotFound) matches whatever the message or details are.
+func (e APIError) Is(target error) bool {
+	t, ok := target.(APIError)
+	return ok && t.Code == e.Code
+}
+
+// WithMessage returns a copy of the error with a more specific message, the code stays the same.
+func (e APIError) WithMessage(msg string) APIError {
+	e.Message = msg
+	return e
+}
+
+// WithDetails returns a copy of the error listing the fields that were rejected.
+func (e APIError) WithDetails(details ...FieldError) APIError {
+	e.Details = details
+	return e
+}
+
+// SendError aborts the request with the error in the standard response.
+func SendError(c *gin.Context, e APIError) {
+	c.AbortWithStatusJSON(e.Status, ResponseJSON{
+		Success:      false,
+		Error:        e.Message,
+		ErrorCode:    e.Code,
+		ErrorDetails: e.Details,
+	})
+}
+
+// BindingErrorDetails turns the error returned while binding a payload into field-level details.
+func BindingErrorDetails(err error) []FieldError {
+	var typeErr *json.UnmarshalTypeError
+	var syntaxErr *json.SyntaxError
+
+	switch {
+	case errors.As(err, &typeErr):
+		return []FieldError{{Field: typeErr.Field, Reason: fmt.Sprintf("should be of type %s", typeErr.Type.String())}}
+	case errors.As(err, &syntaxErr):
+		return []FieldError{{Field: "body", Reason: "is not valid JSON"}}
+	}
+
+	return []FieldError{{Field: "body", Reason: err.Error()}}
+}
@@ -1,8 +1,12 @@
 package helper
 
 // ResponseJSON will be used by us to send standard response for all the api
+// When the request fails Error holds the human readable message, ErrorCode the stable
+// code from the error catalogue and ErrorDetails the fields that were rejected, if any.
 type ResponseJSON struct {
-	Success bool        `json:"success"`
-	Error   string      `json:"error"`
-	Data    interface{} `json:"data"`
+	Success      bool         `json:"success"`
+	Error        string       `json:"error"`
+	ErrorCode    string       `json:"errorCode"`
+	ErrorDetails []FieldError `json:"errorDetails"`
+	Data         interface{}  `json:"data"`
 }
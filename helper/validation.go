@@ -0,0 +1,102 @@
+package helper
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/varadekd/card-game/cards"
+)
+
+var registerValidatorsOnce sync.Once
+
+// RegisterValidators adds the custom validation tags used by the payloads to the gin validator
+// and makes it report fields by their JSON or query name. It is safe to call more than once.
+func RegisterValidators() error {
+	var err error
+
+	registerValidatorsOnce.Do(func() {
+		v, ok := binding.Validator.Engine().(*validator.Validate)
+
+		if !ok {
+			err = fmt.Errorf("gin is not using go-playground/validator")
+			return
+		}
+
+		v.RegisterTagNameFunc(fieldName)
+		err = v.RegisterValidation("cardcode", func(fl validator.FieldLevel) bool {
+			return cards.ValidCode(fl.Field().String())
+		})
+	})
+
+	return err
+}
+
+// PayloadError turns the error returned while binding a JSON payload into the api error to send.
+// Invalid card codes are reported with INVALID_CARD_CODE, anything else with INVALID_PAYLOAD.
+func PayloadError(err error) APIError {
+	return bindingError(err, ErrInvalidPayload)
+}
+
+// QueryError turns the error returned while binding a query string into the api error to send.
+func QueryError(err error) APIError {
+	return bindingError(err, ErrInvalidQuery)
+}
+
+func bindingError(err error, fallback APIError) APIError {
+	var validationErrs validator.ValidationErrors
+
+	if !errors.As(err, &validationErrs) {
+		return fallback.WithDetails(BindingErrorDetails(err)...)
+	}
+
+	apiErr := fallback
+	details := []FieldError{}
+
+	for _, fieldErr := range validationErrs {
+		if fieldErr.Tag() == "cardcode" {
+			apiErr = ErrInvalidCardCode
+		}
+
+		details = append(details, FieldError{Field: fieldErr.Field(), Reason: validationReason(fieldErr)})
+	}
+
+	return apiErr.WithDetails(details...)
+}
+
+// validationReason explains in plain words why the field failed the validation tag.
+func validationReason(fieldErr validator.FieldError) string {
+	switch fieldErr.Tag() {
+	case "required":
+		return "is required"
+	case "min":
+		return fmt.Sprintf("should be at least %s", fieldErr.Param())
+	case "max":
+		return fmt.Sprintf("should be at most %s", fieldErr.Param())
+	case "oneof":
+		return fmt.Sprintf("should be one of %s", strings.ReplaceAll(fieldErr.Param(), " ", ", "))
+	case "unique":
+		return "should not contain duplicates"
+	case "cardcode":
+		return fmt.Sprintf("%v is not a valid card code", fieldErr.Value())
+	}
+
+	return fmt.Sprintf("failed the %s validation", fieldErr.Tag())
+}
+
+// fieldName reports struct fields by the name clients use: the json tag, or the form tag for query strings.
+func fieldName(field reflect.StructField) string {
+	for _, tag := range []string{"json", "form"} {
+		name := strings.SplitN(field.Tag.Get(tag), ",", 2)[0]
+
+		if name != "" && name != "-" {
+			return name
+		}
+	}
+
+	return field.Name
+}
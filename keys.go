@@ -0,0 +1,113 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/varadekd/card-game/auth"
+	"github.com/varadekd/card-game/config"
+)
+
+const keysUsage = `Usage: go run . keys <command> [arguments]
+
+Commands:
+  mint -name <name>   mint a new API key, the key is printed once and only its hash is stored
+  revoke <id>         revoke the API key with the given ID
+  list                list every API key with its status
+
+The keys are stored in the API keys file of the configuration, set with the API_KEYS_FILE
+environment variable or auth.apiKeysFile in the CONFIG_FILE config file.`
+
+// runKeysCommand handles the admin subcommands used for managing API keys and returns the exit code.
+func runKeysCommand(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, keysUsage)
+		return 2
+	}
+
+	cfg, err := config.Load(nil)
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	if cfg.Auth.APIKeysFile == "" {
+		fmt.Fprintln(os.Stderr, "No API keys file is configured, set API_KEYS_FILE or auth.apiKeysFile in the config file.")
+		return 1
+	}
+
+	keys, err := auth.LoadKeyStore(cfg.Auth.APIKeysFile)
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	switch args[0] {
+	case "mint":
+		flags := flag.NewFlagSet("mint", flag.ContinueOnError)
+		name := flags.String("name", "", "name describing who the key belongs to")
+
+		if err := flags.Parse(args[1:]); err != nil {
+			return 2
+		}
+
+		if *name == "" {
+			fmt.Fprintln(os.Stderr, "The -name flag is required.")
+			return 2
+		}
+
+		key, plainKey, err := keys.Mint(*name)
+
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+
+		fmt.Printf("Minted API key %s for %s. Store it safely, it will not be shown again:\n%s\n", key.ID, key.Name, plainKey)
+		return 0
+
+	case "revoke":
+		if len(args) != 2 {
+			fmt.Fprintln(os.Stderr, keysUsage)
+			return 2
+		}
+
+		if err := keys.Revoke(args[1]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+
+		fmt.Printf("Revoked API key %s.\n", args[1])
+		return 0
+
+	case "list":
+		list, err := keys.Keys()
+
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tNAME\tCREATED\tSTATUS")
+
+		for _, key := range list {
+			status := "active"
+			if key.RevokedAt != nil {
+				status = fmt.Sprintf("revoked %s", key.RevokedAt.Format(time.RFC3339))
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", key.ID, key.Name, key.CreatedAt.Format(time.RFC3339), status)
+		}
+
+		w.Flush()
+		return 0
+	}
+
+	fmt.Fprintln(os.Stderr, keysUsage)
+	return 2
+}
@@ -1,34 +1,138 @@
 package main
 
 import (
-	"fmt"
+	"context"
+	"flag"
 	"log"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/gin-gonic/gin"
 	"github.com/varadekd/card-game/config"
+	"github.com/varadekd/card-game/controller"
 	"github.com/varadekd/card-game/helper"
+	"github.com/varadekd/card-game/metrics"
+	"github.com/varadekd/card-game/store"
+	"google.golang.org/grpc"
 )
 
 var router *gin.Engine
 
-// TODO: Hardcoding values in the code is incorrect; it should retrieve values from the environment file.
-const APP_PORT = "8080"
+// deckStore holds the decks, the controllers use it through the metrics instrumentation.
+var deckStore store.DeckStore
 
-// The init function is responsible for enabling all the essential services
+// sweeper removes expired decks from the deck store in the background.
+var sweeper *store.Sweeper
+
+// catalogueWatcher reloads the default deck when the cards file changes, it is nil unless
+// a reload interval is configured.
+var catalogueWatcher *helper.CatalogueWatcher
+
+// grpcServer serves the deck service to internal game servers, it is nil unless a gRPC port is configured.
+var grpcServer *grpc.Server
+
+// The setup function is responsible for enabling all the essential services
 // required for this application's functionality.
-func init() {
-	fmt.Println("Setting up the initial services required by the server.")
+func setup(cfg config.Config) {
+	slog.Info("Setting up the initial services required by the server.")
 
+	helper.UseCardsFile(cfg.Decks.CardsFile)
 	err := helper.GenerateDefaultDeck()
 
 	if err != nil {
 		log.Fatalln(err)
 	}
 
-	router = config.SetupRouter()
+	// The cards are kept in memory, decks are generated without reading the file again
+	catalogue, err := helper.ReloadCatalogue(context.Background())
+
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	slog.Info("Default set of cards generated.", "cards_file", cfg.Decks.CardsFile, "cards", catalogue.Len())
+
+	if cfg.Decks.CardsReload.Duration > 0 {
+		catalogueWatcher = helper.NewCatalogueWatcher(cfg.Decks.CardsReload.Duration)
+	}
+
+	deckStore = cfg.NewStore()
+
+	if cfg.Storage.SnapshotFile == "" {
+		slog.Info("No snapshot file is configured, the decks are lost on restart.")
+	} else if snapshotter, ok := deckStore.(store.Snapshotter); ok {
+		if err := store.LoadSnapshot(snapshotter, cfg.Storage.SnapshotFile); err != nil {
+			log.Fatalln(err)
+		}
+	}
+
+	controller.UseStore(metrics.InstrumentStore(deckStore))
+	router = config.NewRouter(cfg)
+
+	if cfg.Server.GRPCPort != "" {
+		grpcServer = config.SetupGRPCServer(cfg)
+	}
+
+	sweeper = store.NewSweeper(controller.Store(), cfg.Decks.SweepInterval.Duration)
+	controller.UseSweeper(sweeper)
 }
 
 func main() {
-	fmt.Printf("Starting application on port %s\n", APP_PORT)
-	config.StartServer(router, APP_PORT)
+	// Admin subcommands run instead of the server
+	if len(os.Args) > 1 && os.Args[1] == "keys" {
+		os.Exit(runKeysCommand(os.Args[2:]))
+	}
+
+	cfg, err := config.Load(os.Args[1:])
+
+	if err == flag.ErrHelp {
+		os.Exit(0)
+	}
+
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	// The standard logger writes through it too, so every line is JSON
+	slog.SetDefault(cfg.NewLogger(os.Stdout))
+
+	shutdownTracing, err := cfg.SetupTracing(context.Background(), os.Stdout)
+
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	setup(cfg)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	sweeper.Start()
+
+	if catalogueWatcher != nil {
+		catalogueWatcher.Start()
+	}
+
+	if grpcServer != nil {
+		slog.Info("Starting gRPC service.", "port", cfg.Server.GRPCPort)
+		config.StartGRPCServer(grpcServer, cfg.Server.GRPCPort)
+	}
+
+	slog.Info("Starting application.", "port", cfg.Server.Port, "mode", cfg.Server.Mode)
+	server := config.StartServer(router, cfg.Server.Port)
+
+	<-ctx.Done()
+
+	// A second signal kills the application right away
+	stop()
+	config.Shutdown(cfg, config.Services{
+		HTTP:             server,
+		GRPC:             grpcServer,
+		Sweeper:          sweeper,
+		CatalogueWatcher: catalogueWatcher,
+		Store:            deckStore,
+		ShutdownTracing:  shutdownTracing,
+	})
 }
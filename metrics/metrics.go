@@ -0,0 +1,35 @@
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Default holds the metrics of the application, it is exposed at GET /metrics.
+var Default = NewRegistry()
+
+// The metrics of the application.
+var (
+	RequestDuration = Default.NewHistogram("card_game_http_request_duration_seconds",
+		"Latency of the deck routes.", DefaultBuckets, "method", "route", "status")
+	DecksCreated = Default.NewCounter("card_game_decks_created_total",
+		"Decks created, by shuffle and by whether the cards were picked by the client.", "shuffled", "cards")
+	CardsDrawn = Default.NewCounter("card_game_cards_drawn_total",
+		"Cards drawn from every deck.")
+	DrawConflicts = Default.NewCounter("card_game_draw_conflicts_total",
+		"Draws rejected with 409 because the deck had not enough cards left.")
+	ActiveDecks = Default.NewGaugeFunc("card_game_active_decks",
+		"Decks stored and not expired.", nil)
+	StoreDuration = Default.NewHistogram("card_game_store_operation_duration_seconds",
+		"Latency of the deck store operations.", DefaultBuckets, "operation")
+)
+
+// Handler writes the metrics of the registry in the Prometheus text format.
+func Handler(registry *Registry) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Status(http.StatusOK)
+		c.Header("Content-Type", ContentType)
+		registry.WriteTo(c.Writer)
+	}
+}
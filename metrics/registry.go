@@ -0,0 +1,325 @@
+// The metrics package collects the usage metrics of the application and exposes them in the
+// Prometheus text format, see https://prometheus.io/docs/instrumenting/exposition_formats/
+
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ContentType is the content type of the Prometheus text format.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// DefaultBuckets suit request and store latencies, in seconds.
+var DefaultBuckets = []float64{0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5}
+
+// Registry holds the metrics written by WriteTo, in the order they were registered.
+type Registry struct {
+	mu      sync.Mutex
+	metrics []metric
+}
+
+type metric interface {
+	write(w *bufio.Writer)
+}
+
+// NewRegistry returns an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+func (r *Registry) register(m metric) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.metrics = append(r.metrics, m)
+}
+
+// WriteTo writes every metric in the Prometheus text format.
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.mu.Lock()
+	metrics := append([]metric(nil), r.metrics...)
+	r.mu.Unlock()
+
+	counter := &countingWriter{w: w}
+	buffered := bufio.NewWriter(counter)
+
+	for _, m := range metrics {
+		m.write(buffered)
+	}
+
+	err := buffered.Flush()
+	return counter.n, err
+}
+
+// vector holds one value per combination of label values, it is shared by every metric type.
+type vector struct {
+	name   string
+	help   string
+	kind   string
+	labels []string
+
+	mu     sync.Mutex
+	series map[string]*series
+}
+
+type series struct {
+	values []string
+	value  float64
+
+	// Only used by histograms, counts[i] is the number of observations in bucket i
+	counts []uint64
+	sum    float64
+}
+
+func newVector(name, help, kind string, labels []string) vector {
+	return vector{name: name, help: help, kind: kind, labels: labels, series: map[string]*series{}}
+}
+
+// get returns the series of the label values, creating it. The caller must hold the lock.
+func (v *vector) get(values []string) *series {
+	if len(values) != len(v.labels) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values but got %d", v.name, len(v.labels), len(values)))
+	}
+
+	key := strings.Join(values, "\xff")
+	s, found := v.series[key]
+
+	if !found {
+		s = &series{values: append([]string(nil), values...)}
+		v.series[key] = s
+	}
+
+	return s
+}
+
+// sorted returns the series ordered by label values so the output is stable. The caller must hold the lock.
+func (v *vector) sorted() []*series {
+	keys := make([]string, 0, len(v.series))
+
+	for key := range v.series {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+	result := make([]*series, 0, len(keys))
+
+	for _, key := range keys {
+		result = append(result, v.series[key])
+	}
+
+	return result
+}
+
+func (v *vector) writeHeader(w *bufio.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", v.name, escapeHelp(v.help), v.name, v.kind)
+}
+
+// labelPairs formats the labels of the series, extra is appended as is (e.g. le="0.5").
+func (v *vector) labelPairs(values []string, extra string) string {
+	pairs := make([]string, 0, len(values)+1)
+
+	for i, value := range values {
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, v.labels[i], escapeLabel(value)))
+	}
+
+	if extra != "" {
+		pairs = append(pairs, extra)
+	}
+
+	if len(pairs) == 0 {
+		return ""
+	}
+
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// Counter is a value which only goes up, like the number of cards drawn.
+type Counter struct {
+	vector
+}
+
+// NewCounter registers a counter with the given label names.
+func (r *Registry) NewCounter(name, help string, labels ...string) *Counter {
+	c := &Counter{newVector(name, help, "counter", labels)}
+	r.register(c)
+	return c
+}
+
+// Inc adds one to the counter of the label values.
+func (c *Counter) Inc(values ...string) {
+	c.Add(1, values...)
+}
+
+// Add adds delta, which must not be negative, to the counter of the label values.
+func (c *Counter) Add(delta float64, values ...string) {
+	if delta < 0 {
+		panic(fmt.Sprintf("metrics: %s can not decrease", c.name))
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.get(values).value += delta
+}
+
+// Value returns the counter of the label values.
+func (c *Counter) Value(values ...string) float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.get(values).value
+}
+
+func (c *Counter) write(w *bufio.Writer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.writeHeader(w)
+
+	for _, s := range c.sorted() {
+		fmt.Fprintf(w, "%s%s %s\n", c.name, c.labelPairs(s.values, ""), formatFloat(s.value))
+	}
+}
+
+// GaugeFunc is a value which can go up and down, it is read when the metrics are written.
+type GaugeFunc struct {
+	name string
+	help string
+
+	mu sync.Mutex
+	fn func() float64
+}
+
+// NewGaugeFunc registers a gauge reading its value from fn, a nil fn reads 0.
+func (r *Registry) NewGaugeFunc(name, help string, fn func() float64) *GaugeFunc {
+	g := &GaugeFunc{name: name, help: help, fn: fn}
+	r.register(g)
+	return g
+}
+
+// Set replaces the function the value is read from.
+func (g *GaugeFunc) Set(fn func() float64) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.fn = fn
+}
+
+func (g *GaugeFunc) write(w *bufio.Writer) {
+	g.mu.Lock()
+	fn := g.fn
+	g.mu.Unlock()
+
+	value := 0.0
+
+	if fn != nil {
+		value = fn()
+	}
+
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n%s %s\n", g.name, escapeHelp(g.help), g.name, g.name, formatFloat(value))
+}
+
+// Histogram counts observations, like latencies, in buckets.
+type Histogram struct {
+	vector
+	buckets []float64
+}
+
+// NewHistogram registers a histogram with the given upper bounds, which must be sorted.
+func (r *Registry) NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	h := &Histogram{vector: newVector(name, help, "histogram", labels), buckets: buckets}
+	r.register(h)
+	return h
+}
+
+// Observe records the value in the histogram of the label values.
+func (h *Histogram) Observe(value float64, values ...string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	s := h.get(values)
+
+	if s.counts == nil {
+		s.counts = make([]uint64, len(h.buckets))
+	}
+
+	// Buckets are cumulative in the output, each observation is only counted in its own here
+	for i, bound := range h.buckets {
+		if value <= bound {
+			s.counts[i]++
+			break
+		}
+	}
+
+	s.value++
+	s.sum += value
+}
+
+// Count returns how many values were observed for the label values.
+func (h *Histogram) Count(values ...string) uint64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return uint64(h.get(values).value)
+}
+
+func (h *Histogram) write(w *bufio.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.writeHeader(w)
+
+	for _, s := range h.sorted() {
+		cumulative := uint64(0)
+
+		for i, bound := range h.buckets {
+			if s.counts != nil {
+				cumulative += s.counts[i]
+			}
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, h.labelPairs(s.values, fmt.Sprintf("le=%q", formatFloat(bound))), cumulative)
+		}
+
+		fmt.Fprintf(w, "%s_bucket%s %s\n", h.name, h.labelPairs(s.values, `le="+Inf"`), formatFloat(s.value))
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.name, h.labelPairs(s.values, ""), formatFloat(s.sum))
+		fmt.Fprintf(w, "%s_count%s %s\n", h.name, h.labelPairs(s.values, ""), formatFloat(s.value))
+	}
+}
+
+func formatFloat(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	}
+
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+// escapeLabel escapes the backslashes, double quotes and line feeds of a label value.
+func escapeLabel(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+func escapeHelp(help string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(help)
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
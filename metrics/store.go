@@ -0,0 +1,73 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/varadekd/card-game/model"
+	"github.com/varadekd/card-game/store"
+)
+
+// instrumentedStore records the latency of every operation of the store it wraps.
+type instrumentedStore struct {
+	store    store.DeckStore
+	duration *Histogram
+}
+
+// InstrumentStore returns a store recording the latency of the operations of s in StoreDuration.
+func InstrumentStore(s store.DeckStore) store.DeckStore {
+	return &instrumentedStore{store: s, duration: StoreDuration}
+}
+
+func (s *instrumentedStore) observe(operation string, started time.Time) {
+	s.duration.Observe(time.Since(started).Seconds(), operation)
+}
+
+func (s *instrumentedStore) Create(deck model.Deck) error {
+	defer s.observe("create", time.Now())
+	return s.store.Create(deck)
+}
+
+func (s *instrumentedStore) CreateWithLimit(deck model.Deck, max int) error {
+	defer s.observe("createWithLimit", time.Now())
+	return s.store.CreateWithLimit(deck, max)
+}
+
+func (s *instrumentedStore) Get(id string) (model.Deck, error) {
+	defer s.observe("get", time.Now())
+	return s.store.Get(id)
+}
+
+func (s *instrumentedStore) Update(id string, fn func(deck *model.Deck) error) (model.Deck, error) {
+	defer s.observe("update", time.Now())
+	return s.store.Update(id, fn)
+}
+
+func (s *instrumentedStore) AppendAudit(id string, fn func(deck model.Deck) (model.DeckEvent, error)) (model.Deck, error) {
+	defer s.observe("appendAudit", time.Now())
+	return s.store.AppendAudit(id, fn)
+}
+
+func (s *instrumentedStore) Delete(id string) error {
+	defer s.observe("delete", time.Now())
+	return s.store.Delete(id)
+}
+
+func (s *instrumentedStore) DeleteByGame(gameID, ownerID string) ([]model.Deck, error) {
+	defer s.observe("deleteByGame", time.Now())
+	return s.store.DeleteByGame(gameID, ownerID)
+}
+
+func (s *instrumentedStore) List(query store.DeckQuery) (store.DeckPage, error) {
+	defer s.observe("list", time.Now())
+	return s.store.List(query)
+}
+
+func (s *instrumentedStore) Count(query store.DeckQuery) (int, error) {
+	defer s.observe("count", time.Now())
+	return s.store.Count(query)
+}
+
+func (s *instrumentedStore) Sweep() int {
+	defer s.observe("sweep", time.Now())
+	return s.store.Sweep()
+}
@@ -0,0 +1,104 @@
+package middleware
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/varadekd/card-game/auth"
+	"github.com/varadekd/card-game/helper"
+)
+
+// APIKeyHeader is the header callers send their API key in.
+const APIKeyHeader = "X-API-Key"
+
+// APIKeyIDContextKey is the gin context key holding the ID of the API key the
+// request acts for. For player tokens it is the key that requested the token.
+const APIKeyIDContextKey = "apiKeyID"
+
+// ClaimsContextKey is the gin context key holding the auth.Claims of a player token.
+const ClaimsContextKey = "claims"
+
+// Identity is who a request is made for. KeyID is the ID of the API key the request acts
+// for, Claims are set for player tokens. Both are empty when authentication is disabled.
+type Identity struct {
+	KeyID  string
+	Claims *auth.Claims
+}
+
+// Identify resolves the credentials sent with a request, whatever the transport: a player token
+// sent as "Bearer <token>" in authorization or an API key. A nil keys or signer disables that
+// kind of credential, when both are nil every request is anonymous. Rejected credentials are
+// reported with an error of the catalogue.
+func Identify(keys *auth.KeyStore, signer *auth.JWTSigner, authorization, apiKey string) (Identity, error) {
+	if signer != nil && strings.HasPrefix(authorization, "Bearer ") {
+		claims, err := signer.Verify(strings.TrimPrefix(authorization, "Bearer "))
+
+		if err == auth.ErrTokenExpired {
+			return Identity{}, helper.ErrTokenExpired
+		}
+
+		if err != nil {
+			return Identity{}, helper.ErrTokenInvalid
+		}
+
+		return Identity{KeyID: claims.OwnerID, Claims: &claims}, nil
+	}
+
+	if keys == nil {
+		if signer != nil {
+			return Identity{}, helper.ErrTokenMissing
+		}
+
+		return Identity{}, nil
+	}
+
+	if apiKey == "" {
+		return Identity{}, helper.ErrAPIKeyMissing
+	}
+
+	keyID, err := keys.Authenticate(apiKey)
+
+	if err != nil {
+		return Identity{}, helper.ErrAPIKeyInvalid
+	}
+
+	return Identity{KeyID: keyID}, nil
+}
+
+// Authenticate identifies the caller either by a player token sent as
+// "Authorization: Bearer <token>" or by an API key sent in the X-API-Key header,
+// see Identify.
+func Authenticate(keys *auth.KeyStore, signer *auth.JWTSigner) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		identity, err := Identify(keys, signer, c.GetHeader("Authorization"), c.GetHeader(APIKeyHeader))
+
+		if err != nil {
+			Reject(c, err.(helper.APIError), "Rejected the credentials of the request")
+			return
+		}
+
+		if identity.Claims != nil {
+			c.Set(ClaimsContextKey, *identity.Claims)
+			c.Set(RoleContextKey, identity.Claims.Role)
+		}
+
+		if identity.KeyID != "" {
+			c.Set(APIKeyIDContextKey, identity.KeyID)
+		}
+
+		c.Next()
+	}
+}
+
+// RequireAPIKey only lets through callers authenticated with an API key, player
+// tokens are rejected with 403. It must be registered after Authenticate.
+func RequireAPIKey() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, isPlayer := c.Get(ClaimsContextKey); isPlayer || c.GetString(APIKeyIDContextKey) == "" {
+			Reject(c, helper.ErrAPIKeyRequired, "Rejected a caller without an API key")
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,42 @@
+// The middleware package holds the Gin middlewares shared by the api routes.
+
+package middleware
+
+import (
+	"crypto/subtle"
+
+	"github.com/gin-gonic/gin"
+	"github.com/varadekd/card-game/auth"
+	"github.com/varadekd/card-game/helper"
+)
+
+// DealerKeyHeader is the header a privileged caller sends the dealer key in.
+const DealerKeyHeader = "X-Dealer-Key"
+
+// RoleContextKey is the gin context key holding the role of the caller.
+const RoleContextKey = "role"
+
+// RoleDealer is the role allowed to perform privileged deck operations like peeking.
+const RoleDealer = auth.RoleDealer
+
+// RequireDealer only lets through callers holding a dealer player token, or requests carrying
+// the dealer key. When the dealer key is empty it is never accepted, so privileged routes stay
+// closed unless explicitly enabled.
+func RequireDealer(dealerKey string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString(RoleContextKey) == RoleDealer {
+			c.Next()
+			return
+		}
+
+		providedKey := c.GetHeader(DealerKeyHeader)
+
+		if dealerKey == "" || providedKey == "" || subtle.ConstantTimeCompare([]byte(dealerKey), []byte(providedKey)) != 1 {
+			Reject(c, helper.ErrDealerOnly, "Rejected a caller who is not the dealer")
+			return
+		}
+
+		c.Set(RoleContextKey, RoleDealer)
+		c.Next()
+	}
+}
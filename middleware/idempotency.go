@@ -0,0 +1,245 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/varadekd/card-game/auth"
+	"github.com/varadekd/card-game/helper"
+)
+
+// IdempotencyKeyHeader is the header clients send to make a request safe to retry.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// IdempotentReplayHeader is set to true on responses replayed from the store.
+const IdempotentReplayHeader = "Idempotent-Replayed"
+
+// MaxIdempotencyKeyLength is the longest key accepted, UUIDs and similar tokens fit easily.
+const MaxIdempotencyKeyLength = 255
+
+// IdempotencyStore remembers the responses sent for idempotency keys during a TTL.
+// Keys are scoped by client and by player token, two clients or two players of the same
+// client can use the same key without seeing each other's responses.
+type IdempotencyStore struct {
+	ttl time.Duration
+
+	mu         sync.Mutex
+	entries    map[string]*idempotentEntry
+	lastPruned time.Time
+
+	// Now returns the current time, it can be replaced in tests.
+	Now func() time.Time
+}
+
+type idempotentEntry struct {
+	fingerprint [sha256.Size]byte
+	stored      time.Time
+
+	// done is closed once the first request completed, the response fields are set then.
+	// A nil response means it was not kept and the key can be used again.
+	done     chan struct{}
+	response *storedResponse
+}
+
+type storedResponse struct {
+	status int
+	header http.Header
+	body   []byte
+}
+
+// NewIdempotencyStore returns a store keeping responses for ttl. It returns nil when ttl
+// is zero or negative, which disables idempotency keys.
+func NewIdempotencyStore(ttl time.Duration) *IdempotencyStore {
+	if ttl <= 0 {
+		return nil
+	}
+
+	return &IdempotencyStore{
+		ttl:     ttl,
+		entries: map[string]*idempotentEntry{},
+		Now:     time.Now,
+	}
+}
+
+// begin returns the entry of the key, and whether it was created by this call. An existing
+// entry with another fingerprint is returned too, the caller compares them.
+func (s *IdempotencyStore) begin(key string, fingerprint [sha256.Size]byte) (*idempotentEntry, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.Now()
+	s.prune(now)
+
+	if entry, found := s.entries[key]; found && now.Sub(entry.stored) < s.ttl {
+		return entry, false
+	}
+
+	entry := &idempotentEntry{fingerprint: fingerprint, stored: now, done: make(chan struct{})}
+	s.entries[key] = entry
+	return entry, true
+}
+
+// finish stores the response of the first request of the key, or forgets the key when
+// the response is nil.
+func (s *IdempotencyStore) finish(key string, entry *idempotentEntry, response *storedResponse) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry.response = response
+	entry.stored = s.Now()
+
+	if response == nil && s.entries[key] == entry {
+		delete(s.entries, key)
+	}
+
+	close(entry.done)
+}
+
+// prune drops the expired entries. Entries still in flight are kept whatever their age.
+// The caller must hold the lock.
+func (s *IdempotencyStore) prune(now time.Time) {
+	if now.Sub(s.lastPruned) < bucketPruneInterval {
+		return
+	}
+
+	for key, entry := range s.entries {
+		select {
+		case <-entry.done:
+			if now.Sub(entry.stored) >= s.ttl {
+				delete(s.entries, key)
+			}
+		default:
+		}
+	}
+
+	s.lastPruned = now
+}
+
+// Idempotency replays the stored response when a request is sent again with the same
+// Idempotency-Key header, the handler runs only once. Reusing a key with another method,
+// path or body is rejected with 422. Requests sent while the first one is still running
+// wait for its response. Rate limited and server error responses are not stored so the
+// request can be retried with the same key. It must be used after Authenticate.
+// A nil store or a request without the header lets the request through.
+func Idempotency(store *IdempotencyStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+
+		if store == nil || key == "" {
+			c.Next()
+			return
+		}
+
+		if len(key) > MaxIdempotencyKeyLength {
+			Reject(c, helper.ErrIdempotencyKeyInvalid.WithMessage(fmt.Sprintf("The %s header can not be longer than %d characters", IdempotencyKeyHeader, MaxIdempotencyKeyLength)), "Rejected the idempotency key")
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+
+		if err != nil {
+			Reject(c, helper.ErrInvalidPayload, "Unable to read the payload of an idempotent request")
+			return
+		}
+
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		fingerprint := sha256.Sum256(append([]byte(c.Request.Method+" "+c.Request.URL.Path+"\n"), body...))
+		scopedKey := idempotencyScope(c) + "|" + key
+
+		for {
+			entry, created := store.begin(scopedKey, fingerprint)
+
+			if entry.fingerprint != fingerprint {
+				Reject(c, helper.ErrIdempotencyKeyReused, "Rejected the reuse of an idempotency key")
+				return
+			}
+
+			if created {
+				record(c, store, scopedKey, entry)
+				return
+			}
+
+			select {
+			case <-entry.done:
+			case <-c.Request.Context().Done():
+				c.Abort()
+				return
+			}
+
+			if entry.response != nil {
+				replay(c, entry.response)
+				return
+			}
+
+			// The first request was not kept, this one takes its place
+		}
+	}
+}
+
+// idempotencyScope identifies whose keys the request uses. Player tokens act for the API key
+// that requested them, so the player and its game are added to keep the players apart.
+func idempotencyScope(c *gin.Context) string {
+	scope := ClientKey(c)
+
+	if value, isPlayer := c.Get(ClaimsContextKey); isPlayer {
+		claims := value.(auth.Claims)
+		scope += "|player:" + claims.GameID + "/" + claims.PlayerID
+	}
+
+	return scope
+}
+
+// record runs the handler and stores its response for the key.
+func record(c *gin.Context, store *IdempotencyStore, key string, entry *idempotentEntry) {
+	writer := &recordingWriter{ResponseWriter: c.Writer}
+	c.Writer = writer
+
+	var response *storedResponse
+
+	// The entry is released even if the handler panics, waiting requests run it again
+	defer func() {
+		store.finish(key, entry, response)
+	}()
+
+	c.Next()
+
+	status := writer.Status()
+
+	if status != http.StatusTooManyRequests && status < http.StatusInternalServerError {
+		response = &storedResponse{status: status, header: writer.Header().Clone(), body: writer.body.Bytes()}
+	}
+}
+
+func replay(c *gin.Context, response *storedResponse) {
+	for name, values := range response.header {
+		c.Writer.Header()[name] = values
+	}
+
+	c.Header(IdempotentReplayHeader, "true")
+	c.Writer.WriteHeader(response.status)
+	c.Writer.Write(response.body)
+	c.Abort()
+}
+
+// recordingWriter keeps a copy of the body written to the client.
+type recordingWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *recordingWriter) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *recordingWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
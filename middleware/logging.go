@@ -0,0 +1,112 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+	"regexp"
+	"runtime/debug"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/varadekd/card-game/helper"
+	"github.com/varadekd/card-game/logging"
+)
+
+// RequestIDHeader is the header carrying the ID of a request, it is sent back on every response.
+const RequestIDHeader = "X-Request-ID"
+
+// RequestIDContextKey is the gin context key holding the ID of the request.
+const RequestIDContextKey = "requestID"
+
+// validRequestID limits the IDs accepted from clients, so they can not inject anything in the logs.
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// ValidRequestID reports whether a request ID sent by a client can be reused, every transport
+// generates a new one otherwise.
+func ValidRequestID(id string) bool {
+	return validRequestID.MatchString(id)
+}
+
+// RequestID reuses the X-Request-ID header sent by the client or a proxy, or generates one,
+// and sends it back. The logger of the request, see Logger, carries it as request_id.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+
+		if !ValidRequestID(id) {
+			id = uuid.NewString()
+		}
+
+		c.Set(RequestIDContextKey, id)
+		c.Header(RequestIDHeader, id)
+
+		logger := slog.Default().With("request_id", id)
+		c.Request = c.Request.WithContext(logging.WithLogger(c.Request.Context(), logger))
+
+		c.Next()
+	}
+}
+
+// Logger returns the logger of the request, it carries the request ID when RequestID ran.
+func Logger(c *gin.Context) *slog.Logger {
+	return logging.FromContext(c.Request.Context())
+}
+
+// LogRejection logs a request rejected before it reached the deck operations, with the code of
+// the error and the ID of the API key it was made for, the logger carries the request ID. Every
+// transport uses it so rejections are traced the same way.
+func LogRejection(logger *slog.Logger, e helper.APIError, msg, keyID string) {
+	logger.Info(msg, "error_code", e.Code, "error", e.Error(), "key_id", keyID)
+}
+
+// Reject logs why the request was rejected, see LogRejection, and sends the error.
+func Reject(c *gin.Context, e helper.APIError, msg string) {
+	LogRejection(Logger(c), e, msg, c.GetString(APIKeyIDContextKey))
+	helper.SendError(c, e)
+}
+
+// AccessLog logs every request once it is answered. It must be used after RequestID.
+func AccessLog() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		started := time.Now()
+
+		c.Next()
+
+		status := c.Writer.Status()
+		level := slog.LevelInfo
+
+		if status >= http.StatusInternalServerError {
+			level = slog.LevelError
+		}
+
+		Logger(c).LogAttrs(c.Request.Context(), level, "Request served",
+			slog.String("method", c.Request.Method),
+			slog.String("path", c.Request.URL.Path),
+			slog.String("route", c.FullPath()),
+			slog.Int("status", status),
+			slog.Float64("duration_ms", float64(time.Since(started).Microseconds())/1000),
+			slog.Int("bytes", c.Writer.Size()),
+			slog.String("client_ip", c.ClientIP()),
+			slog.String("user_agent", c.Request.UserAgent()),
+		)
+	}
+}
+
+// Recovery answers 500 when a handler panics and logs the panic with its stack.
+func Recovery() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				if recovered == http.ErrAbortHandler {
+					panic(recovered)
+				}
+
+				Logger(c).Error("Recovered from a panic", "panic", recovered, "stack", string(debug.Stack()))
+				helper.SendError(c, helper.ErrInternal)
+			}
+		}()
+
+		c.Next()
+	}
+}
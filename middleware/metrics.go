@@ -0,0 +1,21 @@
+package middleware
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/varadekd/card-game/metrics"
+)
+
+// RequestMetrics records the latency of the request in metrics.RequestDuration, labelled
+// with the route template rather than the path so deck IDs do not create new series.
+func RequestMetrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		started := time.Now()
+
+		c.Next()
+
+		metrics.RequestDuration.Observe(time.Since(started).Seconds(), c.Request.Method, c.FullPath(), strconv.Itoa(c.Writer.Status()))
+	}
+}
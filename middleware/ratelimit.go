@@ -0,0 +1,167 @@
+package middleware
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/varadekd/card-game/helper"
+)
+
+// bucketPruneInterval is how often buckets of idle clients are dropped.
+const bucketPruneInterval = time.Minute
+
+// RateLimiter is a token bucket limiter keyed by client. Every client gets a bucket
+// holding up to burst tokens, refilled at perMinute tokens per minute.
+type RateLimiter struct {
+	rate  float64 // tokens added per second
+	burst float64
+
+	mu         sync.Mutex
+	buckets    map[string]*bucket
+	lastPruned time.Time
+
+	// Now returns the current time, it can be replaced in tests.
+	Now func() time.Time
+}
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// NewRateLimiter returns a limiter allowing perMinute requests per minute with bursts of up to
+// burst requests. It returns nil when perMinute is zero or negative, which disables the limit.
+func NewRateLimiter(perMinute, burst int) *RateLimiter {
+	if perMinute <= 0 {
+		return nil
+	}
+
+	if burst <= 0 {
+		burst = 1
+	}
+
+	return &RateLimiter{
+		rate:    float64(perMinute) / 60,
+		burst:   float64(burst),
+		buckets: map[string]*bucket{},
+		Now:     time.Now,
+	}
+}
+
+// Allow takes a token from the bucket of the client. It returns whether the request is allowed,
+// how many tokens are left and how long until the next token is available.
+func (l *RateLimiter) Allow(key string) (bool, int, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.Now()
+	l.prune(now)
+
+	b, found := l.buckets[key]
+
+	if !found {
+		b = &bucket{tokens: l.burst, updated: now}
+		l.buckets[key] = b
+	}
+
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.updated).Seconds()*l.rate)
+	b.updated = now
+
+	if b.tokens < 1 {
+		return false, 0, l.durationFor(1 - b.tokens)
+	}
+
+	b.tokens--
+	return true, int(b.tokens), 0
+}
+
+// Limit returns the burst size, the maximum number of requests a client can send at once.
+func (l *RateLimiter) Limit() int {
+	return int(l.burst)
+}
+
+// ResetAfter returns how long until the bucket of the client is full again.
+func (l *RateLimiter) ResetAfter(key string) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b, found := l.buckets[key]
+
+	if !found {
+		return 0
+	}
+
+	return l.durationFor(l.burst - b.tokens)
+}
+
+func (l *RateLimiter) durationFor(tokens float64) time.Duration {
+	return time.Duration(tokens / l.rate * float64(time.Second))
+}
+
+// prune drops the buckets that have refilled completely, a missing bucket behaves
+// exactly like a full one. The caller must hold the lock.
+func (l *RateLimiter) prune(now time.Time) {
+	if now.Sub(l.lastPruned) < bucketPruneInterval {
+		return
+	}
+
+	for key, b := range l.buckets {
+		if b.tokens+now.Sub(b.updated).Seconds()*l.rate >= l.burst {
+			delete(l.buckets, key)
+		}
+	}
+
+	l.lastPruned = now
+}
+
+// ClientKey identifies the client for rate limiting and quotas: the API key when the
+// request is authenticated, the client IP otherwise. It must be used after Authenticate.
+func ClientKey(c *gin.Context) string {
+	return ClientKeyFor(c.GetString(APIKeyIDContextKey), c.ClientIP())
+}
+
+// ClientKeyFor identifies the client from the ID of its API key, or from its IP when it has none.
+// Transports other than gin use it so quotas are shared with the REST api.
+func ClientKeyFor(keyID, ip string) string {
+	if keyID != "" {
+		return "key:" + keyID
+	}
+
+	return "ip:" + ip
+}
+
+// RateLimit rejects requests over the limit of the client with 429 and a Retry-After header.
+// Every response carries the X-RateLimit-Limit, X-RateLimit-Remaining and X-RateLimit-Reset
+// headers. A nil limiter lets every request through.
+func RateLimit(limiter *RateLimiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if limiter == nil {
+			c.Next()
+			return
+		}
+
+		key := ClientKey(c)
+		allowed, remaining, retryAfter := limiter.Allow(key)
+
+		c.Header("X-RateLimit-Limit", strconv.Itoa(limiter.Limit()))
+		c.Header("X-RateLimit-Remaining", strconv.Itoa(remaining))
+		c.Header("X-RateLimit-Reset", strconv.Itoa(ceilSeconds(limiter.ResetAfter(key))))
+
+		if !allowed {
+			seconds := ceilSeconds(retryAfter)
+			c.Header("Retry-After", strconv.Itoa(seconds))
+			Reject(c, helper.ErrRateLimited.WithMessage(fmt.Sprintf("Rate limit exceeded, retry in %d seconds", seconds)), "Rejected the request over the rate limit")
+			return
+		}
+
+		c.Next()
+	}
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
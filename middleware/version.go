@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// APIVersionContextKey is the gin context key holding the version of the api served by the route.
+const APIVersionContextKey = "apiVersion"
+
+// LatestAPIVersion is the version of the api new clients should use.
+const LatestAPIVersion = 2
+
+// DeprecationHeader announces that the route is deprecated since the date it holds, as
+// defined by RFC 9745. The route of the successor version is sent in the Link header.
+const DeprecationHeader = "Deprecation"
+
+// APIVersion records the version of the api served by the route group, see Version.
+func APIVersion(version int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(APIVersionContextKey, version)
+		c.Next()
+	}
+}
+
+// Version returns the version of the api served by the route, routes registered outside of a
+// versioned group are served by LatestAPIVersion.
+func Version(c *gin.Context) int {
+	if version := c.GetInt(APIVersionContextKey); version > 0 {
+		return version
+	}
+
+	return LatestAPIVersion
+}
+
+// Deprecated marks every response of the route group as deprecated since the date, and links
+// to the same route under successorPrefix, e.g. /deck/{id} links to /v2/deck/{id}.
+func Deprecated(since time.Time, successorPrefix string) gin.HandlerFunc {
+	deprecation := fmt.Sprintf("@%d", since.Unix())
+
+	return func(c *gin.Context) {
+		c.Header(DeprecationHeader, deprecation)
+		c.Header("Link", fmt.Sprintf(`<%s%s>; rel="successor-version"`, successorPrefix, c.Request.URL.Path))
+		c.Next()
+	}
+}
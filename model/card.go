@@ -7,6 +7,6 @@ package model
 
 type Card struct {
 	Value string `json:"value"`
-	Code  string `json:"code:"`
+	Code  string `json:"code"`
 	Suit  string `json:"suit"`
 }
@@ -6,28 +6,138 @@ import (
 	"github.com/google/uuid"
 )
 
+// Deck is a set of cards generated for a game.
+// SourceDeckID is the ID of the deck this deck was cloned from, it is empty for generated decks.
+// Round starts at 0 and is increased every time the deck is reset.
+// AuditTrail records the privileged operations done on the deck, oldest first.
+// OwnerID is the ID of the API key that created the deck, only that key can use it.
+// Hands holds the cards drawn into each player's hand, keyed by player ID.
+// Piles holds the cards discarded from the hands, keyed by pile name, e.g. "discard".
+// CreatedBy identifies the client that created the deck for quotas, it is never sent to clients.
+// Version starts at 1 and is increased by the store every time the deck changes, it is sent
+// as the ETag of the deck so clients can tell whether their copy is stale.
 type Deck struct {
-	ID             uuid.UUID `json:"_id"`
-	GameID         string    `json:"gameID"`
-	Shuffle        bool      `json:"shuffle"`
-	GeneratedDeck  []Card    `json:"generatedDeck"`
-	PlayingCards   []Card    `json:"playingCards"`
-	DeckSize       int       `json:"deckSize"`
-	CardsRemaining int       `json:"cardRemaining"`
-	DeckLastUsed   time.Time `json:"deckLastUsed"`
-	CreatedAt      time.Time `json:"createdAt"`
+	ID             uuid.UUID         `json:"id"`
+	GameID         string            `json:"gameID"`
+	Shuffle        bool              `json:"shuffle"`
+	GeneratedDeck  []Card            `json:"generatedDeck"`
+	PlayingCards   []Card            `json:"playingCards"`
+	DeckSize       int               `json:"deckSize"`
+	CardsRemaining int               `json:"cardsRemaining"`
+	DeckLastUsed   time.Time         `json:"deckLastUsed"`
+	CreatedAt      time.Time         `json:"createdAt"`
+	SourceDeckID   string            `json:"sourceDeckID"`
+	Round          int               `json:"round"`
+	AuditTrail     []DeckEvent       `json:"auditTrail"`
+	OwnerID        string            `json:"ownerID"`
+	Hands          map[string][]Card `json:"hands"`
+	Piles          map[string][]Card `json:"piles"`
+	CreatedBy      string            `json:"-"`
+	Version        int               `json:"version"`
+}
+
+// Actions recorded in the deck audit trail.
+const (
+	DeckActionReset = "reset"
+	DeckActionPeek  = "peek"
+)
+
+// Actions only sent to the clients watching a deck, they are not part of the audit trail.
+const (
+	DeckActionDraw    = "draw"
+	DeckActionDiscard = "discard"
+	DeckActionDelete  = "delete"
+	DeckActionExpire  = "expire"
+)
+
+// DeckEvent is a single entry of the deck audit trail.
+type DeckEvent struct {
+	Action string    `json:"action"`
+	Round  int       `json:"round"`
+	At     time.Time `json:"at"`
+	Detail string    `json:"detail"`
+}
+
+// DeckUpdate is sent to the clients watching a deck every time the deck changes.
+// CardsRemaining is the number of cards left after the change.
+type DeckUpdate struct {
+	DeckID         uuid.UUID `json:"deckID"`
+	Event          DeckEvent `json:"event"`
+	CardsRemaining int       `json:"cardsRemaining"`
+}
+
+// Last reports whether the deck is gone after the update, it was deleted or it expired, so
+// no update follows it.
+func (u DeckUpdate) Last() bool {
+	return u.Event.Action == DeckActionDelete || u.Event.Action == DeckActionExpire
 }
 
 // GenerateDeckPayload is used for creation on new deck
 // GameID will be used to uniquely identify the deck used in that game.
 // Shuffle true means the card sequence will be shuffled, false will be in sequence.
-// Cards field will be used in case user wants only specific cards to be part of the deck
+// Cards field will be used in case user wants only specific cards to be part of the deck,
+// every code should be a valid card code and appear only once.
 type GenerateDeckPayload struct {
 	GameID  string   `json:"gameID"`
 	Shuffle bool     `json:"shuffle"`
-	Cards   []string `json:"cards"`
+	Cards   []string `json:"cards" binding:"omitempty,unique,dive,cardcode"`
 }
 
+// DrawCardFromDeckPayload is used for drawing cards from a deck.
+// PlayerID is the hand the drawn cards go to, players holding a token always draw into their own hand.
 type DrawCardFromDeckPayload struct {
-	CardsToBeDrawn int `json:"cardsToBeDrawn"`
+	CardsToBeDrawn int    `json:"cardsToBeDrawn" binding:"min=1"`
+	PlayerID       string `json:"playerID"`
+}
+
+// DiscardCardsPayload is used for moving cards from the hand of a player to a pile of the deck.
+// PlayerID is the hand the cards are taken from, players holding a token always discard from their
+// own hand. Pile is the name of the pile, it is created by the first discard.
+type DiscardCardsPayload struct {
+	PlayerID string   `json:"playerID"`
+	Cards    []string `json:"cards" binding:"min=1,unique,dive,cardcode"`
+	Pile     string   `json:"pile" binding:"required,max=64"`
+}
+
+// CloneDeckPayload is used for cloning an existing deck.
+// GameID is the game the clone belongs to, the source deck's GameID is used when empty.
+// CopyState true copies the remaining cards of the source deck, false starts the clone
+// from the full generated order.
+type CloneDeckPayload struct {
+	GameID    string `json:"gameID"`
+	CopyState bool   `json:"copyState"`
+}
+
+// ResetDeckPayload is used for resetting a deck, Shuffle true reshuffles the cards put back in play.
+type ResetDeckPayload struct {
+	Shuffle bool `json:"shuffle"`
+}
+
+// PeekDeckPayload holds the query parameters accepted when peeking at a deck.
+// Count is the number of cards to look at, From is either "top" or "bottom".
+type PeekDeckPayload struct {
+	Count int    `form:"count" binding:"min=1"`
+	From  string `form:"from" binding:"oneof=top bottom"`
+}
+
+// ListDecksPayload holds the query parameters accepted when listing decks.
+// Time filters are expected in RFC3339 format, Status is either "active" or "exhausted".
+// Sort is the field to sort on (createdAt, lastUsed or cardsRemaining), prefixed with "-" for descending order.
+// Cursor is the nextCursor returned with the previous page.
+type ListDecksPayload struct {
+	GameID         string    `form:"gameID"`
+	Shuffle        *bool     `form:"shuffle"`
+	CreatedAfter   time.Time `form:"createdAfter"`
+	CreatedBefore  time.Time `form:"createdBefore"`
+	LastUsedAfter  time.Time `form:"lastUsedAfter"`
+	LastUsedBefore time.Time `form:"lastUsedBefore"`
+	Status         string    `form:"status" binding:"omitempty,oneof=active exhausted"`
+	Sort           string    `form:"sort" binding:"omitempty,oneof=createdAt -createdAt lastUsed -lastUsed cardsRemaining -cardsRemaining"`
+	Limit          int       `form:"limit" binding:"min=0,max=100"`
+	Cursor         string    `form:"cursor"`
+}
+
+// DeleteDecksResult reports how many decks were removed by a bulk delete.
+type DeleteDecksResult struct {
+	Deleted int `json:"deleted"`
 }
@@ -0,0 +1,22 @@
+package model
+
+import "time"
+
+// IssueTokenPayload is used for minting a player token.
+// PlayerID and GameID are bound to the token, Role is one of dealer, player or spectator.
+// TTLSeconds is how long the token stays valid, the server default is used when it is zero.
+type IssueTokenPayload struct {
+	PlayerID   string `json:"playerID" binding:"required"`
+	GameID     string `json:"gameID" binding:"required"`
+	Role       string `json:"role" binding:"required,oneof=dealer player spectator"`
+	TTLSeconds int    `json:"ttlSeconds" binding:"min=0"`
+}
+
+// PlayerToken is the response of a minted player token.
+type PlayerToken struct {
+	Token     string    `json:"token"`
+	PlayerID  string    `json:"playerID"`
+	GameID    string    `json:"gameID"`
+	Role      string    `json:"role"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
@@ -0,0 +1,91 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// The deck routes of the first release, served under /deck, keep their original JSON names so
+// existing clients are not broken: the code of a card is sent under "code:", the ID of a deck
+// under "_id" and its remaining cards under "cardRemaining". They are deprecated, new clients
+// use the /v2 routes which send Deck and Card as they are.
+
+// CardV1 is a card as sent by the v1 routes.
+type CardV1 struct {
+	Value string `json:"value"`
+	Code  string `json:"code:"`
+	Suit  string `json:"suit"`
+}
+
+// DeckV1 is a deck as sent by the v1 routes.
+type DeckV1 struct {
+	ID             uuid.UUID           `json:"_id"`
+	GameID         string              `json:"gameID"`
+	Shuffle        bool                `json:"shuffle"`
+	GeneratedDeck  []CardV1            `json:"generatedDeck"`
+	PlayingCards   []CardV1            `json:"playingCards"`
+	DeckSize       int                 `json:"deckSize"`
+	CardsRemaining int                 `json:"cardRemaining"`
+	DeckLastUsed   time.Time           `json:"deckLastUsed"`
+	CreatedAt      time.Time           `json:"createdAt"`
+	SourceDeckID   string              `json:"sourceDeckID"`
+	Round          int                 `json:"round"`
+	AuditTrail     []DeckEvent         `json:"auditTrail"`
+	OwnerID        string              `json:"ownerID"`
+	Hands          map[string][]CardV1 `json:"hands"`
+	Piles          map[string][]CardV1 `json:"piles"`
+	Version        int                 `json:"version"`
+}
+
+// NewCardsV1 returns the cards in their v1 shape, nil stays nil so it is still sent as null.
+func NewCardsV1(cards []Card) []CardV1 {
+	if cards == nil {
+		return nil
+	}
+
+	converted := make([]CardV1, len(cards))
+
+	for i, card := range cards {
+		converted[i] = CardV1(card)
+	}
+
+	return converted
+}
+
+// newCardSetsV1 returns the hands or piles in their v1 shape, nil stays nil.
+func newCardSetsV1(sets map[string][]Card) map[string][]CardV1 {
+	if sets == nil {
+		return nil
+	}
+
+	converted := make(map[string][]CardV1, len(sets))
+
+	for name, cards := range sets {
+		converted[name] = NewCardsV1(cards)
+	}
+
+	return converted
+}
+
+// NewDeckV1 returns the deck in its v1 shape.
+func NewDeckV1(deck Deck) DeckV1 {
+	return DeckV1{
+		ID:             deck.ID,
+		GameID:         deck.GameID,
+		Shuffle:        deck.Shuffle,
+		GeneratedDeck:  NewCardsV1(deck.GeneratedDeck),
+		PlayingCards:   NewCardsV1(deck.PlayingCards),
+		DeckSize:       deck.DeckSize,
+		CardsRemaining: deck.CardsRemaining,
+		DeckLastUsed:   deck.DeckLastUsed,
+		CreatedAt:      deck.CreatedAt,
+		SourceDeckID:   deck.SourceDeckID,
+		Round:          deck.Round,
+		AuditTrail:     deck.AuditTrail,
+		OwnerID:        deck.OwnerID,
+		Hands:          newCardSetsV1(deck.Hands),
+		Piles:          newCardSetsV1(deck.Piles),
+		Version:        deck.Version,
+	}
+}
@@ -0,0 +1,355 @@
+package openapi
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/varadekd/card-game/cards"
+	"github.com/varadekd/card-game/helper"
+)
+
+// Names of the security schemes added to every document.
+const (
+	SecurityAPIKey    = "apiKey"
+	SecurityBearer    = "playerToken"
+	SecurityDealerKey = "dealerKey"
+)
+
+// tagPatterns holds the regular expressions matching the custom validation tags of the payloads.
+var tagPatterns = map[string]string{
+	"cardcode": cards.CodePattern,
+}
+
+// Operation describes a route of the api, the document is generated from the list of operations.
+type Operation struct {
+	ID          string
+	Method      string
+	Path        string // gin style path, e.g. /deck/:id
+	Summary     string
+	Description string
+	Tags        []string
+
+	// Query is a struct bound from the query string with form tags, Body the JSON payload.
+	// Headers lists the request headers the route reads besides the credentials.
+	Query        interface{}
+	Headers      []Parameter
+	Body         interface{}
+	BodyOptional bool
+
+	// Status is sent on success with Data in the standard response. Routes which do not use
+	// the standard response set Raw instead, sent as ContentType (JSON by default).
+	// EmptyStatuses are other successful statuses sent without a body, like 304 Not Modified.
+	Status        int
+	Data          interface{}
+	Raw           interface{}
+	ContentType   string
+	EmptyStatuses []int
+
+	// Errors lists the errors of the catalogue the route can send.
+	Errors []helper.APIError
+
+	// Public routes skip authentication, Dealer routes also accept the dealer key.
+	Public bool
+	Dealer bool
+
+	// Deprecated routes still work but clients should move to their successor.
+	Deprecated bool
+}
+
+// Path converts a gin style path to the OpenAPI template syntax, /deck/:id becomes /deck/{id}.
+func Path(ginPath string) string {
+	segments := strings.Split(ginPath, "/")
+
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			segments[i] = "{" + segment[1:] + "}"
+		}
+	}
+
+	return strings.Join(segments, "/")
+}
+
+// Generate builds the document describing the operations. The schemas are derived from
+// the Go types of the operations using their json, form and binding tags.
+func Generate(info Info, ops []Operation) *Document {
+	g := generator{schemas: map[string]*Schema{}}
+
+	doc := &Document{
+		OpenAPI: Version,
+		Info:    info,
+		Paths:   map[string]map[string]*PathItem{},
+		Components: Components{
+			Schemas: g.schemas,
+			SecuritySchemes: map[string]*SecurityScheme{
+				SecurityAPIKey:    {Type: "apiKey", In: "header", Name: "X-API-Key", Description: "API key, required when API_KEYS_FILE is set"},
+				SecurityBearer:    {Type: "http", Scheme: "bearer", BearerFormat: "JWT", Description: "Player token issued with POST /token"},
+				SecurityDealerKey: {Type: "apiKey", In: "header", Name: "X-Dealer-Key", Description: "Dealer key set with DEALER_KEY"},
+			},
+		},
+	}
+
+	for _, op := range ops {
+		path := Path(op.Path)
+
+		if doc.Paths[path] == nil {
+			doc.Paths[path] = map[string]*PathItem{}
+		}
+
+		doc.Paths[path][strings.ToLower(op.Method)] = g.operation(op)
+	}
+
+	return doc
+}
+
+type generator struct {
+	schemas map[string]*Schema
+}
+
+func (g *generator) operation(op Operation) *PathItem {
+	item := &PathItem{
+		Summary:     op.Summary,
+		Description: op.Description,
+		OperationID: op.ID,
+		Tags:        op.Tags,
+		Deprecated:  op.Deprecated,
+		Responses:   map[string]Response{},
+	}
+
+	for _, segment := range strings.Split(op.Path, "/") {
+		if strings.HasPrefix(segment, ":") {
+			item.Parameters = append(item.Parameters, Parameter{Name: segment[1:], In: "path", Required: true, Schema: &Schema{Type: "string"}})
+		}
+	}
+
+	if op.Query != nil {
+		item.Parameters = append(item.Parameters, g.queryParameters(reflect.TypeOf(op.Query))...)
+	}
+
+	item.Parameters = append(item.Parameters, op.Headers...)
+
+	if op.Body != nil {
+		item.RequestBody = &RequestBody{
+			Required: !op.BodyOptional,
+			Content:  map[string]MediaType{"application/json": {Schema: g.schemaFor(reflect.TypeOf(op.Body))}},
+		}
+	}
+
+	item.Responses[strconv.Itoa(op.Status)] = g.successResponse(op)
+
+	for _, status := range op.EmptyStatuses {
+		item.Responses[strconv.Itoa(status)] = Response{Description: http.StatusText(status)}
+	}
+
+	// Errors sharing a status are described together, listing their codes
+	codes := map[int][]string{}
+
+	for _, e := range op.Errors {
+		codes[e.Status] = append(codes[e.Status], e.Code)
+	}
+
+	for status, statusCodes := range codes {
+		sort.Strings(statusCodes)
+		item.Responses[strconv.Itoa(status)] = Response{
+			Description: fmt.Sprintf("%s: %s", http.StatusText(status), strings.Join(statusCodes, ", ")),
+			Content:     map[string]MediaType{"application/json": {Schema: g.schemaFor(reflect.TypeOf(helper.ResponseJSON{}))}},
+		}
+	}
+
+	if !op.Public {
+		// An empty requirement keeps the route usable when authentication is disabled
+		item.Security = []map[string][]string{{}, {SecurityAPIKey: {}}, {SecurityBearer: {}}}
+
+		if op.Dealer {
+			item.Security = append(item.Security, map[string][]string{SecurityDealerKey: {}})
+		}
+	}
+
+	return item
+}
+
+func (g *generator) successResponse(op Operation) Response {
+	response := Response{Description: http.StatusText(op.Status)}
+
+	if op.Raw != nil {
+		contentType := op.ContentType
+
+		if contentType == "" {
+			contentType = "application/json"
+		}
+
+		schema := &Schema{Type: "string"}
+
+		if contentType == "application/json" {
+			schema = g.schemaFor(reflect.TypeOf(op.Raw))
+		}
+
+		response.Content = map[string]MediaType{contentType: {Schema: schema}}
+		return response
+	}
+
+	schema := g.schemaFor(reflect.TypeOf(helper.ResponseJSON{}))
+
+	if op.Data != nil {
+		schema = &Schema{AllOf: []*Schema{schema, {
+			Type:       "object",
+			Properties: map[string]*Schema{"data": g.schemaFor(reflect.TypeOf(op.Data))},
+		}}}
+	}
+
+	response.Content = map[string]MediaType{"application/json": {Schema: schema}}
+	return response
+}
+
+func (g *generator) queryParameters(t reflect.Type) []Parameter {
+	params := []Parameter{}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := strings.Split(field.Tag.Get("form"), ",")[0]
+
+		if name == "" || name == "-" {
+			continue
+		}
+
+		schema := g.schemaFor(field.Type)
+		required := applyBinding(schema, field.Tag.Get("binding"))
+		params = append(params, Parameter{Name: name, In: "query", Required: required, Schema: schema})
+	}
+
+	return params
+}
+
+// schemaFor returns the schema of the type, named structs are added to the components
+// and referenced.
+func (g *generator) schemaFor(t reflect.Type) *Schema {
+	if t == reflect.TypeOf(time.Time{}) {
+		return &Schema{Type: "string", Format: "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		schema := g.schemaFor(t.Elem())
+
+		if schema.Ref == "" {
+			schema.Nullable = true
+		}
+		return schema
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: g.schemaFor(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.schemaFor(t.Elem())}
+	case reflect.Struct:
+		return g.structSchema(t)
+	}
+
+	// Interfaces can hold any value
+	return &Schema{}
+}
+
+func (g *generator) structSchema(t reflect.Type) *Schema {
+	ref := &Schema{Ref: "#/components/schemas/" + t.Name()}
+
+	if _, found := g.schemas[t.Name()]; found && t.Name() != "" {
+		return ref
+	}
+
+	schema := &Schema{Type: "object", Properties: map[string]*Schema{}}
+
+	// Registering the schema before its fields keeps recursive types finite
+	if t.Name() != "" {
+		g.schemas[t.Name()] = schema
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+
+		if !field.IsExported() || name == "-" {
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+
+		property := g.schemaFor(field.Type)
+
+		if applyBinding(property, field.Tag.Get("binding")) {
+			schema.Required = append(schema.Required, name)
+		}
+
+		schema.Properties[name] = property
+	}
+
+	if t.Name() == "" {
+		return schema
+	}
+
+	return ref
+}
+
+// applyBinding adds the constraints of the binding tag to the schema and reports whether the
+// field is required. Constraints after dive apply to the items of a slice.
+func applyBinding(schema *Schema, binding string) bool {
+	if binding == "" || schema.Ref != "" {
+		return false
+	}
+
+	required := false
+	target := schema
+
+	for _, rule := range strings.Split(binding, ",") {
+		tag, param, _ := strings.Cut(rule, "=")
+
+		switch tag {
+		case "required":
+			required = true
+		case "dive":
+			if target.Items == nil || target.Items.Ref != "" {
+				return required
+			}
+			target = target.Items
+		case "unique":
+			target.UniqueItems = true
+		case "oneof":
+			target.Enum = strings.Fields(param)
+		case "min", "max":
+			if target.Type != "integer" && target.Type != "number" {
+				continue
+			}
+
+			value, err := strconv.ParseFloat(param, 64)
+
+			if err != nil {
+				continue
+			}
+
+			if tag == "min" {
+				target.Minimum = &value
+			} else {
+				target.Maximum = &value
+			}
+		default:
+			if pattern, found := tagPatterns[tag]; found {
+				target.Pattern = pattern
+			}
+		}
+	}
+
+	return required
+}
@@ -0,0 +1,47 @@
+package store
+
+import (
+	"time"
+
+	"github.com/varadekd/card-game/model"
+)
+
+// ExpiryPolicy decides when a deck is no longer usable:
+// 1. IdleTTL is how long a deck may go without being used, counted from DeckLastUsed
+// (or CreatedAt when the deck was never used).
+// 2. MaxAge is the absolute lifetime of a deck counted from CreatedAt.
+// 3. GracePeriod is how long an expired deck ID keeps answering 410 Gone before
+// it is forgotten and treated as never existing.
+// A zero IdleTTL or MaxAge disables that check.
+type ExpiryPolicy struct {
+	IdleTTL     time.Duration
+	MaxAge      time.Duration
+	GracePeriod time.Duration
+}
+
+// DefaultExpiryPolicy is used when the application does not configure one.
+var DefaultExpiryPolicy = ExpiryPolicy{
+	IdleTTL:     24 * time.Hour,
+	MaxAge:      7 * 24 * time.Hour,
+	GracePeriod: time.Hour,
+}
+
+// Expired reports whether the deck has crossed either the idle or the max age limit at now.
+func (p ExpiryPolicy) Expired(deck model.Deck, now time.Time) bool {
+	if p.MaxAge > 0 && now.Sub(deck.CreatedAt) >= p.MaxAge {
+		return true
+	}
+
+	if p.IdleTTL > 0 {
+		lastUsed := deck.DeckLastUsed
+		if lastUsed.IsZero() {
+			lastUsed = deck.CreatedAt
+		}
+
+		if now.Sub(lastUsed) >= p.IdleTTL {
+			return true
+		}
+	}
+
+	return false
+}
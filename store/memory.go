@@ -0,0 +1,287 @@
+package store
+
+import (
+	"sync"
+	"time"
+
+	"github.com/varadekd/card-game/model"
+)
+
+// MemoryStore keeps decks in the application memory. All the data vanishes once
+// the application is terminated, unless it is saved with SaveSnapshot.
+type MemoryStore struct {
+	mu     sync.Mutex
+	policy ExpiryPolicy
+	decks  map[string]model.Deck
+	// expired holds the time at which a deck was expired, it is used to answer
+	// with ErrDeckExpired until the grace period is over.
+	expired map[string]time.Time
+
+	// OnExpire is called with every deck removed by the expiry policy, whether it is swept or
+	// found expired on lookup. It runs while the store lock is held so it must not use the store.
+	OnExpire func(deck model.Deck)
+
+	// Now returns the current time, it can be replaced in tests.
+	Now func() time.Time
+}
+
+// NewMemoryStore returns an empty in-memory store enforcing the given expiry policy.
+func NewMemoryStore(policy ExpiryPolicy) *MemoryStore {
+	return &MemoryStore{
+		policy:  policy,
+		decks:   map[string]model.Deck{},
+		expired: map[string]time.Time{},
+		Now:     time.Now,
+	}
+}
+
+func (s *MemoryStore) Create(deck model.Deck) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := deck.ID.String()
+
+	if _, found := s.decks[id]; found {
+		return ErrDeckExists
+	}
+
+	s.decks[id] = cloneDeck(deck)
+	return nil
+}
+
+func (s *MemoryStore) CreateWithLimit(deck model.Deck, max int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := deck.ID.String()
+
+	if _, found := s.decks[id]; found {
+		return ErrDeckExists
+	}
+
+	if max > 0 && s.count(DeckQuery{CreatedBy: deck.CreatedBy}, s.Now()) >= max {
+		return ErrQuotaExceeded
+	}
+
+	s.decks[id] = cloneDeck(deck)
+	return nil
+}
+
+func (s *MemoryStore) Get(id string) (model.Deck, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	deck, err := s.lookup(id, s.Now())
+
+	if err != nil {
+		return model.Deck{}, err
+	}
+
+	return cloneDeck(deck), nil
+}
+
+func (s *MemoryStore) Update(id string, fn func(deck *model.Deck) error) (model.Deck, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	deck, err := s.lookup(id, s.Now())
+
+	if err != nil {
+		return model.Deck{}, err
+	}
+
+	// Working on a copy so a failing fn does not leave a half updated deck behind.
+	updated := cloneDeck(deck)
+
+	if err := fn(&updated); err != nil {
+		return model.Deck{}, err
+	}
+
+	updated.Version = deck.Version + 1
+	s.decks[id] = updated
+	return cloneDeck(updated), nil
+}
+
+func (s *MemoryStore) AppendAudit(id string, fn func(deck model.Deck) (model.DeckEvent, error)) (model.Deck, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	deck, err := s.lookup(id, s.Now())
+
+	if err != nil {
+		return model.Deck{}, err
+	}
+
+	event, err := fn(cloneDeck(deck))
+
+	if err != nil {
+		return model.Deck{}, err
+	}
+
+	// The trail is copied so decks returned earlier never see the new event
+	deck.AuditTrail = append(append([]model.DeckEvent(nil), deck.AuditTrail...), event)
+	s.decks[id] = deck
+	return cloneDeck(deck), nil
+}
+
+func (s *MemoryStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := s.lookup(id, s.Now())
+
+	if err != nil {
+		return err
+	}
+
+	delete(s.decks, id)
+	return nil
+}
+
+func (s *MemoryStore) DeleteByGame(gameID, ownerID string) ([]model.Deck, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.Now()
+	removed := []model.Deck{}
+
+	for id, deck := range s.decks {
+		if deck.GameID != gameID || (ownerID != "" && deck.OwnerID != ownerID) {
+			continue
+		}
+
+		// Expired decks are already reported as gone, they are not counted as deleted
+		if s.policy.Expired(deck, now) {
+			s.expire(id, now)
+			continue
+		}
+
+		delete(s.decks, id)
+		removed = append(removed, deck)
+	}
+
+	return removed, nil
+}
+
+func (s *MemoryStore) List(query DeckQuery) (DeckPage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.Now()
+	decks := []model.Deck{}
+
+	for _, deck := range s.decks {
+		if s.policy.Expired(deck, now) || !query.Matches(deck) {
+			continue
+		}
+		decks = append(decks, cloneDeck(deck))
+	}
+
+	return Paginate(decks, query)
+}
+
+func (s *MemoryStore) Count(query DeckQuery) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.count(query, s.Now()), nil
+}
+
+// count returns how many active decks match the filters of the query. The caller must hold the lock.
+func (s *MemoryStore) count(query DeckQuery, now time.Time) int {
+	count := 0
+
+	for _, deck := range s.decks {
+		if !s.policy.Expired(deck, now) && query.Matches(deck) {
+			count++
+		}
+	}
+
+	return count
+}
+
+func (s *MemoryStore) Sweep() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.Now()
+	removed := 0
+
+	for id, deck := range s.decks {
+		if s.policy.Expired(deck, now) {
+			s.expire(id, now)
+			removed++
+		}
+	}
+
+	// Forgetting the decks whose grace period is over
+	for id, expiredAt := range s.expired {
+		if now.Sub(expiredAt) >= s.policy.GracePeriod {
+			delete(s.expired, id)
+		}
+	}
+
+	return removed
+}
+
+// lookup finds the deck and applies the expiry policy lazily, so a deck that
+// expired between two sweeps is never served. The caller must hold the lock.
+func (s *MemoryStore) lookup(id string, now time.Time) (model.Deck, error) {
+	deck, found := s.decks[id]
+
+	if found {
+		if s.policy.Expired(deck, now) {
+			s.expire(id, now)
+			return model.Deck{}, ErrDeckExpired
+		}
+		return deck, nil
+	}
+
+	expiredAt, found := s.expired[id]
+
+	if found {
+		if now.Sub(expiredAt) < s.policy.GracePeriod {
+			return model.Deck{}, ErrDeckExpired
+		}
+		delete(s.expired, id)
+	}
+
+	return model.Deck{}, ErrDeckNotFound
+}
+
+// expire moves the deck to the expired list. The caller must hold the lock.
+func (s *MemoryStore) expire(id string, now time.Time) {
+	deck := s.decks[id]
+	delete(s.decks, id)
+
+	if s.policy.GracePeriod > 0 {
+		s.expired[id] = now
+	}
+
+	if s.OnExpire != nil {
+		s.OnExpire(deck)
+	}
+}
+
+// cloneDeck copies the slices so callers never share memory with the stored deck.
+func cloneDeck(deck model.Deck) model.Deck {
+	deck.GeneratedDeck = append([]model.Card(nil), deck.GeneratedDeck...)
+	deck.PlayingCards = append([]model.Card(nil), deck.PlayingCards...)
+	deck.AuditTrail = append([]model.DeckEvent(nil), deck.AuditTrail...)
+	deck.Hands = cloneCardSets(deck.Hands)
+	deck.Piles = cloneCardSets(deck.Piles)
+	return deck
+}
+
+// cloneCardSets copies the hands or piles of a deck, nil stays nil.
+func cloneCardSets(sets map[string][]model.Card) map[string][]model.Card {
+	if sets == nil {
+		return nil
+	}
+
+	copied := make(map[string][]model.Card, len(sets))
+	for name, cards := range sets {
+		copied[name] = append([]model.Card(nil), cards...)
+	}
+	return copied
+}
@@ -0,0 +1,211 @@
+package store
+
+import (
+	"encoding/base64"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/varadekd/card-game/model"
+)
+
+// Deck states used for filtering, an exhausted deck has no cards remaining.
+const (
+	DeckStatusActive    = "active"
+	DeckStatusExhausted = "exhausted"
+)
+
+// Fields a deck list can be sorted on.
+const (
+	SortByCreatedAt      = "createdAt"
+	SortByLastUsed       = "lastUsed"
+	SortByCardsRemaining = "cardsRemaining"
+)
+
+// DeckQuery describes which decks to list and in what order.
+// Zero values mean the filter is not applied.
+type DeckQuery struct {
+	OwnerID        string
+	CreatedBy      string
+	GameID         string
+	Shuffle        *bool
+	CreatedAfter   time.Time
+	CreatedBefore  time.Time
+	LastUsedAfter  time.Time
+	LastUsedBefore time.Time
+	Status         string
+
+	SortBy     string
+	Descending bool
+
+	// Limit is the maximum number of decks in the page, Cursor is the
+	// NextCursor returned with the previous page.
+	Limit  int
+	Cursor string
+}
+
+// DeckPage is one page of a deck listing. NextCursor is empty on the last page.
+type DeckPage struct {
+	Decks      []model.Deck `json:"decks"`
+	NextCursor string       `json:"nextCursor"`
+}
+
+// DeckPageV1 is a page of a deck listing as sent by the v1 routes, see model.DeckV1.
+type DeckPageV1 struct {
+	Decks      []model.DeckV1 `json:"decks"`
+	NextCursor string         `json:"nextCursor"`
+}
+
+// V1 returns the page in its v1 shape.
+func (p DeckPage) V1() DeckPageV1 {
+	decks := make([]model.DeckV1, len(p.Decks))
+
+	for i, deck := range p.Decks {
+		decks[i] = model.NewDeckV1(deck)
+	}
+
+	return DeckPageV1{Decks: decks, NextCursor: p.NextCursor}
+}
+
+// Matches reports whether the deck passes every filter of the query.
+func (q DeckQuery) Matches(deck model.Deck) bool {
+	if q.OwnerID != "" && deck.OwnerID != q.OwnerID {
+		return false
+	}
+
+	if q.CreatedBy != "" && deck.CreatedBy != q.CreatedBy {
+		return false
+	}
+
+	if q.GameID != "" && deck.GameID != q.GameID {
+		return false
+	}
+
+	if q.Shuffle != nil && deck.Shuffle != *q.Shuffle {
+		return false
+	}
+
+	if !q.CreatedAfter.IsZero() && !deck.CreatedAt.After(q.CreatedAfter) {
+		return false
+	}
+
+	if !q.CreatedBefore.IsZero() && !deck.CreatedAt.Before(q.CreatedBefore) {
+		return false
+	}
+
+	if !q.LastUsedAfter.IsZero() && !deck.DeckLastUsed.After(q.LastUsedAfter) {
+		return false
+	}
+
+	if !q.LastUsedBefore.IsZero() && (deck.DeckLastUsed.IsZero() || !deck.DeckLastUsed.Before(q.LastUsedBefore)) {
+		return false
+	}
+
+	switch q.Status {
+	case DeckStatusActive:
+		return deck.CardsRemaining > 0
+	case DeckStatusExhausted:
+		return deck.CardsRemaining == 0
+	}
+
+	return true
+}
+
+// Paginate sorts the decks according to the query and cuts the page starting
+// right after the cursor. The decks are expected to be already filtered.
+// Decks with the same sort value are ordered by ID so pages never overlap.
+func Paginate(decks []model.Deck, q DeckQuery) (DeckPage, error) {
+	sortBy := q.SortBy
+	if sortBy == "" {
+		sortBy = SortByCreatedAt
+	}
+
+	less := func(a, b model.Deck) bool {
+		ka, kb := sortKey(a, sortBy), sortKey(b, sortBy)
+		if ka != kb {
+			return (ka < kb) != q.Descending
+		}
+		return a.ID.String() < b.ID.String()
+	}
+
+	sort.Slice(decks, func(i, j int) bool {
+		return less(decks[i], decks[j])
+	})
+
+	start := 0
+
+	if q.Cursor != "" {
+		key, id, err := decodeCursor(q.Cursor)
+
+		if err != nil {
+			return DeckPage{}, err
+		}
+
+		// Skipping every deck up to and including the one the cursor points to
+		start = sort.Search(len(decks), func(i int) bool {
+			k := sortKey(decks[i], sortBy)
+			if k != key {
+				return (k > key) != q.Descending
+			}
+			return decks[i].ID.String() > id
+		})
+	}
+
+	end := len(decks)
+	if q.Limit > 0 && start+q.Limit < end {
+		end = start + q.Limit
+	}
+
+	page := DeckPage{Decks: decks[start:end]}
+
+	if end < len(decks) {
+		last := decks[end-1]
+		page.NextCursor = encodeCursor(sortKey(last, sortBy), last.ID.String())
+	}
+
+	return page, nil
+}
+
+func sortKey(deck model.Deck, sortBy string) int64 {
+	switch sortBy {
+	case SortByLastUsed:
+		if deck.DeckLastUsed.IsZero() {
+			return 0
+		}
+		return deck.DeckLastUsed.UnixNano()
+	case SortByCardsRemaining:
+		return int64(deck.CardsRemaining)
+	default:
+		return deck.CreatedAt.UnixNano()
+	}
+}
+
+// The cursor is the sort value and the ID of the last deck in the page, encoded
+// so clients treat it as opaque.
+func encodeCursor(key int64, id string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("%d|%s", key, id)))
+}
+
+func decodeCursor(cursor string) (int64, string, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+
+	if err != nil {
+		return 0, "", ErrInvalidCursor
+	}
+
+	parts := strings.SplitN(string(raw), "|", 2)
+
+	if len(parts) != 2 {
+		return 0, "", ErrInvalidCursor
+	}
+
+	key, err := strconv.ParseInt(parts[0], 10, 64)
+
+	if err != nil {
+		return 0, "", ErrInvalidCursor
+	}
+
+	return key, parts[1], nil
+}
@@ -0,0 +1,116 @@
+package store
+
+import (
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/varadekd/card-game/model"
+)
+
+// Snapshotter is implemented by the stores keeping the decks in memory. Their content is
+// saved to disk when the application shuts down and restored when it starts again.
+type Snapshotter interface {
+	// Snapshot writes every stored deck to w.
+	Snapshot(w io.Writer) error
+
+	// Restore replaces the stored decks with the ones written by Snapshot.
+	Restore(r io.Reader) error
+}
+
+// snapshot is the content of a snapshot file. It is encoded with gob rather than JSON
+// because fields such as CreatedBy are never sent to clients and have no JSON name.
+type snapshot struct {
+	Decks   []model.Deck
+	Expired map[string]time.Time
+}
+
+func (s *MemoryStore) Snapshot(w io.Writer) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	content := snapshot{Decks: make([]model.Deck, 0, len(s.decks)), Expired: s.expired}
+
+	for _, deck := range s.decks {
+		content.Decks = append(content.Decks, deck)
+	}
+
+	return gob.NewEncoder(w).Encode(content)
+}
+
+func (s *MemoryStore) Restore(r io.Reader) error {
+	var content snapshot
+
+	if err := gob.NewDecoder(r).Decode(&content); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.decks = make(map[string]model.Deck, len(content.Decks))
+	s.expired = map[string]time.Time{}
+
+	for _, deck := range content.Decks {
+		s.decks[deck.ID.String()] = deck
+	}
+
+	for id, expiredAt := range content.Expired {
+		s.expired[id] = expiredAt
+	}
+
+	return nil
+}
+
+// SaveSnapshot writes the snapshot of the store to the file. It is written next to the
+// file first and then renamed, so a crash while saving never leaves a truncated snapshot.
+func SaveSnapshot(s Snapshotter, path string) error {
+	file, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+
+	if err != nil {
+		return fmt.Errorf("unable to create the snapshot file: %w", err)
+	}
+
+	defer os.Remove(file.Name())
+
+	if err := s.Snapshot(file); err != nil {
+		file.Close()
+		return fmt.Errorf("unable to write the snapshot: %w", err)
+	}
+
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("unable to write the snapshot: %w", err)
+	}
+
+	if err := os.Rename(file.Name(), path); err != nil {
+		return fmt.Errorf("unable to replace the snapshot file: %w", err)
+	}
+
+	return nil
+}
+
+// LoadSnapshot restores the store from the file written by SaveSnapshot. A missing file
+// is not an error, the store is left empty.
+func LoadSnapshot(s Snapshotter, path string) error {
+	file, err := os.Open(path)
+
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("unable to open the snapshot file: %w", err)
+	}
+
+	defer file.Close()
+
+	if err := s.Restore(file); err != nil {
+		return fmt.Errorf("unable to read the snapshot file %s: %w", path, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,72 @@
+// The store package keeps track of every deck generated by the application.
+// Handlers must go through a DeckStore instead of touching decks directly so
+// that expiry, locking and persistence are handled in one place.
+
+package store
+
+import (
+	"errors"
+
+	"github.com/varadekd/card-game/model"
+)
+
+var (
+	// ErrDeckNotFound is returned when no deck has ever been stored under the ID,
+	// or when an expired deck has outlived its grace period.
+	ErrDeckNotFound = errors.New("deck not found")
+
+	// ErrDeckExpired is returned for decks removed by the expiry policy while
+	// they are still within the grace period.
+	ErrDeckExpired = errors.New("deck expired")
+
+	// ErrDeckExists is returned when a deck is created with an ID already in use.
+	ErrDeckExists = errors.New("deck already exists")
+
+	// ErrQuotaExceeded is returned when a deck is created by a client already keeping the
+	// maximum number of active decks.
+	ErrQuotaExceeded = errors.New("active deck quota exceeded")
+
+	// ErrInvalidCursor is returned when a list cursor cannot be decoded.
+	ErrInvalidCursor = errors.New("invalid cursor")
+)
+
+// DeckStore is the storage contract used by the controllers.
+type DeckStore interface {
+	// Create stores a newly generated deck.
+	Create(deck model.Deck) error
+
+	// CreateWithLimit stores a newly generated deck unless the client that created it,
+	// deck.CreatedBy, already keeps max active decks, in which case ErrQuotaExceeded is returned.
+	// The decks are counted while holding the store lock so concurrent creations can not exceed
+	// the limit. A max of zero or less disables the limit.
+	CreateWithLimit(deck model.Deck, max int) error
+
+	// Get returns the deck stored under the ID.
+	Get(id string) (model.Deck, error)
+
+	// Update runs fn against the stored deck while holding the store lock, so
+	// read-modify-write sequences such as drawing cards are atomic. If fn
+	// returns an error the deck is left untouched, otherwise its version is increased.
+	Update(id string, fn func(deck *model.Deck) error) (model.Deck, error)
+
+	// AppendAudit runs fn against the stored deck while holding the store lock and appends the
+	// event it returns to the audit trail. Unlike Update the cards and the version of the deck are
+	// left untouched, so read-only operations such as peeking do not change its ETag.
+	AppendAudit(id string, fn func(deck model.Deck) (model.DeckEvent, error)) (model.Deck, error)
+
+	// Delete removes the deck stored under the ID.
+	Delete(id string) error
+
+	// DeleteByGame removes every deck generated for the game and returns the removed decks.
+	// When ownerID is not empty only the decks owned by it are removed. Expired decks are not returned.
+	DeleteByGame(gameID, ownerID string) ([]model.Deck, error)
+
+	// List returns one page of the decks matching the query.
+	List(query DeckQuery) (DeckPage, error)
+
+	// Count returns how many active decks match the filters of the query.
+	Count(query DeckQuery) (int, error)
+
+	// Sweep removes every deck that has expired and returns how many were removed.
+	Sweep() int
+}
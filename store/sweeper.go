@@ -0,0 +1,89 @@
+package store
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// DefaultSweepInterval is how often the sweeper looks for expired decks.
+const DefaultSweepInterval = time.Minute
+
+// Sweeper periodically removes expired decks from a store in the background.
+type Sweeper struct {
+	store    DeckStore
+	interval time.Duration
+
+	mu      sync.Mutex
+	stop    chan struct{}
+	done    chan struct{}
+	running bool
+}
+
+// NewSweeper returns a sweeper for the store, it does nothing until Start is called.
+func NewSweeper(store DeckStore, interval time.Duration) *Sweeper {
+	if interval <= 0 {
+		interval = DefaultSweepInterval
+	}
+
+	return &Sweeper{
+		store:    store,
+		interval: interval,
+	}
+}
+
+// Start launches the background goroutine. Calling Start on a running sweeper does nothing.
+func (s *Sweeper) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.running {
+		return
+	}
+
+	s.stop = make(chan struct{})
+	s.done = make(chan struct{})
+	s.running = true
+
+	go s.run(s.stop, s.done)
+}
+
+// Stop signals the goroutine to finish and waits until the sweep in progress, if any, is over.
+func (s *Sweeper) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.running {
+		return
+	}
+
+	close(s.stop)
+	<-s.done
+	s.running = false
+}
+
+// Running reports whether the background goroutine is active.
+func (s *Sweeper) Running() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.running
+}
+
+func (s *Sweeper) run(stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if removed := s.store.Sweep(); removed > 0 {
+				slog.Info("Sweeper removed expired decks.", "removed", removed)
+			}
+		}
+	}
+}
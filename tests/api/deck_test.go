@@ -1,16 +1,29 @@
 package api_test
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
+	"github.com/varadekd/card-game/auth"
 	"github.com/varadekd/card-game/config"
+	"github.com/varadekd/card-game/controller"
 	"github.com/varadekd/card-game/helper"
+	"github.com/varadekd/card-game/middleware"
+	"github.com/varadekd/card-game/model"
+	"github.com/varadekd/card-game/store"
 	"github.com/varadekd/card-game/util"
 )
 
@@ -107,6 +120,13 @@ func TestGenerateDeck(t *testing.T) {
 		// Verifying error message
 		msg := "User shared and invalid payload"
 		assert.Equal(t, msg, res.Error, fmt.Sprintf("We expected error message to be %s but found %s", msg, res.Error))
+
+		// Verifying error code and the rejected field
+		assert.Equal(t, helper.ErrInvalidPayload.Code, res.ErrorCode, fmt.Sprintf("We expected error code to be %s but found %s", helper.ErrInvalidPayload.Code, res.ErrorCode))
+
+		if assert.Len(t, res.ErrorDetails, 1, "We expected the rejected field in the error details") {
+			assert.Equal(t, "shuffle", res.ErrorDetails[0].Field, fmt.Sprintf("We expected the field shuffle to be rejected but found %s", res.ErrorDetails[0].Field))
+		}
 	})
 }
 
@@ -286,3 +306,896 @@ func TestDrawCardsFromDeck(t *testing.T) {
 		assert.Equal(t, msg, res.Error, fmt.Sprintf("We expected error message to be %s but found %s", msg, res.Error))
 	})
 }
+
+func TestPayloadValidation(t *testing.T) {
+	t.Run("Generating deck with an invalid card code", func(t *testing.T) {
+		payloadString, _ := json.Marshal(map[string]any{"cards": []string{"AS", "1X", "KH"}})
+		res, code := util.RequestAndDecodeResponse("POST", "/deck/new", payloadString, t, router)
+
+		// Verifying api status it should be 400
+		assert.Equal(t, http.StatusBadRequest, code, fmt.Sprintf("We expected http status %d but got %d", http.StatusBadRequest, code))
+
+		// Verifying error code and the rejected card
+		assert.Equal(t, helper.ErrInvalidCardCode.Code, res.ErrorCode, fmt.Sprintf("We expected error code to be %s but found %s", helper.ErrInvalidCardCode.Code, res.ErrorCode))
+
+		if assert.Len(t, res.ErrorDetails, 1, "We expected the rejected card in the error details") {
+			assert.Equal(t, "cards[1]", res.ErrorDetails[0].Field, fmt.Sprintf("We expected the field cards[1] to be rejected but found %s", res.ErrorDetails[0].Field))
+		}
+	})
+
+	t.Run("Generating deck with duplicate cards", func(t *testing.T) {
+		payloadString, _ := json.Marshal(map[string]any{"cards": []string{"AS", "AS"}})
+		res, code := util.RequestAndDecodeResponse("POST", "/deck/new", payloadString, t, router)
+
+		// Verifying api status it should be 400
+		assert.Equal(t, http.StatusBadRequest, code, fmt.Sprintf("We expected http status %d but got %d", http.StatusBadRequest, code))
+
+		// Verifying error code and the rejected field
+		assert.Equal(t, helper.ErrInvalidPayload.Code, res.ErrorCode, fmt.Sprintf("We expected error code to be %s but found %s", helper.ErrInvalidPayload.Code, res.ErrorCode))
+
+		if assert.Len(t, res.ErrorDetails, 1, "We expected the rejected field in the error details") {
+			assert.Equal(t, "cards", res.ErrorDetails[0].Field, fmt.Sprintf("We expected the field cards to be rejected but found %s", res.ErrorDetails[0].Field))
+		}
+	})
+
+	for _, cardsToBeDrawn := range []int{0, -1} {
+		t.Run(fmt.Sprintf("Drawing %d cards", cardsToBeDrawn), func(t *testing.T) {
+			payloadString, _ := json.Marshal(map[string]int{"cardsToBeDrawn": cardsToBeDrawn})
+			api := fmt.Sprintf("/deck/%s/draw-cards", deckID)
+			res, code := util.RequestAndDecodeResponse("PUT", api, payloadString, t, router)
+
+			// Verifying api status it should be 400
+			assert.Equal(t, http.StatusBadRequest, code, fmt.Sprintf("We expected http status %d but got %d", http.StatusBadRequest, code))
+
+			if assert.Len(t, res.ErrorDetails, 1, "We expected the rejected field in the error details") {
+				assert.Equal(t, "cardsToBeDrawn", res.ErrorDetails[0].Field, fmt.Sprintf("We expected the field cardsToBeDrawn to be rejected but found %s", res.ErrorDetails[0].Field))
+			}
+		})
+	}
+
+	t.Run("Listing decks with every filter invalid", func(t *testing.T) {
+		res, code := util.RequestAndDecodeResponse("GET", "/deck?status=lost&sort=name&limit=500", nil, t, router)
+
+		// Verifying api status it should be 400
+		assert.Equal(t, http.StatusBadRequest, code, fmt.Sprintf("We expected http status %d but got %d", http.StatusBadRequest, code))
+
+		// Verifying every invalid field is reported at once
+		assert.Equal(t, helper.ErrInvalidQuery.Code, res.ErrorCode, fmt.Sprintf("We expected error code to be %s but found %s", helper.ErrInvalidQuery.Code, res.ErrorCode))
+		assert.Len(t, res.ErrorDetails, 3, "We expected status, sort and limit in the error details")
+	})
+}
+
+func TestExpiredDeck(t *testing.T) {
+	// Swapping the store with one we can control the clock for, restoring it once done
+	now := time.Now()
+	expiringStore := store.NewMemoryStore(store.ExpiryPolicy{IdleTTL: time.Hour, GracePeriod: time.Hour})
+	expiringStore.Now = func() time.Time { return now }
+
+	defaultStore := controller.Store()
+	controller.UseStore(expiringStore)
+	defer controller.UseStore(defaultStore)
+
+	payloadString, _ := json.Marshal(map[string]bool{"shuffle": false})
+	res, _ := util.RequestAndDecodeResponse("POST", "/deck/new", payloadString, t, router)
+
+	if res.Data == nil {
+		t.Fatalf("Test execution failed because the deck was not generated. Err: %s", res.Error)
+	}
+
+	api := fmt.Sprintf("/deck/%s", res.Data.(map[string]interface{})["_id"].(string))
+
+	t.Run("Fetching deck after the idle TTL", func(t *testing.T) {
+		now = now.Add(time.Hour + time.Minute)
+		res, code := util.RequestAndDecodeResponse("GET", api, nil, t, router)
+
+		// Verifying api status it should be 410
+		assert.Equal(t, http.StatusGone, code, fmt.Sprintf("We expected http status %d but got %d", http.StatusGone, code))
+
+		// Verifying error message
+		msg := "DeckID has expired"
+		assert.Equal(t, msg, res.Error, fmt.Sprintf("We expected error message to be %s but found %s", msg, res.Error))
+		assert.Equal(t, helper.ErrDeckExpired.Code, res.ErrorCode, fmt.Sprintf("We expected error code to be %s but found %s", helper.ErrDeckExpired.Code, res.ErrorCode))
+	})
+
+	t.Run("Fetching deck after the grace period", func(t *testing.T) {
+		now = now.Add(time.Hour)
+		_, code := util.RequestAndDecodeResponse("GET", api, nil, t, router)
+
+		// Verifying api status it should be 404
+		assert.Equal(t, http.StatusNotFound, code, fmt.Sprintf("We expected http status %d but got %d", http.StatusNotFound, code))
+	})
+}
+
+func TestListAndDeleteDecks(t *testing.T) {
+	// Using an empty store so the listing only contains the decks generated here
+	defaultStore := controller.Store()
+	controller.UseStore(store.NewMemoryStore(store.DefaultExpiryPolicy))
+	defer controller.UseStore(defaultStore)
+
+	ids := []string{}
+
+	for _, gameID := range []string{"poker", "poker", "bridge"} {
+		payloadString, _ := json.Marshal(map[string]any{"gameID": gameID, "shuffle": true})
+		res, _ := util.RequestAndDecodeResponse("POST", "/deck/new", payloadString, t, router)
+
+		if res.Data == nil {
+			t.Fatalf("Test execution failed because the deck was not generated. Err: %s", res.Error)
+		}
+		ids = append(ids, res.Data.(map[string]interface{})["_id"].(string))
+	}
+
+	t.Run("Listing decks of a game", func(t *testing.T) {
+		res, code := util.RequestAndDecodeResponse("GET", "/deck?gameID=poker&sort=-createdAt&limit=1", nil, t, router)
+
+		// Verifying api status it should be 200
+		assert.Equal(t, http.StatusOK, code, fmt.Sprintf("We expected http status %d but got %d", http.StatusOK, code))
+
+		page := res.Data.(map[string]interface{})
+		decks := page["decks"].([]interface{})
+
+		// Verifying that only one deck is returned with a cursor for the next page
+		assert.Len(t, decks, 1, "We expected a single deck in the page")
+		assert.NotEmpty(t, page["nextCursor"], "We expected a cursor for the next page")
+
+		res, _ = util.RequestAndDecodeResponse("GET", fmt.Sprintf("/deck?gameID=poker&sort=-createdAt&limit=1&cursor=%s", page["nextCursor"]), nil, t, router)
+		page = res.Data.(map[string]interface{})
+
+		assert.Len(t, page["decks"].([]interface{}), 1, "We expected a single deck in the second page")
+		assert.Empty(t, page["nextCursor"], "We expected no cursor on the last page")
+	})
+
+	t.Run("Listing decks with invalid sort", func(t *testing.T) {
+		res, code := util.RequestAndDecodeResponse("GET", "/deck?sort=suit", nil, t, router)
+
+		// Verifying api status it should be 400
+		assert.Equal(t, http.StatusBadRequest, code, fmt.Sprintf("We expected http status %d but got %d", http.StatusBadRequest, code))
+		assert.Equal(t, false, res.Success, fmt.Sprintf("We expected the 'success' field to be set to false but found it as %t", res.Success))
+	})
+
+	t.Run("Deleting a single deck", func(t *testing.T) {
+		api := fmt.Sprintf("/deck/%s", ids[2])
+		_, code := util.RequestAndDecodeResponse("DELETE", api, nil, t, router)
+
+		// Verifying api status it should be 200
+		assert.Equal(t, http.StatusOK, code, fmt.Sprintf("We expected http status %d but got %d", http.StatusOK, code))
+
+		_, code = util.RequestAndDecodeResponse("GET", api, nil, t, router)
+		assert.Equal(t, http.StatusNotFound, code, fmt.Sprintf("We expected http status %d but got %d", http.StatusNotFound, code))
+	})
+
+	t.Run("Deleting decks by game", func(t *testing.T) {
+		res, code := util.RequestAndDecodeResponse("DELETE", "/deck?gameID=poker", nil, t, router)
+
+		// Verifying api status it should be 200
+		assert.Equal(t, http.StatusOK, code, fmt.Sprintf("We expected http status %d but got %d", http.StatusOK, code))
+
+		deleted := res.Data.(map[string]interface{})["deleted"].(float64)
+		assert.Equal(t, float64(2), deleted, fmt.Sprintf("We expected 2 decks to be deleted but got %v", deleted))
+	})
+
+	t.Run("Deleting decks without gameID", func(t *testing.T) {
+		res, code := util.RequestAndDecodeResponse("DELETE", "/deck", nil, t, router)
+
+		// Verifying api status it should be 400
+		assert.Equal(t, http.StatusBadRequest, code, fmt.Sprintf("We expected http status %d but got %d", http.StatusBadRequest, code))
+
+		msg := "GameID is missing"
+		assert.Equal(t, msg, res.Error, fmt.Sprintf("We expected error message to be %s but found %s", msg, res.Error))
+	})
+}
+
+func TestCloneDeck(t *testing.T) {
+	payloadString, _ := json.Marshal(map[string]any{"gameID": "tournament", "shuffle": true})
+	res, _ := util.RequestAndDecodeResponse("POST", "/deck/new", payloadString, t, router)
+
+	if res.Data == nil {
+		t.Fatalf("Test execution failed because the deck was not generated. Err: %s", res.Error)
+	}
+
+	source := res.Data.(map[string]interface{})
+	sourceID := source["_id"].(string)
+
+	// Drawing a few cards so the source deck state differs from its generated order
+	payloadString, _ = json.Marshal(map[string]int{"cardsToBeDrawn": 5})
+	util.RequestAndDecodeResponse("PUT", fmt.Sprintf("/deck/%s/draw-cards", sourceID), payloadString, t, router)
+
+	api := fmt.Sprintf("/deck/%s/clone", sourceID)
+
+	t.Run("Cloning deck from the generated order", func(t *testing.T) {
+		res, code := util.RequestAndDecodeResponse("POST", api, nil, t, router)
+
+		// Verifying api status it should be 201
+		assert.Equal(t, http.StatusCreated, code, fmt.Sprintf("We expected http status %d but got %d", http.StatusCreated, code))
+
+		clone := res.Data.(map[string]interface{})
+
+		// Verifying the clone is a new deck linked to the source with the identical order
+		assert.NotEqual(t, sourceID, clone["_id"], "We expected the clone to have a new deckID")
+		assert.Equal(t, sourceID, clone["sourceDeckID"], "We expected the clone to be linked to the source deck")
+		assert.Equal(t, source["generatedDeck"], clone["generatedDeck"], "We expected the clone to have the same generated order")
+		assert.Equal(t, float64(52), clone["cardRemaining"], "We expected the clone to start with the full deck")
+	})
+
+	t.Run("Cloning deck with the current state", func(t *testing.T) {
+		payloadString, _ := json.Marshal(map[string]any{"copyState": true, "gameID": "table-2"})
+		res, code := util.RequestAndDecodeResponse("POST", api, payloadString, t, router)
+
+		// Verifying api status it should be 201
+		assert.Equal(t, http.StatusCreated, code, fmt.Sprintf("We expected http status %d but got %d", http.StatusCreated, code))
+
+		clone := res.Data.(map[string]interface{})
+
+		assert.Equal(t, float64(47), clone["cardRemaining"], "We expected the clone to keep the remaining cards of the source")
+		assert.Equal(t, "table-2", clone["gameID"], "We expected the clone to use the gameID from the payload")
+	})
+
+	t.Run("Cloning deck not part of data", func(t *testing.T) {
+		_, code := util.RequestAndDecodeResponse("POST", fmt.Sprintf("/deck/%s/clone", uuid.New().String()), nil, t, router)
+
+		// Verifying api status it should be 404
+		assert.Equal(t, http.StatusNotFound, code, fmt.Sprintf("We expected http status %d but got %d", http.StatusNotFound, code))
+	})
+}
+
+func TestResetDeck(t *testing.T) {
+	payloadString, _ := json.Marshal(map[string]any{"shuffle": false})
+	res, _ := util.RequestAndDecodeResponse("POST", "/deck/new", payloadString, t, router)
+
+	if res.Data == nil {
+		t.Fatalf("Test execution failed because the deck was not generated. Err: %s", res.Error)
+	}
+
+	resetDeckID := res.Data.(map[string]interface{})["_id"].(string)
+	api := fmt.Sprintf("/deck/%s/reset", resetDeckID)
+
+	payloadString, _ = json.Marshal(map[string]any{"cardsToBeDrawn": 52, "playerID": "p1"})
+	util.RequestAndDecodeResponse("PUT", fmt.Sprintf("/deck/%s/draw-cards", resetDeckID), payloadString, t, router)
+
+	if _, err := controller.DiscardCards(controller.Caller{}, resetDeckID, model.DiscardCardsPayload{PlayerID: "p1", Cards: []string{"AS"}, Pile: "discard"}, ""); err != nil {
+		t.Fatalf("Test execution failed because the card was not discarded. Err: %s", err.Error())
+	}
+
+	t.Run("Resetting an exhausted deck", func(t *testing.T) {
+		res, code := util.RequestAndDecodeResponse("POST", api, nil, t, router)
+
+		// Verifying api status it should be 200
+		assert.Equal(t, http.StatusOK, code, fmt.Sprintf("We expected http status %d but got %d", http.StatusOK, code))
+
+		deck := res.Data.(map[string]interface{})
+
+		// Verifying the cards are back in play, the round is bumped and the reset is recorded
+		assert.Equal(t, float64(52), deck["cardRemaining"], "We expected all the cards to be back in play")
+		assert.Equal(t, deck["generatedDeck"], deck["playingCards"], "We expected the cards in the generated order")
+		assert.Equal(t, float64(1), deck["round"], "We expected the round to be bumped")
+		assert.Len(t, deck["auditTrail"], 1, "We expected the reset to be recorded in the audit trail")
+		assert.Nil(t, deck["hands"], "We expected the hands to be emptied")
+		assert.Nil(t, deck["piles"], "We expected the piles to be emptied")
+	})
+
+	t.Run("Resetting a deck with shuffle", func(t *testing.T) {
+		payloadString, _ := json.Marshal(map[string]bool{"shuffle": true})
+		res, code := util.RequestAndDecodeResponse("POST", api, payloadString, t, router)
+
+		// Verifying api status it should be 200
+		assert.Equal(t, http.StatusOK, code, fmt.Sprintf("We expected http status %d but got %d", http.StatusOK, code))
+
+		deck := res.Data.(map[string]interface{})
+
+		assert.Equal(t, float64(2), deck["round"], "We expected the round to be bumped")
+		assert.ElementsMatch(t, deck["generatedDeck"], deck["playingCards"], "We expected the same cards back in play")
+	})
+
+	t.Run("Resetting deck using invalid payload", func(t *testing.T) {
+		payloadString, _ := json.Marshal(map[string]string{"shuffle": "yes"})
+		_, code := util.RequestAndDecodeResponse("POST", api, payloadString, t, router)
+
+		// Verifying api status it should be 400
+		assert.Equal(t, http.StatusBadRequest, code, fmt.Sprintf("We expected http status %d but got %d", http.StatusBadRequest, code))
+	})
+}
+
+func TestDiscardCards(t *testing.T) {
+	payloadString, _ := json.Marshal(map[string]any{"shuffle": false, "cards": []string{"AS", "KH", "10D"}})
+	res, _ := util.RequestAndDecodeResponse("POST", "/v2/deck/new", payloadString, t, router)
+
+	if res.Data == nil {
+		t.Fatalf("Test execution failed because the deck was not generated. Err: %s", res.Error)
+	}
+
+	discardDeckID := res.Data.(map[string]interface{})["id"].(string)
+	api := fmt.Sprintf("/v2/deck/%s/discard-cards", discardDeckID)
+
+	payloadString, _ = json.Marshal(map[string]any{"cardsToBeDrawn": 2, "playerID": "p1"})
+	res, _ = util.RequestAndDecodeResponse("PUT", fmt.Sprintf("/v2/deck/%s/draw-cards", discardDeckID), payloadString, t, router)
+
+	if res.Data == nil {
+		t.Fatalf("Test execution failed because the cards were not drawn. Err: %s", res.Error)
+	}
+
+	drawn := res.Data.([]interface{})
+	discarded := drawn[0].(map[string]interface{})["code"].(string)
+
+	t.Run("Discarding cards to a pile", func(t *testing.T) {
+		payloadString, _ := json.Marshal(map[string]any{"playerID": "p1", "cards": []string{discarded}, "pile": "discard"})
+		res, code := util.RequestAndDecodeResponse("PUT", api, payloadString, t, router)
+
+		// Verifying api status it should be 200
+		assert.Equal(t, http.StatusOK, code, fmt.Sprintf("We expected http status %d but got %d", http.StatusOK, code))
+
+		deck := res.Data.(map[string]interface{})
+
+		// Verifying the card moved from the hand to the pile
+		assert.Equal(t, []interface{}{drawn[1]}, deck["hands"].(map[string]interface{})["p1"], "We expected one card left in the hand")
+		assert.Equal(t, []interface{}{drawn[0]}, deck["piles"].(map[string]interface{})["discard"], fmt.Sprintf("We expected %s in the discard pile", discarded))
+	})
+
+	t.Run("Discarding a card the player does not hold", func(t *testing.T) {
+		payloadString, _ := json.Marshal(map[string]any{"playerID": "p1", "cards": []string{discarded}, "pile": "discard"})
+		res, code := util.RequestAndDecodeResponse("PUT", api, payloadString, t, router)
+
+		// Verifying api status it should be 409
+		assert.Equal(t, http.StatusConflict, code, fmt.Sprintf("We expected http status %d but got %d", http.StatusConflict, code))
+		assert.Equal(t, helper.ErrCardNotInHand.Code, res.ErrorCode, fmt.Sprintf("We expected the error code %s but got %s", helper.ErrCardNotInHand.Code, res.ErrorCode))
+	})
+
+	t.Run("Discarding from a deck modified since it was read", func(t *testing.T) {
+		payloadString, _ := json.Marshal(map[string]any{"playerID": "p1", "cards": []string{drawn[1].(map[string]interface{})["code"].(string)}, "pile": "discard"})
+		res, code := util.RequestWithHeadersAndDecodeResponse("PUT", api, payloadString, map[string]string{"If-Match": `"1"`}, t, router)
+
+		// Verifying api status it should be 412
+		assert.Equal(t, http.StatusPreconditionFailed, code, fmt.Sprintf("We expected http status %d but got %d", http.StatusPreconditionFailed, code))
+		assert.Equal(t, helper.ErrDeckModified.Code, res.ErrorCode, fmt.Sprintf("We expected the error code %s but got %s", helper.ErrDeckModified.Code, res.ErrorCode))
+	})
+
+	t.Run("Discarding without a pile", func(t *testing.T) {
+		payloadString, _ := json.Marshal(map[string]any{"playerID": "p1", "cards": []string{discarded}})
+		_, code := util.RequestAndDecodeResponse("PUT", api, payloadString, t, router)
+
+		// Verifying api status it should be 400
+		assert.Equal(t, http.StatusBadRequest, code, fmt.Sprintf("We expected http status %d but got %d", http.StatusBadRequest, code))
+	})
+}
+
+func TestPeekDeck(t *testing.T) {
+	t.Setenv("DEALER_KEY", "dealer-secret")
+	dealerRouter := config.SetupRouter()
+
+	payloadString, _ := json.Marshal(map[string]any{"shuffle": false, "cards": []string{"AS", "2S", "3S", "4S"}})
+	res, _ := util.RequestAndDecodeResponse("POST", "/deck/new", payloadString, t, dealerRouter)
+
+	if res.Data == nil {
+		t.Fatalf("Test execution failed because the deck was not generated. Err: %s", res.Error)
+	}
+
+	peekDeckID := res.Data.(map[string]interface{})["_id"].(string)
+
+	peek := func(query, dealerKey string) (helper.ResponseJSON, int) {
+		api := fmt.Sprintf("/deck/%s/peek%s", peekDeckID, query)
+		return util.RequestWithHeadersAndDecodeResponse("GET", api, nil, map[string]string{middleware.DealerKeyHeader: dealerKey}, t, dealerRouter)
+	}
+
+	t.Run("Peeking without the dealer key", func(t *testing.T) {
+		_, code := peek("?count=2", "wrong-key")
+
+		// Verifying api status it should be 403
+		assert.Equal(t, http.StatusForbidden, code, fmt.Sprintf("We expected http status %d but got %d", http.StatusForbidden, code))
+	})
+
+	t.Run("Peeking at the top and bottom of the deck", func(t *testing.T) {
+		res, code := peek("?count=2", "dealer-secret")
+
+		// Verifying api status it should be 200
+		assert.Equal(t, http.StatusOK, code, fmt.Sprintf("We expected http status %d but got %d", http.StatusOK, code))
+
+		cards := res.Data.([]interface{})
+		assert.Len(t, cards, 2, "We expected two cards")
+		assert.Equal(t, "AS", cards[0].(map[string]interface{})["code:"], "We expected the top card to be AS")
+
+		res, _ = peek("?count=1&from=bottom", "dealer-secret")
+		cards = res.Data.([]interface{})
+		assert.Equal(t, "4S", cards[0].(map[string]interface{})["code:"], "We expected the bottom card to be 4S")
+	})
+
+	t.Run("Peeking does not draw the cards and is audited", func(t *testing.T) {
+		res, _ := util.RequestAndDecodeResponse("GET", fmt.Sprintf("/deck/%s", peekDeckID), nil, t, dealerRouter)
+		deck := res.Data.(map[string]interface{})
+
+		assert.Equal(t, float64(4), deck["cardRemaining"], "We expected no card to be drawn")
+		assert.Len(t, deck["auditTrail"], 2, "We expected both peeks to be recorded")
+	})
+
+	t.Run("Drawing with If-Match after a peek", func(t *testing.T) {
+		res, _ := util.RequestAndDecodeResponse("GET", fmt.Sprintf("/deck/%s", peekDeckID), nil, t, dealerRouter)
+		etag := fmt.Sprintf(`"%.0f"`, res.Data.(map[string]interface{})["version"].(float64))
+
+		peek("?count=1", "dealer-secret")
+
+		payloadString, _ := json.Marshal(map[string]int{"cardsToBeDrawn": 1})
+		_, code := util.RequestWithHeadersAndDecodeResponse("PUT", fmt.Sprintf("/deck/%s/draw-cards", peekDeckID), payloadString, map[string]string{"If-Match": etag}, t, dealerRouter)
+
+		// Verifying the peek left the version of the deck untouched
+		assert.Equal(t, http.StatusOK, code, fmt.Sprintf("We expected http status %d but got %d", http.StatusOK, code))
+	})
+
+	t.Run("Peeking at more cards than remaining", func(t *testing.T) {
+		_, code := peek("?count=5", "dealer-secret")
+
+		// Verifying api status it should be 409
+		assert.Equal(t, http.StatusConflict, code, fmt.Sprintf("We expected http status %d but got %d", http.StatusConflict, code))
+	})
+
+	t.Run("Peeking with invalid position", func(t *testing.T) {
+		_, code := peek("?from=middle", "dealer-secret")
+
+		// Verifying api status it should be 400
+		assert.Equal(t, http.StatusBadRequest, code, fmt.Sprintf("We expected http status %d but got %d", http.StatusBadRequest, code))
+	})
+}
+
+func TestDeckOwnership(t *testing.T) {
+	keysFile := filepath.Join(t.TempDir(), "keys.json")
+	keys, _ := auth.LoadKeyStore(keysFile)
+	_, aliceKey, _ := keys.Mint("alice")
+	_, bobKey, _ := keys.Mint("bob")
+
+	// Setting up a router with API key authentication enabled
+	t.Setenv("API_KEYS_FILE", keysFile)
+	authRouter := config.SetupRouter()
+
+	alice := map[string]string{middleware.APIKeyHeader: aliceKey}
+	bob := map[string]string{middleware.APIKeyHeader: bobKey}
+
+	payloadString, _ := json.Marshal(map[string]any{"gameID": "ownership", "shuffle": true})
+	res, _ := util.RequestWithHeadersAndDecodeResponse("POST", "/deck/new", payloadString, alice, t, authRouter)
+
+	if res.Data == nil {
+		t.Fatalf("Test execution failed because the deck was not generated. Err: %s", res.Error)
+	}
+
+	api := fmt.Sprintf("/deck/%s", res.Data.(map[string]interface{})["_id"].(string))
+
+	t.Run("Request without API key", func(t *testing.T) {
+		res, code := util.RequestAndDecodeResponse("GET", api, nil, t, authRouter)
+
+		// Verifying api status it should be 401
+		assert.Equal(t, http.StatusUnauthorized, code, fmt.Sprintf("We expected http status %d but got %d", http.StatusUnauthorized, code))
+
+		msg := "API key is missing"
+		assert.Equal(t, msg, res.Error, fmt.Sprintf("We expected error message to be %s but found %s", msg, res.Error))
+	})
+
+	t.Run("Owner opening the deck", func(t *testing.T) {
+		_, code := util.RequestWithHeadersAndDecodeResponse("GET", api, nil, alice, t, authRouter)
+
+		// Verifying api status it should be 200
+		assert.Equal(t, http.StatusOK, code, fmt.Sprintf("We expected http status %d but got %d", http.StatusOK, code))
+	})
+
+	t.Run("Another key using the deck", func(t *testing.T) {
+		res, code := util.RequestWithHeadersAndDecodeResponse("GET", api, nil, bob, t, authRouter)
+
+		// Verifying api status it should be 403
+		assert.Equal(t, http.StatusForbidden, code, fmt.Sprintf("We expected http status %d but got %d", http.StatusForbidden, code))
+
+		msg := "You are not allowed to access this deck"
+		assert.Equal(t, msg, res.Error, fmt.Sprintf("We expected error message to be %s but found %s", msg, res.Error))
+
+		payloadString, _ := json.Marshal(map[string]int{"cardsToBeDrawn": 1})
+		_, code = util.RequestWithHeadersAndDecodeResponse("PUT", api+"/draw-cards", payloadString, bob, t, authRouter)
+		assert.Equal(t, http.StatusForbidden, code, fmt.Sprintf("We expected http status %d but got %d", http.StatusForbidden, code))
+	})
+
+	t.Run("Listing only returns the decks of the caller", func(t *testing.T) {
+		res, _ := util.RequestWithHeadersAndDecodeResponse("GET", "/deck?gameID=ownership", nil, bob, t, authRouter)
+		assert.Len(t, res.Data.(map[string]interface{})["decks"], 0, "We expected bob to see no deck")
+
+		res, _ = util.RequestWithHeadersAndDecodeResponse("GET", "/deck?gameID=ownership", nil, alice, t, authRouter)
+		assert.Len(t, res.Data.(map[string]interface{})["decks"], 1, "We expected alice to see the deck created with that key")
+	})
+}
+
+func TestPlayerTokens(t *testing.T) {
+	dir := t.TempDir()
+	keysFile := filepath.Join(dir, "keys.json")
+	signingKeyFile := filepath.Join(dir, "signing.key")
+	os.WriteFile(signingKeyFile, []byte(strings.Repeat("k", 32)), 0600)
+
+	keys, _ := auth.LoadKeyStore(keysFile)
+	_, gameServerKey, _ := keys.Mint("game-server")
+
+	// Setting up a router with player tokens enabled
+	t.Setenv("API_KEYS_FILE", keysFile)
+	t.Setenv("JWT_SIGNING_KEY_FILE", signingKeyFile)
+	tokenRouter := config.SetupRouter()
+
+	gameServer := map[string]string{middleware.APIKeyHeader: gameServerKey}
+
+	mint := func(playerID, gameID, role string) map[string]string {
+		payloadString, _ := json.Marshal(map[string]string{"playerID": playerID, "gameID": gameID, "role": role})
+		res, code := util.RequestWithHeadersAndDecodeResponse("POST", "/token", payloadString, gameServer, t, tokenRouter)
+
+		if code != http.StatusCreated {
+			t.Fatalf("Test execution failed because the token was not issued. Err: %s", res.Error)
+		}
+		return map[string]string{"Authorization": "Bearer " + res.Data.(map[string]interface{})["token"].(string)}
+	}
+
+	dealer := mint("dealer-1", "table-1", auth.RoleDealer)
+	player := mint("player-1", "table-1", auth.RolePlayer)
+	spectator := mint("spectator-1", "table-1", auth.RoleSpectator)
+	otherTable := mint("player-2", "table-2", auth.RolePlayer)
+
+	payloadString, _ := json.Marshal(map[string]bool{"shuffle": true})
+	res, code := util.RequestWithHeadersAndDecodeResponse("POST", "/deck/new", payloadString, dealer, t, tokenRouter)
+
+	if res.Data == nil {
+		t.Fatalf("Test execution failed because the deck was not generated. Err: %s", res.Error)
+	}
+
+	deck := res.Data.(map[string]interface{})
+	api := fmt.Sprintf("/deck/%s", deck["_id"].(string))
+
+	t.Run("Dealer creating a deck for the token game", func(t *testing.T) {
+		// Verifying api status it should be 201
+		assert.Equal(t, http.StatusCreated, code, fmt.Sprintf("We expected http status %d but got %d", http.StatusCreated, code))
+		assert.Equal(t, "table-1", deck["gameID"], "We expected the deck to belong to the game of the token")
+	})
+
+	t.Run("Player creating a shuffled deck", func(t *testing.T) {
+		res, code := util.RequestWithHeadersAndDecodeResponse("POST", "/deck/new", payloadString, player, t, tokenRouter)
+
+		// Verifying api status it should be 403
+		assert.Equal(t, http.StatusForbidden, code, fmt.Sprintf("We expected http status %d but got %d", http.StatusForbidden, code))
+		assert.Equal(t, helper.ErrDealerOnly.Code, res.ErrorCode, fmt.Sprintf("We expected the error code %s but got %s", helper.ErrDealerOnly.Code, res.ErrorCode))
+	})
+
+	t.Run("Player drawing into their own hand", func(t *testing.T) {
+		payloadString, _ := json.Marshal(map[string]int{"cardsToBeDrawn": 2})
+		_, code := util.RequestWithHeadersAndDecodeResponse("PUT", api+"/draw-cards", payloadString, player, t, tokenRouter)
+
+		// Verifying api status it should be 200
+		assert.Equal(t, http.StatusOK, code, fmt.Sprintf("We expected http status %d but got %d", http.StatusOK, code))
+
+		res, _ := util.RequestWithHeadersAndDecodeResponse("GET", api, nil, spectator, t, tokenRouter)
+		hands := res.Data.(map[string]interface{})["hands"].(map[string]interface{})
+		assert.Len(t, hands["player-1"], 2, "We expected the cards in the hand of the player")
+	})
+
+	t.Run("Player drawing into the hand of another player", func(t *testing.T) {
+		payloadString, _ := json.Marshal(map[string]any{"cardsToBeDrawn": 1, "playerID": "player-3"})
+		res, code := util.RequestWithHeadersAndDecodeResponse("PUT", api+"/draw-cards", payloadString, player, t, tokenRouter)
+
+		// Verifying api status it should be 403
+		assert.Equal(t, http.StatusForbidden, code, fmt.Sprintf("We expected http status %d but got %d", http.StatusForbidden, code))
+
+		msg := "Players can only draw into their own hand"
+		assert.Equal(t, msg, res.Error, fmt.Sprintf("We expected error message to be %s but found %s", msg, res.Error))
+	})
+
+	t.Run("Spectator drawing cards", func(t *testing.T) {
+		payloadString, _ := json.Marshal(map[string]int{"cardsToBeDrawn": 1})
+		res, code := util.RequestWithHeadersAndDecodeResponse("PUT", api+"/draw-cards", payloadString, spectator, t, tokenRouter)
+
+		// Verifying api status it should be 403
+		assert.Equal(t, http.StatusForbidden, code, fmt.Sprintf("We expected http status %d but got %d", http.StatusForbidden, code))
+		assert.Equal(t, helper.ErrHandForbidden.Code, res.ErrorCode, fmt.Sprintf("We expected the error code %s but got %s", helper.ErrHandForbidden.Code, res.ErrorCode))
+	})
+
+	t.Run("Only the dealer resets the deck", func(t *testing.T) {
+		res, code := util.RequestWithHeadersAndDecodeResponse("POST", api+"/reset", nil, player, t, tokenRouter)
+		assert.Equal(t, http.StatusForbidden, code, fmt.Sprintf("We expected http status %d but got %d", http.StatusForbidden, code))
+		assert.Equal(t, helper.ErrDealerOnly.Code, res.ErrorCode, fmt.Sprintf("We expected the error code %s but got %s", helper.ErrDealerOnly.Code, res.ErrorCode))
+
+		_, code = util.RequestWithHeadersAndDecodeResponse("POST", api+"/reset", nil, dealer, t, tokenRouter)
+		assert.Equal(t, http.StatusOK, code, fmt.Sprintf("We expected http status %d but got %d", http.StatusOK, code))
+	})
+
+	t.Run("Players reusing an idempotency key", func(t *testing.T) {
+		other := mint("player-4", "table-1", auth.RolePlayer)
+		payloadString, _ := json.Marshal(map[string]int{"cardsToBeDrawn": 1})
+
+		draw := func(headers map[string]string) []interface{} {
+			withKey := map[string]string{middleware.IdempotencyKeyHeader: "draw-1"}
+			for name, value := range headers {
+				withKey[name] = value
+			}
+
+			res, code := util.RequestWithHeadersAndDecodeResponse("PUT", api+"/draw-cards", payloadString, withKey, t, tokenRouter)
+			assert.Equal(t, http.StatusOK, code, fmt.Sprintf("We expected http status %d but got %d", http.StatusOK, code))
+			cards, _ := res.Data.([]interface{})
+			return cards
+		}
+
+		first := draw(player)
+		second := draw(other)
+
+		// Verifying the second player drew its own card instead of getting the first response
+		assert.NotEqual(t, first, second, "We expected each player to draw a different card")
+
+		res, _ := util.RequestWithHeadersAndDecodeResponse("GET", api, nil, spectator, t, tokenRouter)
+		hands := res.Data.(map[string]interface{})["hands"].(map[string]interface{})
+		assert.Equal(t, first, hands["player-1"], "We expected the card of the first player in its hand")
+		assert.Equal(t, second, hands["player-4"], "We expected the card of the second player in its hand")
+
+		// Verifying the key is still replayed for the same player
+		assert.Equal(t, first, draw(player), "We expected the draw of the first player to be replayed")
+	})
+
+	t.Run("Token of another game opening the deck", func(t *testing.T) {
+		res, code := util.RequestWithHeadersAndDecodeResponse("GET", api, nil, otherTable, t, tokenRouter)
+
+		// Verifying api status it should be 403
+		assert.Equal(t, http.StatusForbidden, code, fmt.Sprintf("We expected http status %d but got %d", http.StatusForbidden, code))
+		assert.Equal(t, helper.ErrDeckForbidden.Code, res.ErrorCode, fmt.Sprintf("We expected the error code %s but got %s", helper.ErrDeckForbidden.Code, res.ErrorCode))
+	})
+
+	t.Run("Player minting a token", func(t *testing.T) {
+		payloadString, _ := json.Marshal(map[string]string{"playerID": "player-1", "gameID": "table-1", "role": auth.RoleDealer})
+		_, code := util.RequestWithHeadersAndDecodeResponse("POST", "/token", payloadString, player, t, tokenRouter)
+
+		// Verifying api status it should be 403
+		assert.Equal(t, http.StatusForbidden, code, fmt.Sprintf("We expected http status %d but got %d", http.StatusForbidden, code))
+	})
+
+	t.Run("Request with a forged token", func(t *testing.T) {
+		res, code := util.RequestWithHeadersAndDecodeResponse("GET", api, nil, map[string]string{"Authorization": "Bearer not.a.token"}, t, tokenRouter)
+
+		// Verifying api status it should be 401
+		assert.Equal(t, http.StatusUnauthorized, code, fmt.Sprintf("We expected http status %d but got %d", http.StatusUnauthorized, code))
+
+		msg := "Token is invalid"
+		assert.Equal(t, msg, res.Error, fmt.Sprintf("We expected error message to be %s but found %s", msg, res.Error))
+	})
+}
+
+func TestRateLimits(t *testing.T) {
+	// Setting up a router with tiny limits and a fresh store
+	t.Setenv("RATE_LIMIT_CREATE_PER_MINUTE", "1")
+	t.Setenv("RATE_LIMIT_CREATE_BURST", "2")
+	limitedRouter := config.SetupRouter()
+
+	defaultStore := controller.Store()
+	controller.UseStore(store.NewMemoryStore(store.DefaultExpiryPolicy))
+	defer controller.UseStore(defaultStore)
+
+	payloadString, _ := json.Marshal(map[string]bool{"shuffle": false})
+
+	t.Run("Creating decks over the rate limit", func(t *testing.T) {
+		for i := 0; i < 2; i++ {
+			_, code := util.RequestAndDecodeResponse("POST", "/deck/new", payloadString, t, limitedRouter)
+			assert.Equal(t, http.StatusCreated, code, fmt.Sprintf("We expected http status %d but got %d", http.StatusCreated, code))
+		}
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/deck/new", bytes.NewBuffer(payloadString))
+		limitedRouter.ServeHTTP(w, req)
+
+		// Verifying api status it should be 429 with the rate limit headers
+		assert.Equal(t, http.StatusTooManyRequests, w.Code, fmt.Sprintf("We expected http status %d but got %d", http.StatusTooManyRequests, w.Code))
+		assert.Equal(t, "60", w.Header().Get("Retry-After"), "We expected to retry after a minute")
+		assert.Equal(t, "2", w.Header().Get("X-RateLimit-Limit"), "We expected the limit header")
+		assert.Equal(t, "0", w.Header().Get("X-RateLimit-Remaining"), "We expected no request remaining")
+	})
+
+	t.Run("Creating decks over the rate limit with GraphQL", func(t *testing.T) {
+		query, _ := json.Marshal(map[string]string{"query": "mutation { newDeck(input: {}) { id } }"})
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/graphql", bytes.NewBuffer(query))
+		req.Header.Set("Content-Type", "application/json")
+		limitedRouter.ServeHTTP(w, req)
+
+		var response struct {
+			Errors []struct {
+				Extensions map[string]interface{} `json:"extensions"`
+			} `json:"errors"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &response)
+
+		// Verifying the mutation shares the bucket the REST route emptied
+		if assert.Len(t, response.Errors, 1, "We expected one error") {
+			assert.Equal(t, helper.ErrRateLimited.Code, response.Errors[0].Extensions["code"], fmt.Sprintf("We expected %s but found %v", helper.ErrRateLimited.Code, response.Errors[0].Extensions))
+		}
+	})
+
+	t.Run("Creating decks over the active deck cap", func(t *testing.T) {
+		t.Setenv("RATE_LIMIT_CREATE_PER_MINUTE", "0")
+		t.Setenv("MAX_ACTIVE_DECKS_PER_CLIENT", "2")
+		cappedRouter := config.SetupRouter()
+		defer controller.SetMaxActiveDecks(0)
+
+		// The two decks created above already reach the cap
+		res, code := util.RequestAndDecodeResponse("POST", "/deck/new", payloadString, t, cappedRouter)
+
+		// Verifying api status it should be 429
+		assert.Equal(t, http.StatusTooManyRequests, code, fmt.Sprintf("We expected http status %d but got %d", http.StatusTooManyRequests, code))
+
+		msg := "You have reached the limit of 2 active decks, delete some before creating new ones"
+		assert.Equal(t, msg, res.Error, fmt.Sprintf("We expected error message to be %s but found %s", msg, res.Error))
+	})
+
+	t.Run("Creating decks concurrently over the active deck cap", func(t *testing.T) {
+		t.Setenv("RATE_LIMIT_CREATE_PER_MINUTE", "0")
+		t.Setenv("MAX_ACTIVE_DECKS_PER_CLIENT", "5")
+		cappedRouter := config.SetupRouter()
+		defer controller.SetMaxActiveDecks(0)
+
+		cappedStore := store.NewMemoryStore(store.DefaultExpiryPolicy)
+		controller.UseStore(cappedStore)
+
+		var created int32
+		var wg sync.WaitGroup
+
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+
+			go func() {
+				defer wg.Done()
+
+				w := httptest.NewRecorder()
+				cappedRouter.ServeHTTP(w, httptest.NewRequest("POST", "/deck/new", bytes.NewReader(payloadString)))
+
+				if w.Code == http.StatusCreated {
+					atomic.AddInt32(&created, 1)
+				}
+			}()
+		}
+
+		wg.Wait()
+
+		// Verifying the concurrent requests did not push the client past the cap
+		assert.Equal(t, int32(5), created, fmt.Sprintf("We expected 5 decks to be created but got %d", created))
+
+		active, _ := cappedStore.Count(store.DeckQuery{})
+		assert.Equal(t, 5, active, fmt.Sprintf("We expected 5 active decks but found %d", active))
+	})
+}
+
+func TestIdempotencyKeys(t *testing.T) {
+	idempotentRouter := config.SetupRouter()
+
+	defaultStore := controller.Store()
+	controller.UseStore(store.NewMemoryStore(store.DefaultExpiryPolicy))
+	defer controller.UseStore(defaultStore)
+
+	send := func(method, path, key string, payload []byte) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, bytes.NewBuffer(payload))
+		req.Header.Set(middleware.IdempotencyKeyHeader, key)
+		idempotentRouter.ServeHTTP(w, req)
+		return w
+	}
+
+	createPayload, _ := json.Marshal(map[string]interface{}{"shuffle": true})
+	created := send("POST", "/deck/new", "create-1", createPayload)
+
+	var deck struct {
+		Data struct {
+			ID string `json:"_id"`
+		} `json:"data"`
+	}
+
+	if err := json.Unmarshal(created.Body.Bytes(), &deck); err != nil || created.Code != http.StatusCreated {
+		t.Fatalf("Test execution failed because the deck was not generated. Status: %d", created.Code)
+	}
+
+	t.Run("Replaying the creation of a deck", func(t *testing.T) {
+		replayed := send("POST", "/deck/new", "create-1", createPayload)
+
+		// Verifying the first response is replayed verbatim instead of creating another deck
+		assert.Equal(t, http.StatusCreated, replayed.Code, fmt.Sprintf("We expected http status %d but got %d", http.StatusCreated, replayed.Code))
+		assert.Equal(t, created.Body.String(), replayed.Body.String(), "We expected the first response to be replayed")
+		assert.Equal(t, "true", replayed.Header().Get(middleware.IdempotentReplayHeader), "We expected the replay header")
+
+		count, _ := controller.Store().Count(store.DeckQuery{})
+		assert.Equal(t, 1, count, fmt.Sprintf("We expected a single deck but found %d", count))
+	})
+
+	t.Run("Replaying a draw", func(t *testing.T) {
+		api := fmt.Sprintf("/deck/%s/draw-cards", deck.Data.ID)
+		drawPayload, _ := json.Marshal(map[string]int{"cardsToBeDrawn": 2})
+
+		first := send("PUT", api, "draw-1", drawPayload)
+		second := send("PUT", api, "draw-1", drawPayload)
+
+		assert.Equal(t, http.StatusOK, first.Code, fmt.Sprintf("We expected http status %d but got %d", http.StatusOK, first.Code))
+		assert.Equal(t, first.Body.String(), second.Body.String(), "We expected the same cards to be replayed")
+
+		opened, _ := controller.Store().Get(deck.Data.ID)
+		assert.Equal(t, 50, opened.CardsRemaining, fmt.Sprintf("We expected the cards to be drawn once but %d are remaining", opened.CardsRemaining))
+
+		// Verifying a new key draws again
+		third := send("PUT", api, "draw-2", drawPayload)
+		assert.NotEqual(t, first.Body.String(), third.Body.String(), "We expected other cards to be drawn with a new key")
+	})
+
+	t.Run("Reusing a key with another payload", func(t *testing.T) {
+		otherPayload, _ := json.Marshal(map[string]interface{}{"shuffle": false})
+		w := send("POST", "/deck/new", "create-1", otherPayload)
+
+		// Verifying api status it should be 422
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code, fmt.Sprintf("We expected http status %d but got %d", http.StatusUnprocessableEntity, w.Code))
+		assert.Contains(t, w.Body.String(), helper.ErrIdempotencyKeyReused.Code, "We expected the reused key error")
+	})
+
+	t.Run("Rejecting keys that are too long", func(t *testing.T) {
+		w := send("POST", "/deck/new", strings.Repeat("k", middleware.MaxIdempotencyKeyLength+1), createPayload)
+
+		// Verifying api status it should be 400
+		assert.Equal(t, http.StatusBadRequest, w.Code, fmt.Sprintf("We expected http status %d but got %d", http.StatusBadRequest, w.Code))
+		assert.Contains(t, w.Body.String(), helper.ErrIdempotencyKeyInvalid.Code, "We expected the invalid key error")
+	})
+}
+
+func TestDeckVersions(t *testing.T) {
+	versionRouter := config.SetupRouter()
+
+	send := func(method, path string, header http.Header, payload []byte) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, bytes.NewBuffer(payload))
+		req.Header = header
+		versionRouter.ServeHTTP(w, req)
+		return w
+	}
+
+	createPayload, _ := json.Marshal(map[string]bool{"shuffle": false})
+	created := send("POST", "/deck/new", http.Header{}, createPayload)
+
+	var deck struct {
+		Data struct {
+			ID      string `json:"_id"`
+			Version int    `json:"version"`
+		} `json:"data"`
+	}
+
+	if err := json.Unmarshal(created.Body.Bytes(), &deck); err != nil || created.Code != http.StatusCreated {
+		t.Fatalf("Test execution failed because the deck was not generated. Status: %d", created.Code)
+	}
+
+	deckPath := fmt.Sprintf("/deck/%s", deck.Data.ID)
+	drawPayload, _ := json.Marshal(map[string]int{"cardsToBeDrawn": 1})
+
+	t.Run("Opening a deck returns its version as ETag", func(t *testing.T) {
+		assert.Equal(t, 1, deck.Data.Version, fmt.Sprintf("We expected a new deck at version 1 but found %d", deck.Data.Version))
+
+		w := send("GET", deckPath, http.Header{}, nil)
+		assert.Equal(t, `"1"`, w.Header().Get("ETag"), fmt.Sprintf("We expected the ETag \"1\" but found %s", w.Header().Get("ETag")))
+
+		// Verifying an unchanged deck is not sent again
+		w = send("GET", deckPath, http.Header{"If-None-Match": {`"1"`}}, nil)
+		assert.Equal(t, http.StatusNotModified, w.Code, fmt.Sprintf("We expected http status %d but got %d", http.StatusNotModified, w.Code))
+		assert.Empty(t, w.Body.String(), "We expected no body for a 304")
+	})
+
+	t.Run("Drawing with the current ETag", func(t *testing.T) {
+		w := send("PUT", deckPath+"/draw-cards", http.Header{"If-Match": {`"1"`}}, drawPayload)
+
+		assert.Equal(t, http.StatusOK, w.Code, fmt.Sprintf("We expected http status %d but got %d", http.StatusOK, w.Code))
+		assert.Equal(t, `"2"`, w.Header().Get("ETag"), fmt.Sprintf("We expected the ETag \"2\" but found %s", w.Header().Get("ETag")))
+
+		// Verifying the old ETag no longer matches
+		w = send("GET", deckPath, http.Header{"If-None-Match": {`"1"`}}, nil)
+		assert.Equal(t, http.StatusOK, w.Code, fmt.Sprintf("We expected http status %d but got %d", http.StatusOK, w.Code))
+	})
+
+	t.Run("Stale writes are rejected", func(t *testing.T) {
+		w := send("PUT", deckPath+"/draw-cards", http.Header{"If-Match": {`"1"`}}, drawPayload)
+
+		// Verifying api status it should be 412
+		assert.Equal(t, http.StatusPreconditionFailed, w.Code, fmt.Sprintf("We expected http status %d but got %d", http.StatusPreconditionFailed, w.Code))
+		assert.Contains(t, w.Body.String(), helper.ErrDeckModified.Code, "We expected the deck modified error")
+
+		w = send("POST", deckPath+"/reset", http.Header{"If-Match": {`W/"2"`}}, nil)
+		assert.Equal(t, http.StatusPreconditionFailed, w.Code, fmt.Sprintf("We expected a weak ETag to be rejected but got %d", w.Code))
+
+		opened, _ := controller.Store().Get(deck.Data.ID)
+		assert.Equal(t, 51, opened.CardsRemaining, fmt.Sprintf("We expected the stale writes to be ignored but %d cards are remaining", opened.CardsRemaining))
+	})
+
+	t.Run("Resetting with the current ETag", func(t *testing.T) {
+		w := send("POST", deckPath+"/reset", http.Header{"If-Match": {`"0", "2"`}}, nil)
+
+		assert.Equal(t, http.StatusOK, w.Code, fmt.Sprintf("We expected http status %d but got %d", http.StatusOK, w.Code))
+		assert.Equal(t, `"3"`, w.Header().Get("ETag"), fmt.Sprintf("We expected the ETag \"3\" but found %s", w.Header().Get("ETag")))
+	})
+}
@@ -0,0 +1,63 @@
+package api_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/varadekd/card-game/config"
+	"github.com/varadekd/card-game/logging"
+	"github.com/varadekd/card-game/util"
+)
+
+func TestErrorLogs(t *testing.T) {
+	var logs bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(logging.New(&logs, slog.LevelInfo))
+	defer slog.SetDefault(previous)
+
+	loggingRouter := config.SetupRouter()
+
+	payloadString, _ := json.Marshal(map[string]any{"gameID": "logged-game", "cards": []string{"AS"}})
+	res, _ := util.RequestAndDecodeResponse("POST", "/deck/new", payloadString, t, loggingRouter)
+
+	if res.Data == nil {
+		t.Fatalf("Test execution failed because the deck was not generated. Err: %s", res.Error)
+	}
+
+	id := res.Data.(map[string]interface{})["_id"].(string)
+	logs.Reset()
+
+	drawPayload, _ := json.Marshal(map[string]int{"cardsToBeDrawn": 2})
+	_, status := util.RequestWithHeadersAndDecodeResponse("PUT", "/deck/"+id+"/draw-cards", drawPayload, map[string]string{"X-Request-ID": "draw-conflict"}, t, loggingRouter)
+
+	// Verifying the draw was rejected
+	assert.Equal(t, http.StatusConflict, status, fmt.Sprintf("We expected http status %d but got %d", http.StatusConflict, status))
+
+	t.Run("Logging the failed draw with its deck, game and request", func(t *testing.T) {
+		var failure map[string]interface{}
+
+		for _, line := range strings.Split(strings.TrimSpace(logs.String()), "\n") {
+			entry := map[string]interface{}{}
+			json.Unmarshal([]byte(line), &entry)
+
+			if entry["msg"] == "Unable to draw from the deck" {
+				failure = entry
+			}
+		}
+
+		if failure == nil {
+			t.Fatalf("We expected the failed draw to be logged, got %s", logs.String())
+		}
+
+		assert.Equal(t, id, failure["deck_id"], "We expected the deck ID")
+		assert.Equal(t, "logged-game", failure["game_id"], "We expected the game ID")
+		assert.Equal(t, "draw-conflict", failure["request_id"], "We expected the request ID")
+		assert.Equal(t, "INSUFFICIENT_CARDS", failure["error_code"], "We expected the error code")
+	})
+}
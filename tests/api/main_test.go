@@ -0,0 +1,28 @@
+package api_test
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/varadekd/card-game/helper"
+)
+
+// TestMain writes the default deck to a temporary file, set through the environment so the
+// routers loaded with config.SetupRouter use it too.
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "cards")
+
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	cardsFile := filepath.Join(dir, "cards.json")
+	os.Setenv("DEFAULT_CARDS_FILE_STORAGE", cardsFile)
+	helper.UseCardsFile(cardsFile)
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
@@ -0,0 +1,64 @@
+package api_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/varadekd/card-game/config"
+	"github.com/varadekd/card-game/metrics"
+	"github.com/varadekd/card-game/util"
+)
+
+func TestMetrics(t *testing.T) {
+	metricsRouter := config.SetupRouter()
+
+	created := metrics.DecksCreated.Value("true", "custom")
+	drawn := metrics.CardsDrawn.Value()
+	conflicts := metrics.DrawConflicts.Value()
+	draws := metrics.RequestDuration.Count("PUT", "/deck/:id/draw-cards", "200")
+
+	payloadString, _ := json.Marshal(map[string]any{"shuffle": true, "cards": []string{"AS", "2S", "3S"}})
+	res, _ := util.RequestAndDecodeResponse("POST", "/deck/new", payloadString, t, metricsRouter)
+
+	if res.Data == nil {
+		t.Fatalf("Test execution failed because the deck was not generated. Err: %s", res.Error)
+	}
+
+	id := res.Data.(map[string]interface{})["_id"].(string)
+
+	drawPayload, _ := json.Marshal(map[string]int{"cardsToBeDrawn": 2})
+	util.RequestAndDecodeResponse("PUT", "/deck/"+id+"/draw-cards", drawPayload, t, metricsRouter)
+	_, status := util.RequestAndDecodeResponse("PUT", "/deck/"+id+"/draw-cards", drawPayload, t, metricsRouter)
+
+	// Verifying the second draw was a conflict
+	assert.Equal(t, http.StatusConflict, status, fmt.Sprintf("We expected http status %d but got %d", http.StatusConflict, status))
+
+	t.Run("Counting decks and draws", func(t *testing.T) {
+		assert.Equal(t, created+1, metrics.DecksCreated.Value("true", "custom"), "We expected the shuffled custom deck to be counted")
+		assert.Equal(t, drawn+2, metrics.CardsDrawn.Value(), "We expected the 2 drawn cards to be counted")
+		assert.Equal(t, conflicts+1, metrics.DrawConflicts.Value(), "We expected the conflict to be counted")
+		assert.Equal(t, draws+1, metrics.RequestDuration.Count("PUT", "/deck/:id/draw-cards", "200"), "We expected the latency of the draw to be recorded by route")
+	})
+
+	t.Run("Exposing the metrics", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		metricsRouter.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+
+		assert.Equal(t, http.StatusOK, w.Code, fmt.Sprintf("We expected http status %d but got %d", http.StatusOK, w.Code))
+		assert.Equal(t, metrics.ContentType, w.Header().Get("Content-Type"), "We expected the Prometheus text format")
+
+		body := w.Body.String()
+
+		for _, name := range []string{"card_game_decks_created_total", "card_game_cards_drawn_total", "card_game_draw_conflicts_total", "card_game_active_decks", "card_game_http_request_duration_seconds_bucket"} {
+			assert.True(t, strings.Contains(body, name), fmt.Sprintf("We expected the metric %s", name))
+		}
+
+		// Verifying deck IDs never end up in the labels
+		assert.False(t, strings.Contains(body, id), "We expected the route template instead of the path")
+	})
+}
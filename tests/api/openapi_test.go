@@ -0,0 +1,126 @@
+package api_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/varadekd/card-game/config"
+	"github.com/varadekd/card-game/openapi"
+)
+
+func TestOpenApi(t *testing.T) {
+	dir := t.TempDir()
+	signingKeyFile := filepath.Join(dir, "signing.key")
+	os.WriteFile(signingKeyFile, []byte(strings.Repeat("k", 32)), 0600)
+
+	// Setting up a router with every optional route enabled
+	t.Setenv("API_KEYS_FILE", filepath.Join(dir, "keys.json"))
+	t.Setenv("JWT_SIGNING_KEY_FILE", signingKeyFile)
+	docsRouter := config.SetupRouter()
+
+	w := httptest.NewRecorder()
+	docsRouter.ServeHTTP(w, httptest.NewRequest("GET", "/openapi.json", nil))
+
+	// Verifying api status it should be 200
+	assert.Equal(t, http.StatusOK, w.Code, fmt.Sprintf("We expected http status %d but got %d", http.StatusOK, w.Code))
+
+	document := openapi.Document{}
+
+	if err := json.Unmarshal(w.Body.Bytes(), &document); err != nil {
+		t.Fatalf("Test execution failed because the document could not be decoded. Err: %s", err.Error())
+	}
+
+	t.Run("Every registered route is described", func(t *testing.T) {
+		for _, route := range docsRouter.Routes() {
+			path := openapi.Path(route.Path)
+			_, found := document.Paths[path][strings.ToLower(route.Method)]
+
+			assert.True(t, found, fmt.Sprintf("We expected the route %s %s to be described in the OpenAPI document", route.Method, path))
+		}
+	})
+
+	t.Run("Every described route is registered", func(t *testing.T) {
+		registered := map[string]bool{}
+
+		for _, route := range docsRouter.Routes() {
+			registered[strings.ToLower(route.Method)+" "+openapi.Path(route.Path)] = true
+		}
+
+		for path, operations := range document.Paths {
+			for method := range operations {
+				assert.True(t, registered[method+" "+path], fmt.Sprintf("We expected the described route %s %s to be registered", method, path))
+			}
+		}
+	})
+
+	t.Run("Schemas are derived from the models", func(t *testing.T) {
+		for _, name := range []string{"Deck", "Card", "DeckV1", "CardV1", "DeckEvent", "GenerateDeckPayload", "DrawCardFromDeckPayload", "ResponseJSON", "FieldError"} {
+			assert.Contains(t, document.Components.Schemas, name, fmt.Sprintf("We expected the schema %s in the document", name))
+		}
+
+		deck := document.Components.Schemas["Deck"]
+
+		if assert.NotNil(t, deck, "We expected the deck schema") {
+			assert.Contains(t, deck.Properties, "cardsRemaining", "We expected the deck properties to use the json names")
+			assert.NotContains(t, deck.Properties, "CreatedBy", "We expected fields hidden from json to be left out")
+		}
+
+		legacyDeck := document.Components.Schemas["DeckV1"]
+
+		if assert.NotNil(t, legacyDeck, "We expected the v1 deck schema") {
+			assert.Contains(t, legacyDeck.Properties, "cardRemaining", "We expected the v1 deck to keep its original json names")
+		}
+	})
+
+	t.Run("Deprecating the v1 routes", func(t *testing.T) {
+		assert.True(t, document.Paths["/deck/{id}"]["get"].Deprecated, "We expected the v1 routes to be deprecated")
+		assert.False(t, document.Paths["/v2/deck/{id}"]["get"].Deprecated, "We expected the v2 routes not to be deprecated")
+		assert.Equal(t, "openDeck", document.Paths["/deck/{id}"]["get"].OperationID, "We expected the v1 routes to keep their operation IDs")
+
+		cards := document.Components.Schemas["GenerateDeckPayload"].Properties["cards"]
+
+		if assert.NotNil(t, cards, "We expected the cards of the payload to be described") {
+			assert.True(t, cards.UniqueItems, "We expected the cards to be unique")
+			assert.NotEmpty(t, cards.Items.Pattern, "We expected the card codes to be validated by a pattern")
+		}
+
+		draw := document.Components.Schemas["DrawCardFromDeckPayload"].Properties["cardsToBeDrawn"]
+
+		if assert.NotNil(t, draw.Minimum, "We expected a minimum number of cards to draw") {
+			assert.Equal(t, float64(1), *draw.Minimum, "We expected at least one card to be drawn")
+		}
+	})
+
+	t.Run("Browsing the documentation", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		docsRouter.ServeHTTP(w, httptest.NewRequest("GET", "/docs", nil))
+
+		// Verifying api status it should be 200
+		assert.Equal(t, http.StatusOK, w.Code, fmt.Sprintf("We expected http status %d but got %d", http.StatusOK, w.Code))
+		assert.Contains(t, w.Body.String(), "/openapi.json", "We expected the page to load the OpenAPI document")
+		assert.NotContains(t, w.Body.String(), "https://", "We expected the page to load nothing from a CDN")
+	})
+
+	t.Run("Serving the embedded Swagger UI", func(t *testing.T) {
+		for _, asset := range []string{"/docs/assets/swagger-ui.css", "/docs/assets/swagger-ui-bundle.js"} {
+			w := httptest.NewRecorder()
+			docsRouter.ServeHTTP(w, httptest.NewRequest("GET", asset, nil))
+
+			assert.Equal(t, http.StatusOK, w.Code, fmt.Sprintf("We expected http status %d for %s but got %d", http.StatusOK, asset, w.Code))
+			assert.NotZero(t, w.Body.Len(), fmt.Sprintf("We expected the content of %s", asset))
+		}
+
+		w := httptest.NewRecorder()
+		docsRouter.ServeHTTP(w, httptest.NewRequest("GET", "/docs/assets/index.html", nil))
+
+		// Verifying only the files of the page are served
+		assert.Equal(t, http.StatusNotFound, w.Code, fmt.Sprintf("We expected http status %d but got %d", http.StatusNotFound, w.Code))
+	})
+}
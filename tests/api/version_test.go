@@ -0,0 +1,181 @@
+package api_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/varadekd/card-game/api"
+	"github.com/varadekd/card-game/config"
+	"github.com/varadekd/card-game/helper"
+	"github.com/varadekd/card-game/middleware"
+)
+
+// apiContract lists the JSON names a version of the deck routes sends.
+type apiContract struct {
+	prefix     string
+	idKey      string
+	deckKeys   []string
+	cardKeys   []string
+	deprecated bool
+}
+
+var contracts = []apiContract{
+	{
+		prefix: "/deck",
+		idKey:  "_id",
+		deckKeys: []string{"_id", "auditTrail", "cardRemaining", "createdAt", "deckLastUsed", "deckSize", "gameID",
+			"generatedDeck", "hands", "ownerID", "piles", "playingCards", "round", "shuffle", "sourceDeckID", "version"},
+		cardKeys:   []string{"code:", "suit", "value"},
+		deprecated: true,
+	},
+	{
+		prefix: "/v2/deck",
+		idKey:  "id",
+		deckKeys: []string{"auditTrail", "cardsRemaining", "createdAt", "deckLastUsed", "deckSize", "gameID",
+			"generatedDeck", "hands", "id", "ownerID", "piles", "playingCards", "round", "shuffle", "sourceDeckID", "version"},
+		cardKeys: []string{"code", "suit", "value"},
+	},
+}
+
+// contractRequest sends the request and returns the response with the data it holds.
+func contractRequest(t *testing.T, r *gin.Engine, method, path string, payload any, headers map[string]string) (*httptest.ResponseRecorder, any) {
+	var body []byte
+
+	if payload != nil {
+		body, _ = json.Marshal(payload)
+	}
+
+	req := httptest.NewRequest(method, path, bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	response := struct {
+		Data any `json:"data"`
+	}{}
+
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Unable to decode the response of %s %s: %v", method, path, err)
+	}
+
+	return w, response.Data
+}
+
+// keysOf returns the sorted keys of a JSON object.
+func keysOf(value any) []string {
+	object, _ := value.(map[string]any)
+	keys := make([]string, 0, len(object))
+
+	for key := range object {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+	return keys
+}
+
+func TestVersionContracts(t *testing.T) {
+	t.Setenv("DEALER_KEY", "dealer-secret")
+	contractRouter := config.SetupRouter()
+	helper.GenerateDefaultDeck()
+
+	for _, contract := range contracts {
+		contract := contract
+
+		t.Run(contract.prefix, func(t *testing.T) {
+			w, data := contractRequest(t, contractRouter, "POST", contract.prefix+"/new", map[string]any{"gameID": "contract", "cards": []string{"AS", "2S", "3S", "4S"}}, nil)
+
+			if w.Code != http.StatusCreated {
+				t.Fatalf("We expected http status %d but got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+			}
+
+			deck := data.(map[string]any)
+			id, _ := deck[contract.idKey].(string)
+
+			t.Run("Sending the deck with the JSON names of the version", func(t *testing.T) {
+				// Verifying the created deck
+				assert.Equal(t, contract.deckKeys, keysOf(deck), "We expected the deck keys of the version")
+
+				for _, card := range deck["generatedDeck"].([]any) {
+					assert.Equal(t, contract.cardKeys, keysOf(card), "We expected the card keys of the version")
+				}
+
+				// Verifying the opened deck
+				_, opened := contractRequest(t, contractRouter, "GET", contract.prefix+"/"+id, nil, nil)
+				assert.Equal(t, contract.deckKeys, keysOf(opened), "We expected the opened deck keys of the version")
+			})
+
+			t.Run("Sending the cards with the JSON names of the version", func(t *testing.T) {
+				_, drawn := contractRequest(t, contractRouter, "PUT", contract.prefix+"/"+id+"/draw-cards", map[string]any{"cardsToBeDrawn": 1, "playerID": "p1"}, nil)
+
+				if assert.Len(t, drawn, 1, "We expected one card to be drawn") {
+					assert.Equal(t, contract.cardKeys, keysOf(drawn.([]any)[0]), "We expected the drawn card keys of the version")
+				}
+
+				_, peeked := contractRequest(t, contractRouter, "GET", contract.prefix+"/"+id+"/peek?count=1", nil, map[string]string{middleware.DealerKeyHeader: "dealer-secret"})
+
+				if assert.Len(t, peeked, 1, "We expected one card to be peeked") {
+					assert.Equal(t, contract.cardKeys, keysOf(peeked.([]any)[0]), "We expected the peeked card keys of the version")
+				}
+
+				_, opened := contractRequest(t, contractRouter, "GET", contract.prefix+"/"+id, nil, nil)
+				hand := opened.(map[string]any)["hands"].(map[string]any)["p1"].([]any)
+				assert.Equal(t, contract.cardKeys, keysOf(hand[0]), "We expected the cards of the hands to use the keys of the version")
+			})
+
+			t.Run("Listing, cloning and resetting with the JSON names of the version", func(t *testing.T) {
+				_, page := contractRequest(t, contractRouter, "GET", contract.prefix+"?gameID=contract", nil, nil)
+				decks := page.(map[string]any)["decks"].([]any)
+
+				if assert.NotEmpty(t, decks, "We expected the deck to be listed") {
+					assert.Equal(t, contract.deckKeys, keysOf(decks[0]), "We expected the listed deck keys of the version")
+				}
+
+				_, clone := contractRequest(t, contractRouter, "POST", contract.prefix+"/"+id+"/clone", nil, nil)
+				assert.Equal(t, contract.deckKeys, keysOf(clone), "We expected the clone keys of the version")
+
+				_, reset := contractRequest(t, contractRouter, "POST", contract.prefix+"/"+id+"/reset", nil, nil)
+				assert.Equal(t, contract.deckKeys, keysOf(reset), "We expected the reset deck keys of the version")
+			})
+
+			t.Run("Announcing the deprecation", func(t *testing.T) {
+				if !contract.deprecated {
+					// Verifying the current version is not deprecated
+					assert.Empty(t, w.Header().Get(middleware.DeprecationHeader), "We expected no deprecation header")
+					assert.Empty(t, w.Header().Get("Link"), "We expected no successor link")
+					return
+				}
+
+				// Verifying the deprecation date and the successor of the route
+				assert.Equal(t, fmt.Sprintf("@%d", api.V1Deprecated.Unix()), w.Header().Get(middleware.DeprecationHeader), "We expected the date of the deprecation")
+				assert.Equal(t, `</v2/deck/new>; rel="successor-version"`, w.Header().Get("Link"), "We expected a link to the v2 route")
+
+				notFound, _ := contractRequest(t, contractRouter, "GET", contract.prefix+"/unknown", nil, nil)
+				assert.NotEmpty(t, notFound.Header().Get(middleware.DeprecationHeader), "We expected errors to be deprecated too")
+			})
+		})
+	}
+
+	t.Run("Sharing the decks between the versions", func(t *testing.T) {
+		_, data := contractRequest(t, contractRouter, "POST", "/deck/new", map[string]any{"cards": []string{"KH"}}, nil)
+		id := data.(map[string]any)["_id"].(string)
+
+		w, opened := contractRequest(t, contractRouter, "GET", "/v2/deck/"+id, nil, nil)
+
+		// Verifying a deck created with v1 can be used with v2
+		assert.Equal(t, http.StatusOK, w.Code, fmt.Sprintf("We expected http status %d but got %d", http.StatusOK, w.Code))
+		assert.Equal(t, float64(1), opened.(map[string]any)["cardsRemaining"], "We expected the v2 names for the deck")
+	})
+}
@@ -0,0 +1,129 @@
+package auth_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/varadekd/card-game/auth"
+)
+
+func TestKeyStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.json")
+
+	keys, err := auth.LoadKeyStore(path)
+
+	if err != nil {
+		t.Fatalf("Test execution failed because of an error. Err: %s", err.Error())
+	}
+
+	key, plainKey, err := keys.Mint("alice")
+
+	if err != nil {
+		t.Fatalf("Test execution failed because of an error. Err: %s", err.Error())
+	}
+
+	t.Run("Minted key is prefixed and never stored in plain", func(t *testing.T) {
+		assert.True(t, strings.HasPrefix(plainKey, auth.APIKeyPrefix), fmt.Sprintf("We expected the key to start with %s", auth.APIKeyPrefix))
+		assert.NotContains(t, key.Hash, plainKey, "We expected only the hash of the key to be stored")
+	})
+
+	t.Run("Authenticating with the minted key", func(t *testing.T) {
+		id, err := keys.Authenticate(plainKey)
+
+		assert.Nil(t, err, "We expected the key to be valid")
+		assert.Equal(t, key.ID, id, fmt.Sprintf("We expected key ID %s but got %s", key.ID, id))
+
+		_, err = keys.Authenticate(plainKey + "x")
+		assert.Equal(t, auth.ErrInvalidAPIKey, err, "We expected an unknown key to be rejected")
+	})
+
+	t.Run("Key revoked by another process is rejected", func(t *testing.T) {
+		// The CLI loads its own store on the same file
+		cli, _ := auth.LoadKeyStore(path)
+		assert.Nil(t, cli.Revoke(key.ID), "We expected the key to be revoked")
+
+		_, err := keys.Authenticate(plainKey)
+		assert.Equal(t, auth.ErrInvalidAPIKey, err, "We expected the revoked key to be rejected")
+	})
+
+	t.Run("Revoking an unknown key", func(t *testing.T) {
+		assert.Equal(t, auth.ErrAPIKeyNotFound, keys.Revoke("unknown"), "We expected the key not to be found")
+	})
+}
+
+func TestJWTSigner(t *testing.T) {
+	_, privateKey, _ := ed25519.GenerateKey(rand.Reader)
+	hmacSigner, _ := auth.NewHMACSigner([]byte(strings.Repeat("s", 32)))
+
+	signers := map[string]*auth.JWTSigner{
+		auth.AlgorithmHS256: hmacSigner,
+		auth.AlgorithmEdDSA: auth.NewEd25519Signer(privateKey),
+	}
+
+	for algorithm, signer := range signers {
+		t.Run(fmt.Sprintf("Issuing and verifying %s token", algorithm), func(t *testing.T) {
+			token, _, err := signer.Issue(auth.Claims{PlayerID: "p1", GameID: "g1", Role: auth.RolePlayer}, 0)
+			assert.Nil(t, err, "We expected the token to be issued")
+
+			claims, err := signer.Verify(token)
+			assert.Nil(t, err, "We expected the token to be valid")
+			assert.Equal(t, "p1", claims.PlayerID, fmt.Sprintf("We expected player p1 but got %s", claims.PlayerID))
+			assert.Equal(t, auth.RolePlayer, claims.Role, fmt.Sprintf("We expected role player but got %s", claims.Role))
+		})
+
+		t.Run(fmt.Sprintf("Rejecting tampered %s token", algorithm), func(t *testing.T) {
+			token, _, _ := signer.Issue(auth.Claims{PlayerID: "p1", GameID: "g1", Role: auth.RolePlayer}, 0)
+			parts := strings.Split(token, ".")
+
+			// Promoting the player to dealer without re-signing
+			payload, _ := json.Marshal(auth.Claims{PlayerID: "p1", GameID: "g1", Role: auth.RoleDealer, ExpiresAt: time.Now().Add(time.Hour).Unix()})
+			parts[1] = base64.RawURLEncoding.EncodeToString(payload)
+
+			_, err := signer.Verify(strings.Join(parts, "."))
+			assert.Equal(t, auth.ErrInvalidToken, err, "We expected the tampered token to be rejected")
+		})
+	}
+
+	t.Run("Rejecting token signed with another algorithm", func(t *testing.T) {
+		token, _, _ := signers[auth.AlgorithmEdDSA].Issue(auth.Claims{PlayerID: "p1", GameID: "g1", Role: auth.RolePlayer}, 0)
+
+		_, err := hmacSigner.Verify(token)
+		assert.Equal(t, auth.ErrInvalidToken, err, "We expected the token to be rejected")
+	})
+
+	t.Run("Rejecting expired token", func(t *testing.T) {
+		token, _, _ := hmacSigner.Issue(auth.Claims{PlayerID: "p1", GameID: "g1", Role: auth.RolePlayer}, time.Minute)
+
+		hmacSigner.Now = func() time.Time { return time.Now().Add(2 * time.Minute) }
+		defer func() { hmacSigner.Now = time.Now }()
+
+		_, err := hmacSigner.Verify(token)
+		assert.Equal(t, auth.ErrTokenExpired, err, "We expected the token to be expired")
+	})
+
+	t.Run("Rejecting short hmac secret", func(t *testing.T) {
+		_, err := auth.NewHMACSigner([]byte("short"))
+		assert.NotNil(t, err, "We expected the secret to be rejected")
+	})
+
+	t.Run("Loading Ed25519 key from PEM file", func(t *testing.T) {
+		der, _ := x509.MarshalPKCS8PrivateKey(privateKey)
+		path := filepath.Join(t.TempDir(), "signing.pem")
+		os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600)
+
+		signer, err := auth.LoadSigner(path)
+		assert.Nil(t, err, "We expected the key to be loaded")
+		assert.Equal(t, auth.AlgorithmEdDSA, signer.Algorithm(), "We expected EdDSA to be used")
+	})
+}
@@ -0,0 +1,113 @@
+package cards_test
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/varadekd/card-game/cards"
+	"github.com/varadekd/card-game/model"
+)
+
+func TestParseCode(t *testing.T) {
+	valid := map[string]cards.Card{
+		"AS":  cards.New(cards.Ace, cards.Spades),
+		"10H": cards.New(cards.Ten, cards.Hearts),
+		"7D":  cards.New(cards.Seven, cards.Diamonds),
+		"KC":  cards.New(cards.King, cards.Clubs),
+	}
+
+	for code, expected := range valid {
+		t.Run("Parsing "+code, func(t *testing.T) {
+			card, err := cards.ParseCode(code)
+
+			assert.Nil(t, err, fmt.Sprintf("We expected no error but got %v", err))
+			assert.Equal(t, expected, card, fmt.Sprintf("We expected %s to be parsed", code))
+			// Verifying the code is written back the same way
+			assert.Equal(t, code, card.String(), fmt.Sprintf("We expected %s but got %s", code, card.String()))
+		})
+	}
+
+	t.Run("Rejecting invalid codes", func(t *testing.T) {
+		for _, code := range []string{"", "A", "1S", "11H", "as", "AX", "010S", "S"} {
+			_, err := cards.ParseCode(code)
+
+			assert.True(t, errors.Is(err, cards.ErrInvalidCode), fmt.Sprintf("We expected ErrInvalidCode for %q but got %v", code, err))
+			assert.False(t, cards.ValidCode(code), fmt.Sprintf("We expected %q to be invalid", code))
+		}
+	})
+}
+
+func TestStandard(t *testing.T) {
+	deck := cards.Standard()
+	pattern := regexp.MustCompile(cards.CodePattern)
+	seen := map[string]bool{}
+
+	// Verifying the deck holds 52 distinct cards
+	assert.Equal(t, 52, len(deck), fmt.Sprintf("We expected 52 cards but got %d", len(deck)))
+
+	for _, card := range deck {
+		code := card.String()
+
+		assert.False(t, seen[code], fmt.Sprintf("We expected %s only once", code))
+		assert.True(t, pattern.MatchString(code), fmt.Sprintf("We expected %s to match the code pattern", code))
+		seen[code] = true
+	}
+
+	// Verifying the order of the default deck
+	assert.Equal(t, "AS", deck[0].String(), "We expected the ace of spades first")
+	assert.Equal(t, "AD", deck[13].String(), "We expected the diamonds after the spades")
+	assert.Equal(t, "KH", deck[51].String(), "We expected the king of hearts last")
+}
+
+func TestOrdering(t *testing.T) {
+	t.Run("Ranking the ace low or high", func(t *testing.T) {
+		assert.Equal(t, -1, cards.AceLow.Compare(cards.Ace, cards.Two), "We expected a low ace below the two")
+		assert.Equal(t, 1, cards.AceHigh.Compare(cards.Ace, cards.King), "We expected a high ace above the king")
+		assert.Equal(t, 0, cards.AceHigh.Compare(cards.Queen, cards.Queen), "We expected equal ranks")
+		assert.Equal(t, 14, cards.AceHigh.Value(cards.Ace), "We expected a high ace to be worth 14")
+	})
+
+	t.Run("Ordering the suits", func(t *testing.T) {
+		assert.Equal(t, 1, cards.BridgeOrder.Compare(cards.Spades, cards.Hearts), "We expected spades above hearts in bridge")
+		assert.Equal(t, -1, cards.DeckOrder.Compare(cards.Spades, cards.Hearts), "We expected spades first in the deck")
+	})
+
+	t.Run("Comparing cards by rank then suit", func(t *testing.T) {
+		tenOfSpades := cards.New(cards.Ten, cards.Spades)
+		tenOfClubs := cards.New(cards.Ten, cards.Clubs)
+		aceOfClubs := cards.New(cards.Ace, cards.Clubs)
+
+		assert.Equal(t, 1, tenOfSpades.Compare(tenOfClubs, cards.AceHigh, cards.BridgeOrder), "We expected the suit to break the tie")
+		assert.Equal(t, -1, tenOfSpades.Compare(aceOfClubs, cards.AceHigh, cards.BridgeOrder), "We expected the rank to win over the suit")
+		assert.Equal(t, 1, tenOfSpades.Compare(aceOfClubs, cards.AceLow, cards.BridgeOrder), "We expected a low ace below the ten")
+	})
+}
+
+func TestPredicates(t *testing.T) {
+	assert.Equal(t, cards.Red, cards.New(cards.Two, cards.Hearts).Colour(), "We expected hearts to be red")
+	assert.Equal(t, cards.Red, cards.New(cards.Two, cards.Diamonds).Colour(), "We expected diamonds to be red")
+	assert.Equal(t, cards.Black, cards.New(cards.Two, cards.Clubs).Colour(), "We expected clubs to be black")
+	assert.Equal(t, cards.Black, cards.New(cards.Two, cards.Spades).Colour(), "We expected spades to be black")
+
+	for _, rank := range cards.Ranks {
+		expected := rank == cards.Jack || rank == cards.Queen || rank == cards.King
+		assert.Equal(t, expected, rank.IsFace(), fmt.Sprintf("We expected IsFace of %s to be %t", rank, expected))
+	}
+}
+
+func TestModel(t *testing.T) {
+	card := cards.New(cards.Queen, cards.Diamonds)
+
+	// Verifying the card sent to clients
+	assert.Equal(t, model.Card{Value: "Q", Suit: "DIAMONDS", Code: "QD"}, card.Model(), "We expected the value, suit and code of the card")
+
+	parsed, err := cards.FromModel(card.Model())
+	assert.Nil(t, err, fmt.Sprintf("We expected no error but got %v", err))
+	assert.Equal(t, card, parsed, "We expected the same card back")
+
+	_, err = cards.FromModel(model.Card{Value: "K", Suit: "DIAMONDS", Code: "QD"})
+	assert.True(t, errors.Is(err, cards.ErrInvalidCode), "We expected a code not matching its value to be rejected")
+}
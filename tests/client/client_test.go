@@ -0,0 +1,283 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go/build"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/varadekd/card-game/client"
+	"github.com/varadekd/card-game/config"
+	"github.com/varadekd/card-game/helper"
+	"github.com/varadekd/card-game/model"
+)
+
+func TestClient(t *testing.T) {
+	t.Setenv("DEALER_KEY", "dealer-secret")
+
+	// Generating default card deck to ensure default deck is generated
+	helper.GenerateDefaultDeck()
+
+	server := httptest.NewServer(config.SetupRouter())
+	defer server.Close()
+
+	ctx := context.Background()
+	c := client.New(server.URL)
+
+	deck, err := c.NewDeck(ctx, model.GenerateDeckPayload{Cards: []string{"AS", "KH", "10D"}})
+
+	if err != nil {
+		t.Fatalf("Test execution failed because the deck was not generated. Err: %s", err.Error())
+	}
+
+	t.Run("Creating and opening a deck", func(t *testing.T) {
+		// Verifying the typed deck is returned
+		assert.Equal(t, 3, deck.DeckSize, fmt.Sprintf("We expected a deck of 3 cards but found %d", deck.DeckSize))
+
+		opened, err := c.OpenDeck(ctx, deck.ID)
+
+		assert.Nil(t, err, "We expected the deck to be opened")
+		assert.Equal(t, deck.ID, opened.ID, "We expected the same deck to be opened")
+	})
+
+	t.Run("Drawing cards", func(t *testing.T) {
+		cards, err := c.Draw(ctx, deck.ID, model.DrawCardFromDeckPayload{CardsToBeDrawn: 2})
+
+		assert.Nil(t, err, "We expected the cards to be drawn")
+
+		if assert.Len(t, cards, 2, "We expected two cards") {
+			assert.Equal(t, "AS", cards[0].Code, fmt.Sprintf("We expected the first card to be AS but found %s", cards[0].Code))
+		}
+	})
+
+	t.Run("Peeking as the dealer", func(t *testing.T) {
+		c := client.New(server.URL)
+		c.DealerKey = "dealer-secret"
+
+		cards, err := c.PeekDeck(ctx, deck.ID, model.PeekDeckPayload{Count: 1})
+
+		assert.Nil(t, err, "We expected the dealer to peek")
+		assert.Len(t, cards, 1, "We expected one card")
+	})
+
+	t.Run("Errors are mapped to the catalogue", func(t *testing.T) {
+		_, err := c.Draw(ctx, deck.ID, model.DrawCardFromDeckPayload{CardsToBeDrawn: 5})
+
+		// Verifying the error can be matched with errors.Is
+		assert.True(t, errors.Is(err, client.ErrInsufficientCards), fmt.Sprintf("We expected an insufficient cards error but found %v", err))
+
+		_, err = c.NewDeck(ctx, model.GenerateDeckPayload{Cards: []string{"1X"}})
+
+		var apiErr *client.Error
+
+		if assert.True(t, errors.As(err, &apiErr), "We expected a typed api error") {
+			assert.Equal(t, http.StatusBadRequest, apiErr.Status, fmt.Sprintf("We expected http status %d but got %d", http.StatusBadRequest, apiErr.Status))
+			assert.Equal(t, client.ErrInvalidCardCode.Code, apiErr.Code, fmt.Sprintf("We expected error code to be %s but found %s", client.ErrInvalidCardCode.Code, apiErr.Code))
+			assert.Len(t, apiErr.Details, 1, "We expected the rejected card in the error details")
+		}
+
+		err = c.DeleteDeck(ctx, deck.ID)
+		assert.Nil(t, err, "We expected the deck to be deleted")
+
+		_, err = c.OpenDeck(ctx, deck.ID)
+		assert.True(t, errors.Is(err, client.ErrDeckNotFound), fmt.Sprintf("We expected a deck not found error but found %v", err))
+	})
+}
+
+func TestClientRetries(t *testing.T) {
+	var attempts int32
+	deckID := uuid.New()
+
+	// The server fails twice before answering
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) <= 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		if r.URL.Path != "/v2/deck/"+deckID.String() {
+			t.Errorf("We expected the client to call the v2 routes but got %s", r.URL.Path)
+		}
+
+		fmt.Fprintf(w, `{"success": true, "data": {"id": "%s"}}`, deckID)
+	}))
+	defer server.Close()
+
+	c := client.New(server.URL)
+	c.RetryWait = time.Millisecond
+
+	t.Run("Retrying idempotent calls", func(t *testing.T) {
+		deck, err := c.OpenDeck(context.Background(), deckID)
+
+		assert.Nil(t, err, "We expected the call to succeed after retrying")
+		assert.Equal(t, deckID, deck.ID, "We expected the deck of the last attempt")
+		assert.Equal(t, int32(3), atomic.LoadInt32(&attempts), "We expected three attempts")
+	})
+
+	t.Run("Never retrying peeks", func(t *testing.T) {
+		atomic.StoreInt32(&attempts, 0)
+
+		_, err := c.PeekDeck(context.Background(), deckID, model.PeekDeckPayload{Count: 1})
+
+		// Verifying a peek is not audited twice
+		assert.NotNil(t, err, "We expected the peek to fail")
+		assert.Equal(t, int32(1), atomic.LoadInt32(&attempts), "We expected a single attempt")
+	})
+
+	t.Run("Stopping when the context is cancelled", func(t *testing.T) {
+		atomic.StoreInt32(&attempts, 0)
+		c.RetryWait = time.Hour
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		_, err := c.OpenDeck(ctx, deckID)
+
+		assert.True(t, errors.Is(err, context.DeadlineExceeded), fmt.Sprintf("We expected the context error but found %v", err))
+	})
+}
+
+func TestClientIdempotencyKeys(t *testing.T) {
+	var mu sync.Mutex
+	var keys []string
+	deckID := uuid.New()
+
+	// The server fails every other attempt and records the idempotency keys it receives
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		keys = append(keys, r.Header.Get(client.IdempotencyKeyHeader))
+		failing := len(keys)%2 == 1
+		mu.Unlock()
+
+		if failing {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		if r.Method == http.MethodPut {
+			fmt.Fprint(w, `{"success": true, "data": [{"code": "AS"}]}`)
+			return
+		}
+
+		fmt.Fprintf(w, `{"success": true, "data": {"id": "%s"}}`, deckID)
+	}))
+	defer server.Close()
+
+	c := client.New(server.URL)
+	c.RetryWait = time.Millisecond
+
+	// sentKeys returns the keys received since the last call and forgets them
+	sentKeys := func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+
+		sent := keys
+		keys = nil
+		return sent
+	}
+
+	t.Run("Retrying draws with the same key", func(t *testing.T) {
+		cards, err := c.Draw(context.Background(), deckID, model.DrawCardFromDeckPayload{CardsToBeDrawn: 1})
+
+		assert.Nil(t, err, "We expected the draw to succeed after retrying")
+		assert.Len(t, cards, 1, "We expected the card of the last attempt")
+
+		sent := sentKeys()
+
+		if assert.Len(t, sent, 2, "We expected two attempts") {
+			assert.NotEmpty(t, sent[0], "We expected an idempotency key")
+			assert.Equal(t, sent[0], sent[1], "We expected the retry to send the same key")
+		}
+	})
+
+	t.Run("Retrying deck creation with the same key", func(t *testing.T) {
+		deck, err := c.NewDeck(context.Background(), model.GenerateDeckPayload{})
+
+		assert.Nil(t, err, "We expected the deck to be created after retrying")
+		assert.Equal(t, deckID, deck.ID, "We expected the deck of the last attempt")
+
+		sent := sentKeys()
+
+		if assert.Len(t, sent, 2, "We expected two attempts") {
+			assert.NotEmpty(t, sent[0], "We expected an idempotency key")
+			assert.Equal(t, sent[0], sent[1], "We expected the retry to send the same key")
+		}
+
+		// Verifying every call gets its own key
+		c.NewDeck(context.Background(), model.GenerateDeckPayload{})
+		assert.NotEqual(t, sent[0], sentKeys()[0], "We expected a new key for a new deck")
+	})
+}
+
+func TestClientDeleteRetries(t *testing.T) {
+	var attempts int32
+
+	// The first attempt deletes the deck but its response is lost
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"success": false, "errorCode": "DECK_NOT_FOUND"}`)
+	}))
+	defer server.Close()
+
+	c := client.New(server.URL)
+	c.RetryWait = time.Millisecond
+
+	t.Run("Treating a retried delete of a missing deck as deleted", func(t *testing.T) {
+		err := c.DeleteDeck(context.Background(), uuid.New())
+
+		assert.Nil(t, err, "We expected the deck to be reported as deleted")
+		assert.Equal(t, int32(2), atomic.LoadInt32(&attempts), "We expected two attempts")
+	})
+
+	t.Run("Reporting a missing deck on the first attempt", func(t *testing.T) {
+		err := c.DeleteDeck(context.Background(), uuid.New())
+
+		assert.True(t, errors.Is(err, client.ErrDeckNotFound), fmt.Sprintf("We expected a deck not found error but found %v", err))
+	})
+}
+
+func TestClientPackage(t *testing.T) {
+	t.Run("Matching the error catalogue of the server", func(t *testing.T) {
+		codes := []string{}
+
+		for _, e := range client.Catalogue {
+			codes = append(codes, e.Code)
+		}
+
+		for _, e := range helper.ErrorCatalogue {
+			assert.Contains(t, codes, e.Code, fmt.Sprintf("We expected the client to know the error %s", e.Code))
+		}
+
+		assert.Len(t, codes, len(helper.ErrorCatalogue), "We expected the client to know only the errors of the server")
+	})
+
+	t.Run("Depending on the model only", func(t *testing.T) {
+		pkg, err := build.ImportDir("../../client", 0)
+
+		if err != nil {
+			t.Fatalf("Unable to read the client package: %v", err)
+		}
+
+		for _, path := range pkg.Imports {
+			if strings.HasPrefix(path, "github.com/varadekd/card-game/") {
+				assert.Equal(t, "github.com/varadekd/card-game/model", path, "We expected the client to import no server package but the model")
+			}
+
+			assert.False(t, strings.HasPrefix(path, "github.com/gin-gonic/"), "We expected the client not to import gin")
+		}
+	})
+}
@@ -5,10 +5,12 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/varadekd/card-game/config"
+	"github.com/varadekd/card-game/helper"
 )
 
 func TestPing(t *testing.T) {
@@ -30,3 +32,48 @@ func TestPing(t *testing.T) {
 
 	})
 }
+
+func TestErrorCatalogue(t *testing.T) {
+	router := config.SetupRouter()
+	t.Run("Fetching the error catalogue", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/errors", nil)
+
+		router.ServeHTTP(w, req)
+
+		var res struct {
+			Success bool              `json:"success"`
+			Data    []helper.APIError `json:"data"`
+		}
+
+		if err := json.NewDecoder(w.Body).Decode(&res); err != nil {
+			t.Fatalf("Failed to decode JSON: %v", err)
+		}
+
+		assert.EqualValues(t, http.StatusOK, w.Code, fmt.Sprintf("We expected http status %d but got %d", http.StatusOK, w.Code))
+		assert.Len(t, res.Data, len(helper.ErrorCatalogue), "We expected every error of the catalogue")
+
+		// Verifying that every code is unique so clients can switch on it
+		codes := map[string]bool{}
+		for _, apiErr := range res.Data {
+			assert.False(t, codes[apiErr.Code], fmt.Sprintf("We expected the code %s to be unique", apiErr.Code))
+			codes[apiErr.Code] = true
+		}
+	})
+}
+
+func TestRouterConfig(t *testing.T) {
+	cardsFile, _ := helper.CardsFile()
+	defer helper.UseCardsFile(cardsFile)
+
+	t.Run("Using the cards file of the configuration", func(t *testing.T) {
+		cfg := config.Default()
+		cfg.Decks.CardsFile = filepath.Join(t.TempDir(), "cards.json")
+		config.NewRouter(cfg)
+
+		configured, err := helper.CardsFile()
+
+		assert.NoError(t, err, "We expected the cards file to be configured")
+		assert.Equal(t, cfg.Decks.CardsFile, configured, fmt.Sprintf("We expected the cards file %s but got %s", cfg.Decks.CardsFile, configured))
+	})
+}
@@ -0,0 +1,124 @@
+package config_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/varadekd/card-game/buildinfo"
+	"github.com/varadekd/card-game/config"
+	"github.com/varadekd/card-game/controller"
+	"github.com/varadekd/card-game/health"
+	"github.com/varadekd/card-game/helper"
+	"github.com/varadekd/card-game/store"
+)
+
+type healthResponse struct {
+	Success   bool          `json:"success"`
+	ErrorCode string        `json:"errorCode"`
+	Data      health.Report `json:"data"`
+}
+
+func probe(t *testing.T, router *gin.Engine, path string, data interface{}) int {
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+
+	if err := json.NewDecoder(w.Body).Decode(data); err != nil {
+		t.Fatalf("Failed to decode JSON: %v", err)
+	}
+
+	return w.Code
+}
+
+// checkStatus returns the status of the named check of the report.
+func checkStatus(report health.Report, name string) string {
+	for _, result := range report.Checks {
+		if result.Name == name {
+			return result.Status
+		}
+	}
+
+	return ""
+}
+
+func TestHealth(t *testing.T) {
+	router := config.SetupRouter()
+	helper.GenerateDefaultDeck()
+
+	t.Run("Liveness", func(t *testing.T) {
+		res := healthResponse{}
+		code := probe(t, router, "/healthz", &res)
+
+		assert.Equal(t, http.StatusOK, code, fmt.Sprintf("We expected http status %d but got %d", http.StatusOK, code))
+		assert.True(t, res.Success, "We expected the application to be alive")
+	})
+
+	t.Run("Not ready while the sweeper is stopped", func(t *testing.T) {
+		controller.UseSweeper(nil)
+
+		res := healthResponse{}
+		code := probe(t, router, "/readyz", &res)
+
+		assert.Equal(t, http.StatusServiceUnavailable, code, fmt.Sprintf("We expected http status %d but got %d", http.StatusServiceUnavailable, code))
+		assert.Equal(t, helper.ErrNotReady.Code, res.ErrorCode, fmt.Sprintf("We expected the error code %s but got %s", helper.ErrNotReady.Code, res.ErrorCode))
+		// Verifying the report tells which check failed
+		assert.Equal(t, health.StatusFailing, checkStatus(res.Data, "sweeper"), "We expected the sweeper check to fail")
+		assert.Equal(t, health.StatusOK, checkStatus(res.Data, "defaultDeck"), "We expected the default deck check to pass")
+		assert.Equal(t, health.StatusOK, checkStatus(res.Data, "storage"), "We expected the storage check to pass")
+	})
+
+	sweeper := store.NewSweeper(controller.Store(), time.Hour)
+	sweeper.Start()
+	defer sweeper.Stop()
+	controller.UseSweeper(sweeper)
+	defer controller.UseSweeper(nil)
+
+	t.Run("Ready once every check passes", func(t *testing.T) {
+		res := healthResponse{}
+		code := probe(t, router, "/readyz", &res)
+
+		assert.Equal(t, http.StatusOK, code, fmt.Sprintf("We expected http status %d but got %d", http.StatusOK, code))
+		assert.Equal(t, health.StatusOK, res.Data.Status, fmt.Sprintf("We expected the status ok but got %s", res.Data.Status))
+		assert.Len(t, res.Data.Checks, 3, "We expected the report of every check")
+	})
+
+	t.Run("Not ready without the default deck", func(t *testing.T) {
+		cardsFile, _ := helper.CardsFile()
+		helper.UseCardsFile(filepath.Join(t.TempDir(), "missing.json"))
+		defer helper.UseCardsFile(cardsFile)
+
+		res := healthResponse{}
+		code := probe(t, router, "/readyz", &res)
+
+		assert.Equal(t, http.StatusServiceUnavailable, code, fmt.Sprintf("We expected http status %d but got %d", http.StatusServiceUnavailable, code))
+		assert.Equal(t, health.StatusFailing, checkStatus(res.Data, "defaultDeck"), "We expected the default deck check to fail")
+	})
+
+	t.Run("Not ready when the snapshot directory is missing", func(t *testing.T) {
+		t.Setenv("STORAGE_SNAPSHOT_FILE", filepath.Join(t.TempDir(), "missing", "decks.snapshot"))
+		snapshotRouter := config.SetupRouter()
+
+		res := healthResponse{}
+		code := probe(t, snapshotRouter, "/readyz", &res)
+
+		assert.Equal(t, http.StatusServiceUnavailable, code, fmt.Sprintf("We expected http status %d but got %d", http.StatusServiceUnavailable, code))
+		assert.Equal(t, health.StatusFailing, checkStatus(res.Data, "storage"), "We expected the storage check to fail")
+	})
+
+	t.Run("Build info", func(t *testing.T) {
+		var res struct {
+			Data buildinfo.Info `json:"data"`
+		}
+		code := probe(t, router, "/version", &res)
+
+		assert.Equal(t, http.StatusOK, code, fmt.Sprintf("We expected http status %d but got %d", http.StatusOK, code))
+		assert.Equal(t, buildinfo.Version, res.Data.Version, fmt.Sprintf("We expected the version %s but got %s", buildinfo.Version, res.Data.Version))
+		assert.NotEmpty(t, res.Data.GoVersion, "We expected the Go version")
+	})
+}
@@ -0,0 +1,209 @@
+package config_test
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/varadekd/card-game/config"
+)
+
+// clearEnv unsets the variables read by config.Load for the duration of the test.
+func clearEnv(t *testing.T) {
+	for _, variable := range []string{config.ConfigFileVariable, "APP_PORT", "GRPC_PORT", "GIN_MODE", "STORAGE_BACKEND", "DEFAULT_CARDS_FILE_STORAGE", "CARDS_RELOAD_INTERVAL", "DECK_IDLE_TTL", "API_KEYS_FILE", "JWT_SIGNING_KEY_FILE", "RATE_LIMIT_DRAW_PER_MINUTE", "IDEMPOTENCY_TTL", config.DeprecatedIdempotencyTTLVariable, "LOG_LEVEL", "TRACING_EXPORTER", "TRACING_OTLP_ENDPOINT"} {
+		t.Setenv(variable, "")
+	}
+}
+
+func writeConfig(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Unable to write the config file: %v", err)
+	}
+
+	return path
+}
+
+func TestLoadConfig(t *testing.T) {
+	t.Run("Loading the defaults", func(t *testing.T) {
+		clearEnv(t)
+
+		cfg, err := config.Load(nil)
+
+		assert.Nil(t, err, fmt.Sprintf("We expected no error but got %v", err))
+		assert.Equal(t, config.Default(), cfg, "We expected the default configuration")
+	})
+
+	t.Run("Loading a YAML file with -config", func(t *testing.T) {
+		clearEnv(t)
+		path := writeConfig(t, "config.yaml", "server:\n  port: \"9000\"\n  mode: release\ndecks:\n  idleTTL: 90s\n  maxAge: 3600\nlimits:\n  drawPerMinute: 10\n")
+
+		cfg, err := config.Load([]string{"-config", path})
+
+		assert.Nil(t, err, fmt.Sprintf("We expected no error but got %v", err))
+		assert.Equal(t, "9000", cfg.Server.Port, fmt.Sprintf("We expected port 9000 but got %s", cfg.Server.Port))
+		assert.Equal(t, "release", cfg.Server.Mode, fmt.Sprintf("We expected the release mode but got %s", cfg.Server.Mode))
+		assert.Equal(t, 90*time.Second, cfg.Decks.IdleTTL.Duration, fmt.Sprintf("We expected an idle TTL of 90s but got %s", cfg.Decks.IdleTTL))
+		// Verifying a plain number is a number of seconds
+		assert.Equal(t, time.Hour, cfg.Decks.MaxAge.Duration, fmt.Sprintf("We expected a max age of 1h but got %s", cfg.Decks.MaxAge))
+		assert.Equal(t, 10, cfg.Limits.DrawPerMinute, fmt.Sprintf("We expected 10 draws per minute but got %d", cfg.Limits.DrawPerMinute))
+		// Verifying the settings missing from the file keep their default
+		assert.Equal(t, config.Default().Limits.CreatePerMinute, cfg.Limits.CreatePerMinute, "We expected the default create limit")
+	})
+
+	t.Run("Loading the example file", func(t *testing.T) {
+		clearEnv(t)
+
+		cfg, err := config.Load([]string{"-config", "../../config.example.yaml"})
+
+		assert.Nil(t, err, fmt.Sprintf("We expected no error but got %v", err))
+		assert.Equal(t, config.Default(), cfg, "We expected the example file to list the defaults")
+	})
+
+	t.Run("Loading a TOML file with CONFIG_FILE", func(t *testing.T) {
+		clearEnv(t)
+		path := writeConfig(t, "config.toml", "[server]\nport = \"9100\"\n\n[decks]\ngracePeriod = \"2m\"\n")
+		t.Setenv(config.ConfigFileVariable, path)
+
+		cfg, err := config.Load(nil)
+
+		assert.Nil(t, err, fmt.Sprintf("We expected no error but got %v", err))
+		assert.Equal(t, "9100", cfg.Server.Port, fmt.Sprintf("We expected port 9100 but got %s", cfg.Server.Port))
+		assert.Equal(t, 2*time.Minute, cfg.Decks.GracePeriod.Duration, fmt.Sprintf("We expected a grace period of 2m but got %s", cfg.Decks.GracePeriod))
+	})
+
+	t.Run("Overriding the file with the environment and the flags", func(t *testing.T) {
+		clearEnv(t)
+		path := writeConfig(t, "config.yaml", "server:\n  port: \"9000\"\n  mode: release\nlimits:\n  drawPerMinute: 10\n")
+		t.Setenv("APP_PORT", "9001")
+		t.Setenv("RATE_LIMIT_DRAW_PER_MINUTE", "20")
+
+		cfg, err := config.Load([]string{"-config", path, "-port", "9002"})
+
+		assert.Nil(t, err, fmt.Sprintf("We expected no error but got %v", err))
+		assert.Equal(t, "9002", cfg.Server.Port, fmt.Sprintf("We expected the flag to win with port 9002 but got %s", cfg.Server.Port))
+		assert.Equal(t, 20, cfg.Limits.DrawPerMinute, fmt.Sprintf("We expected the variable to win with 20 draws per minute but got %d", cfg.Limits.DrawPerMinute))
+		assert.Equal(t, "release", cfg.Server.Mode, fmt.Sprintf("We expected the mode of the file but got %s", cfg.Server.Mode))
+	})
+
+	t.Run("Reading the idempotency TTL", func(t *testing.T) {
+		clearEnv(t)
+		t.Setenv("IDEMPOTENCY_TTL", "2h")
+
+		cfg, err := config.Load(nil)
+
+		assert.Nil(t, err, fmt.Sprintf("We expected no error but got %v", err))
+		assert.Equal(t, 2*time.Hour, cfg.Limits.IdempotencyTTL.Duration, fmt.Sprintf("We expected a TTL of 2h but got %s", cfg.Limits.IdempotencyTTL))
+
+		// Verifying the former variable is still read as a number of seconds
+		t.Setenv("IDEMPOTENCY_TTL", "")
+		t.Setenv(config.DeprecatedIdempotencyTTLVariable, "600")
+
+		cfg, err = config.Load(nil)
+
+		assert.Nil(t, err, fmt.Sprintf("We expected no error but got %v", err))
+		assert.Equal(t, 10*time.Minute, cfg.Limits.IdempotencyTTL.Duration, fmt.Sprintf("We expected a TTL of 10m but got %s", cfg.Limits.IdempotencyTTL))
+
+		t.Setenv(config.DeprecatedIdempotencyTTLVariable, "24h")
+
+		_, err = config.Load(nil)
+		assert.ErrorContains(t, err, config.DeprecatedIdempotencyTTLVariable, "We expected durations to be rejected in the former variable")
+
+		// Verifying the new variable wins over the former one
+		t.Setenv("IDEMPOTENCY_TTL", "1h")
+
+		cfg, err = config.Load(nil)
+
+		assert.Nil(t, err, fmt.Sprintf("We expected no error but got %v", err))
+		assert.Equal(t, time.Hour, cfg.Limits.IdempotencyTTL.Duration, fmt.Sprintf("We expected a TTL of 1h but got %s", cfg.Limits.IdempotencyTTL))
+	})
+
+	t.Run("Rejecting unknown keys", func(t *testing.T) {
+		clearEnv(t)
+
+		for name, content := range map[string]string{
+			"config.yaml": "server:\n  prot: \"9000\"\n",
+			"config.toml": "[server]\nprot = \"9000\"\n",
+		} {
+			_, err := config.Load([]string{"-config", writeConfig(t, name, content)})
+			assert.NotNil(t, err, fmt.Sprintf("We expected an error for the unknown key of %s", name))
+		}
+	})
+
+	t.Run("Rejecting unsupported files", func(t *testing.T) {
+		clearEnv(t)
+
+		_, err := config.Load([]string{"-config", writeConfig(t, "config.json", "{}")})
+		assert.NotNil(t, err, "We expected an error for a JSON config file")
+
+		_, err = config.Load([]string{"-config", filepath.Join(t.TempDir(), "missing.yaml")})
+		assert.NotNil(t, err, "We expected an error for a missing config file")
+	})
+
+	t.Run("Rejecting invalid values", func(t *testing.T) {
+		clearEnv(t)
+		t.Setenv("DECK_IDLE_TTL", "soon")
+
+		_, err := config.Load(nil)
+		assert.ErrorContains(t, err, "DECK_IDLE_TTL", "We expected the error to name the variable")
+
+		t.Setenv("DECK_IDLE_TTL", "")
+		_, err = config.Load([]string{"-draw-burst", "many"})
+		assert.NotNil(t, err, "We expected an error for an invalid flag")
+	})
+
+	t.Run("Requesting the usage", func(t *testing.T) {
+		clearEnv(t)
+
+		_, err := config.Load([]string{"-h"})
+		assert.True(t, errors.Is(err, flag.ErrHelp), fmt.Sprintf("We expected flag.ErrHelp but got %v", err))
+	})
+}
+
+func TestValidateConfig(t *testing.T) {
+	tests := []struct {
+		name     string
+		modify   func(cfg *config.Config)
+		expected string
+	}{
+		{"Invalid port", func(cfg *config.Config) { cfg.Server.Port = "http" }, "server.port should be a port"},
+		{"Same ports", func(cfg *config.Config) { cfg.Server.GRPCPort = cfg.Server.Port }, "server.grpcPort should be different"},
+		{"Invalid mode", func(cfg *config.Config) { cfg.Server.Mode = "production" }, "server.mode should be one of"},
+		{"Invalid log level", func(cfg *config.Config) { cfg.Server.LogLevel = "verbose" }, "server.logLevel should be one of"},
+		{"Unknown storage backend", func(cfg *config.Config) { cfg.Storage.Backend = "redis" }, "storage.backend should be memory"},
+		{"Negative duration", func(cfg *config.Config) { cfg.Decks.MaxAge = config.Duration{Duration: -time.Second} }, "decks.maxAge can not be negative"},
+		{"Negative limit", func(cfg *config.Config) { cfg.Limits.DrawBurst = -1 }, "limits.drawBurst can not be negative"},
+		{"Unknown tracing exporter", func(cfg *config.Config) { cfg.Tracing.Exporter = "jaeger" }, "tracing.exporter should be one of"},
+		{"Invalid OTLP endpoint", func(cfg *config.Config) {
+			cfg.Tracing.Exporter = "otlp"
+			cfg.Tracing.Endpoint = "localhost:4318"
+		}, "tracing.endpoint should be an http or https URL"},
+		{"Player tokens without API keys", func(cfg *config.Config) { cfg.Auth.JWTSigningKeyFile = "jwt.key" }, "auth.jwtSigningKeyFile requires auth.apiKeysFile"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cfg := config.Default()
+			test.modify(&cfg)
+
+			err := cfg.Validate()
+			assert.ErrorContains(t, err, test.expected, fmt.Sprintf("We expected the error to contain %q", test.expected))
+		})
+	}
+
+	t.Run("Reporting every problem at once", func(t *testing.T) {
+		cfg := config.Default()
+		cfg.Server.Port = ""
+		cfg.Server.Mode = ""
+
+		err := cfg.Validate()
+		assert.ErrorContains(t, err, "server.port", "We expected the port to be reported")
+		assert.ErrorContains(t, err, "server.mode", "We expected the mode to be reported")
+	})
+}
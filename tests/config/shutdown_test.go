@@ -0,0 +1,167 @@
+package config_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/varadekd/card-game/config"
+	"github.com/varadekd/card-game/controller"
+	"github.com/varadekd/card-game/deckpb"
+	"github.com/varadekd/card-game/gqlapi"
+	"github.com/varadekd/card-game/helper"
+	"github.com/varadekd/card-game/store"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+// listen serves on a free local port and returns its address.
+func listen(t *testing.T, serve func(net.Listener)) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+
+	if err != nil {
+		t.Fatalf("Test execution failed because no port could be listened on. Err: %s", err.Error())
+	}
+
+	go serve(listener)
+	return listener.Addr().String()
+}
+
+// The shutdown closes the deck events for good, so it is the last test of the package.
+func TestShutdown(t *testing.T) {
+	cfg := config.Default()
+	cfg.Server.Mode = gin.TestMode
+	cfg.Server.ShutdownTimeout = config.Duration{Duration: 5 * time.Second}
+	cfg.Decks.CardsFile, _ = helper.CardsFile()
+	cfg.Storage.SnapshotFile = filepath.Join(t.TempDir(), "decks.json")
+
+	deckStore := controller.NewMemoryStore(cfg.ExpiryPolicy())
+	previous := controller.Store()
+	controller.UseStore(deckStore)
+	defer controller.UseStore(previous)
+
+	helper.GenerateDefaultDeck()
+	router := config.NewRouter(cfg)
+
+	// A slow request is still in flight when the shutdown starts
+	started := make(chan struct{})
+	router.GET("/slow", func(c *gin.Context) {
+		close(started)
+		time.Sleep(200 * time.Millisecond)
+		c.Status(http.StatusOK)
+	})
+
+	httpServer := &http.Server{Handler: router}
+	httpAddr := listen(t, func(l net.Listener) { httpServer.Serve(l) })
+
+	grpcServer := config.SetupGRPCServer(cfg)
+	grpcAddr := listen(t, func(l net.Listener) { grpcServer.Serve(l) })
+
+	conn, err := grpc.Dial(grpcAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+
+	if err != nil {
+		t.Fatalf("Test execution failed because the connection failed. Err: %s", err.Error())
+	}
+
+	defer conn.Close()
+	client := deckpb.NewDeckServiceClient(conn)
+
+	deck, err := client.CreateDeck(context.Background(), &deckpb.CreateDeckRequest{Cards: []string{"AS", "KH"}})
+
+	if err != nil {
+		t.Fatalf("Test execution failed because the deck was not generated. Err: %s", err.Error())
+	}
+
+	stream, err := client.WatchDeck(context.Background(), &deckpb.WatchDeckRequest{DeckId: deck.Id})
+
+	if err != nil {
+		t.Fatalf("Test execution failed because the deck could not be watched. Err: %s", err.Error())
+	}
+
+	dialer := websocket.Dialer{Subprotocols: []string{gqlapi.Subprotocol}}
+	ws, _, err := dialer.Dial("ws://"+httpAddr+"/graphql", nil)
+
+	if err != nil {
+		t.Fatalf("Test execution failed because the websocket could not be opened. Err: %s", err.Error())
+	}
+
+	defer ws.Close()
+	ws.SetReadDeadline(time.Now().Add(5 * time.Second))
+	ws.WriteJSON(map[string]string{"type": "connection_init"})
+
+	var ack map[string]interface{}
+	ws.ReadJSON(&ack)
+
+	query, _ := json.Marshal(gqlapi.Request{
+		Query:     `subscription($id: ID!) { deckChanged(deckID: $id) { cardsRemaining } }`,
+		Variables: map[string]interface{}{"id": deck.Id},
+	})
+	ws.WriteJSON(map[string]interface{}{"id": "1", "type": "subscribe", "payload": json.RawMessage(query)})
+
+	// Both watchers subscribe in the background, waiting until the deck is watched twice
+	for i := 0; i < 100 && controller.Events().Subscribers(deck.Id) < 2; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	slow := make(chan int, 1)
+
+	go func() {
+		res, err := http.Get("http://" + httpAddr + "/slow")
+
+		if err != nil {
+			slow <- 0
+			return
+		}
+
+		res.Body.Close()
+		slow <- res.StatusCode
+	}()
+
+	<-started
+
+	config.Shutdown(cfg, config.Services{HTTP: httpServer, GRPC: grpcServer, Store: deckStore})
+
+	t.Run("Finishing the requests in flight", func(t *testing.T) {
+		code := <-slow
+		assert.Equal(t, http.StatusOK, code, fmt.Sprintf("We expected http status %d but got %d", http.StatusOK, code))
+	})
+
+	t.Run("Closing the websockets as going away", func(t *testing.T) {
+		var msg map[string]interface{}
+		err := ws.ReadJSON(&msg)
+
+		closeErr, ok := err.(*websocket.CloseError)
+
+		if assert.True(t, ok, fmt.Sprintf("We expected the websocket to be closed but got %v", err)) {
+			assert.Equal(t, websocket.CloseGoingAway, closeErr.Code, fmt.Sprintf("We expected the close code %d but got %d", websocket.CloseGoingAway, closeErr.Code))
+		}
+	})
+
+	t.Run("Ending the gRPC watches as unavailable", func(t *testing.T) {
+		_, err := stream.Recv()
+		assert.Equal(t, codes.Unavailable, status.Code(err), fmt.Sprintf("We expected the code %s but got %s", codes.Unavailable, status.Code(err)))
+	})
+
+	t.Run("Saving the decks to the snapshot file", func(t *testing.T) {
+		restored := store.NewMemoryStore(store.ExpiryPolicy{})
+
+		if err := store.LoadSnapshot(restored, cfg.Storage.SnapshotFile); err != nil {
+			t.Fatalf("Test execution failed because the snapshot could not be loaded. Err: %s", err.Error())
+		}
+
+		saved, err := restored.Get(deck.Id)
+
+		assert.NoError(t, err, "We expected the deck to be saved")
+		assert.Equal(t, 2, saved.CardsRemaining, fmt.Sprintf("We expected 2 cards in the saved deck but found %d", saved.CardsRemaining))
+	})
+}
@@ -0,0 +1,49 @@
+package controller_test
+
+import (
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/varadekd/card-game/controller"
+	"github.com/varadekd/card-game/helper"
+	"github.com/varadekd/card-game/model"
+	"github.com/varadekd/card-game/store"
+)
+
+// benchmarkCreateDeck creates decks from the payload in a fresh store, without any quota.
+func benchmarkCreateDeck(b *testing.B, payload model.GenerateDeckPayload) {
+	if err := helper.GenerateDefaultDeck(); err != nil {
+		b.Fatalf("Unable to generate the default deck: %v", err)
+	}
+
+	previous := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	defer slog.SetDefault(previous)
+
+	controller.UseStore(store.NewMemoryStore(store.ExpiryPolicy{}))
+	controller.SetMaxActiveDecks(0)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := controller.CreateDeck(controller.Caller{}, payload); err != nil {
+			b.Fatalf("Unable to create the deck: %v", err)
+		}
+	}
+}
+
+func BenchmarkCreateDeck(b *testing.B) {
+	b.Run("Default deck", func(b *testing.B) {
+		benchmarkCreateDeck(b, model.GenerateDeckPayload{})
+	})
+
+	b.Run("Shuffled default deck", func(b *testing.B) {
+		benchmarkCreateDeck(b, model.GenerateDeckPayload{Shuffle: true})
+	})
+
+	b.Run("Custom deck", func(b *testing.B) {
+		benchmarkCreateDeck(b, model.GenerateDeckPayload{Cards: []string{"AS", "KD", "QC", "JH", "10S", "2D"}})
+	})
+}
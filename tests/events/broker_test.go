@@ -0,0 +1,78 @@
+package events_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/varadekd/card-game/events"
+	"github.com/varadekd/card-game/model"
+)
+
+func TestBroker(t *testing.T) {
+	deckID := uuid.New()
+
+	t.Run("Publishing to the subscribers of a deck", func(t *testing.T) {
+		broker := events.NewBroker()
+		updates, stop := broker.Subscribe(deckID.String(), 1)
+		defer stop()
+
+		broker.Publish(model.DeckUpdate{DeckID: deckID, CardsRemaining: 51})
+
+		update := <-updates
+		assert.Equal(t, 51, update.CardsRemaining, fmt.Sprintf("We expected 51 cards remaining but got %d", update.CardsRemaining))
+	})
+
+	t.Run("Dropping a subscriber falling behind", func(t *testing.T) {
+		broker := events.NewBroker()
+		updates, stop := broker.Subscribe(deckID.String(), 1)
+		defer stop()
+
+		broker.Publish(model.DeckUpdate{DeckID: deckID})
+		broker.Publish(model.DeckUpdate{DeckID: deckID})
+
+		<-updates
+		_, open := <-updates
+		assert.False(t, open, "We expected the channel to be closed")
+		assert.False(t, broker.Closed(), "We expected the broker to stay open")
+	})
+
+	t.Run("Closing the broker", func(t *testing.T) {
+		broker := events.NewBroker()
+		updates, stop := broker.Subscribe(deckID.String(), 1)
+		defer stop()
+
+		broker.Close()
+
+		_, open := <-updates
+		assert.False(t, open, "We expected the channel of the subscriber to be closed")
+		assert.True(t, broker.Closed(), "We expected the broker to be closed")
+		assert.Equal(t, 0, broker.Subscribers(deckID.String()), "We expected no subscriber left")
+
+		// Verifying the clients subscribing during the shutdown are closed right away
+		late, stopLate := broker.Subscribe(deckID.String(), 1)
+		defer stopLate()
+
+		_, open = <-late
+		assert.False(t, open, "We expected the channel of a late subscriber to be closed")
+	})
+
+	t.Run("Keeping the history of a deck", func(t *testing.T) {
+		broker := events.NewBroker()
+
+		for i := 0; i <= events.HistorySize; i++ {
+			broker.Publish(model.DeckUpdate{DeckID: deckID, Event: model.DeckEvent{Action: model.DeckActionDraw, Round: i}})
+		}
+
+		history := broker.History(deckID.String())
+
+		// Verifying only the latest events are kept, oldest first
+		if assert.Len(t, history, events.HistorySize, fmt.Sprintf("We expected the latest %d events", events.HistorySize)) {
+			assert.Equal(t, 1, history[0].Round, fmt.Sprintf("We expected the oldest event to be dropped but found round %d", history[0].Round))
+		}
+
+		broker.Publish(model.DeckUpdate{DeckID: deckID, Event: model.DeckEvent{Action: model.DeckActionDelete}})
+		assert.Empty(t, broker.History(deckID.String()), "We expected the history to be dropped with the deck")
+	})
+}
@@ -0,0 +1,310 @@
+package gqlapi_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/varadekd/card-game/api"
+	"github.com/varadekd/card-game/auth"
+	"github.com/varadekd/card-game/controller"
+	"github.com/varadekd/card-game/gqlapi"
+	"github.com/varadekd/card-game/helper"
+	"github.com/varadekd/card-game/middleware"
+)
+
+type graphqlResponse struct {
+	Data   map[string]json.RawMessage `json:"data"`
+	Errors []struct {
+		Message    string                 `json:"message"`
+		Extensions map[string]interface{} `json:"extensions"`
+	} `json:"errors"`
+}
+
+type wsMessage struct {
+	ID      string          `json:"id,omitempty"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// setupServer serves the GraphQL routes the way config.SetupRouter registers them.
+func setupServer(keys *auth.KeyStore) *httptest.Server {
+	gin.SetMode(gin.TestMode)
+	helper.GenerateDefaultDeck()
+	helper.RegisterValidators()
+
+	router := gin.New()
+	schema := gqlapi.NewSchema()
+	api.SetupGraphQLSubscriptions(router, schema, keys, nil)
+	router.Use(middleware.Authenticate(keys, nil))
+	api.SetupGraphQLApi(router, schema)
+
+	return httptest.NewServer(router)
+}
+
+func execute(t *testing.T, server *httptest.Server, apiKey, query string, variables map[string]interface{}) graphqlResponse {
+	body, _ := json.Marshal(gqlapi.Request{Query: query, Variables: variables})
+	req, _ := http.NewRequest(http.MethodPost, server.URL+"/graphql", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	if apiKey != "" {
+		req.Header.Set(middleware.APIKeyHeader, apiKey)
+	}
+
+	res, err := http.DefaultClient.Do(req)
+
+	if err != nil {
+		t.Fatalf("Test execution failed because the request failed. Err: %s", err.Error())
+	}
+
+	defer res.Body.Close()
+
+	var response graphqlResponse
+	json.NewDecoder(res.Body).Decode(&response)
+	return response
+}
+
+func connect(t *testing.T, server *httptest.Server) *websocket.Conn {
+	dialer := websocket.Dialer{Subprotocols: []string{gqlapi.Subprotocol}}
+	conn, _, err := dialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/graphql", nil)
+
+	if err != nil {
+		t.Fatalf("Test execution failed because the websocket could not be opened. Err: %s", err.Error())
+	}
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestGraphQL(t *testing.T) {
+	server := setupServer(nil)
+	defer server.Close()
+
+	// Decks stay in the store between runs, every run plays its own game
+	gameID := uuid.NewString()
+
+	created := execute(t, server, "", `mutation($gameID: String, $cards: [String!]) {
+		newDeck(input: {gameID: $gameID, cards: $cards}) { id deckSize cardsRemaining }
+	}`, map[string]interface{}{"gameID": gameID, "cards": []string{"AS", "KH", "10D"}})
+
+	var deck struct {
+		ID             string `json:"id"`
+		DeckSize       int    `json:"deckSize"`
+		CardsRemaining int    `json:"cardsRemaining"`
+	}
+
+	if len(created.Errors) > 0 || json.Unmarshal(created.Data["newDeck"], &deck) != nil {
+		t.Fatalf("Test execution failed because the deck was not generated. Errors: %v", created.Errors)
+	}
+
+	t.Run("Creating a deck", func(t *testing.T) {
+		assert.Equal(t, 3, deck.DeckSize, fmt.Sprintf("We expected a deck of 3 cards but found %d", deck.DeckSize))
+		assert.Equal(t, 3, deck.CardsRemaining, fmt.Sprintf("We expected 3 cards remaining but found %d", deck.CardsRemaining))
+	})
+
+	t.Run("Drawing cards and reading the game", func(t *testing.T) {
+		drawn := execute(t, server, "", `mutation($id: ID!) { drawCards(deckID: $id, count: 2, playerID: "alice") { code } }`,
+			map[string]interface{}{"id": deck.ID})
+
+		assert.Empty(t, drawn.Errors, "We expected the cards to be drawn")
+		assert.JSONEq(t, `[{"code":"AS"},{"code":"10D"}]`, string(drawn.Data["drawCards"]), "We expected AS and 10D to be drawn")
+
+		game := execute(t, server, "", `query($id: String!) { game(id: $id) { decks { id hands { playerID cards { code } } } } }`,
+			map[string]interface{}{"id": gameID})
+
+		assert.Empty(t, game.Errors, "We expected the game to be found")
+		assert.JSONEq(t, `{"decks":[{"id":"`+deck.ID+`","hands":[{"playerID":"alice","cards":[{"code":"AS"},{"code":"10D"}]}]}]}`,
+			string(game.Data["game"]), "We expected the deck of the game with the hand of alice")
+	})
+
+	t.Run("Discarding cards to a pile", func(t *testing.T) {
+		discard := `mutation($id: ID!, $cards: [String!]!) {
+			discardCards(deckID: $id, cards: $cards, pile: "discard", playerID: "alice") {
+				hands { playerID cards { code } }
+				piles { name cards { code } }
+				pile(name: "discard") { cards { code } }
+			}
+		}`
+
+		discarded := execute(t, server, "", discard, map[string]interface{}{"id": deck.ID, "cards": []string{"10D"}})
+
+		// Verifying the card moved from the hand of alice to the pile
+		assert.Empty(t, discarded.Errors, "We expected the card to be discarded")
+		assert.JSONEq(t, `{"hands":[{"playerID":"alice","cards":[{"code":"AS"}]}],"piles":[{"name":"discard","cards":[{"code":"10D"}]}],"pile":{"cards":[{"code":"10D"}]}}`,
+			string(discarded.Data["discardCards"]), "We expected 10D in the discard pile")
+
+		game := execute(t, server, "", `query($id: String!) { game(id: $id) { piles { deckID pile { name cards { code } } } } }`,
+			map[string]interface{}{"id": gameID})
+
+		assert.Empty(t, game.Errors, "We expected the game to be found")
+		assert.JSONEq(t, `{"piles":[{"deckID":"`+deck.ID+`","pile":{"name":"discard","cards":[{"code":"10D"}]}}]}`,
+			string(game.Data["game"]), "We expected the piles of the game")
+
+		// Verifying a card can only be discarded once
+		again := execute(t, server, "", discard, map[string]interface{}{"id": deck.ID, "cards": []string{"10D"}})
+
+		if assert.Len(t, again.Errors, 1, "We expected one error") {
+			assert.Equal(t, helper.ErrCardNotInHand.Code, again.Errors[0].Extensions["code"], fmt.Sprintf("We expected %s but found %v", helper.ErrCardNotInHand.Code, again.Errors[0].Extensions))
+		}
+	})
+
+	t.Run("Reading the recent events of the game", func(t *testing.T) {
+		game := execute(t, server, "", `query($id: String!) { game(id: $id) { recentEvents { deckID event { action } } } }`,
+			map[string]interface{}{"id": gameID})
+
+		// Verifying the draw and the discard are listed, newest first
+		assert.Empty(t, game.Errors, "We expected the game to be found")
+		assert.JSONEq(t, `{"recentEvents":[{"deckID":"`+deck.ID+`","event":{"action":"discard"}},{"deckID":"`+deck.ID+`","event":{"action":"draw"}}]}`,
+			string(game.Data["game"]), "We expected the discard and the draw of the game")
+	})
+
+	t.Run("Errors carry the code of the catalogue", func(t *testing.T) {
+		drawn := execute(t, server, "", `mutation($id: ID!) { drawCards(deckID: $id, count: 5) { code } }`,
+			map[string]interface{}{"id": deck.ID})
+
+		if assert.Len(t, drawn.Errors, 1, "We expected one error") {
+			assert.Equal(t, helper.ErrInsufficientCards.Code, drawn.Errors[0].Extensions["code"], fmt.Sprintf("We expected %s but found %v", helper.ErrInsufficientCards.Code, drawn.Errors[0].Extensions))
+		}
+
+		missing := execute(t, server, "", `{ deck(id: "invalid") { id } }`, nil)
+
+		if assert.Len(t, missing.Errors, 1, "We expected one error") {
+			assert.Equal(t, helper.ErrDeckIDInvalid.Code, missing.Errors[0].Extensions["code"], fmt.Sprintf("We expected %s but found %v", helper.ErrDeckIDInvalid.Code, missing.Errors[0].Extensions))
+		}
+
+		invalid := execute(t, server, "", `{ decks(sort: "size") { nextCursor } }`, nil)
+
+		if assert.Len(t, invalid.Errors, 1, "We expected one error") {
+			assert.Equal(t, helper.ErrInvalidQuery.Code, invalid.Errors[0].Extensions["code"], fmt.Sprintf("We expected %s but found %v", helper.ErrInvalidQuery.Code, invalid.Errors[0].Extensions))
+		}
+	})
+
+	t.Run("Subscribing to the changes of a deck", func(t *testing.T) {
+		conn := connect(t, server)
+		conn.WriteJSON(wsMessage{Type: "connection_init"})
+
+		var msg wsMessage
+		conn.ReadJSON(&msg)
+		assert.Equal(t, "connection_ack", msg.Type, fmt.Sprintf("We expected the connection to be acknowledged but found %s", msg.Type))
+
+		query, _ := json.Marshal(gqlapi.Request{
+			Query:     `subscription($id: ID!) { deckChanged(deckID: $id) { event { action } cardsRemaining } }`,
+			Variables: map[string]interface{}{"id": deck.ID},
+		})
+		conn.WriteJSON(wsMessage{ID: "1", Type: "subscribe", Payload: query})
+
+		conn.WriteJSON(wsMessage{Type: "ping"})
+		conn.ReadJSON(&msg)
+		assert.Equal(t, "pong", msg.Type, fmt.Sprintf("We expected a pong but found %s", msg.Type))
+
+		// The subscription starts in the background, waiting until the deck is watched
+		for i := 0; i < 100 && controller.Events().Subscribers(deck.ID) == 0; i++ {
+			time.Sleep(10 * time.Millisecond)
+		}
+
+		execute(t, server, "", `mutation($id: ID!) { drawCards(deckID: $id, count: 1) { code } }`, map[string]interface{}{"id": deck.ID})
+
+		conn.ReadJSON(&msg)
+		assert.Equal(t, "next", msg.Type, fmt.Sprintf("We expected an update but found %s", msg.Type))
+		assert.Equal(t, "1", msg.ID, "We expected the update of the subscription")
+		assert.JSONEq(t, `{"data":{"deckChanged":{"event":{"action":"draw"},"cardsRemaining":0}}}`, string(msg.Payload), "We expected the draw of the last card")
+	})
+}
+
+func TestGraphQLRateLimits(t *testing.T) {
+	server := setupServer(nil)
+	defer server.Close()
+
+	controller.UseRateLimiters(middleware.NewRateLimiter(1, 1), middleware.NewRateLimiter(1, 1))
+	defer controller.UseRateLimiters(nil, nil)
+
+	created := execute(t, server, "", `mutation { newDeck(input: {cards: ["AS", "KH"]}) { id } }`, nil)
+
+	var deck struct {
+		ID string `json:"id"`
+	}
+
+	if len(created.Errors) > 0 || json.Unmarshal(created.Data["newDeck"], &deck) != nil {
+		t.Fatalf("Test execution failed because the deck was not generated. Errors: %v", created.Errors)
+	}
+
+	t.Run("Creating decks over the rate limit", func(t *testing.T) {
+		limited := execute(t, server, "", `mutation { newDeck(input: {cards: ["AS"]}) { id } }`, nil)
+
+		if assert.Len(t, limited.Errors, 1, "We expected one error") {
+			assert.Equal(t, helper.ErrRateLimited.Code, limited.Errors[0].Extensions["code"], fmt.Sprintf("We expected %s but found %v", helper.ErrRateLimited.Code, limited.Errors[0].Extensions))
+		}
+	})
+
+	t.Run("Drawing cards over the rate limit", func(t *testing.T) {
+		draw := `mutation($id: ID!) { drawCards(deckID: $id, count: 1) { code } }`
+
+		drawn := execute(t, server, "", draw, map[string]interface{}{"id": deck.ID})
+		assert.Empty(t, drawn.Errors, "We expected the first draw to succeed")
+
+		limited := execute(t, server, "", draw, map[string]interface{}{"id": deck.ID})
+
+		if assert.Len(t, limited.Errors, 1, "We expected one error") {
+			assert.Equal(t, helper.ErrRateLimited.Code, limited.Errors[0].Extensions["code"], fmt.Sprintf("We expected %s but found %v", helper.ErrRateLimited.Code, limited.Errors[0].Extensions))
+		}
+	})
+}
+
+func TestGraphQLAuthentication(t *testing.T) {
+	keys, _ := auth.LoadKeyStore(filepath.Join(t.TempDir(), "keys.json"))
+	_, plainKey, _ := keys.Mint("game-server")
+	_, otherKey, _ := keys.Mint("other-server")
+
+	server := setupServer(keys)
+	defer server.Close()
+
+	t.Run("Queries need an API key", func(t *testing.T) {
+		res, _ := http.Post(server.URL+"/graphql", "application/json", strings.NewReader(`{"query":"{ decks { nextCursor } }"}`))
+		assert.Equal(t, http.StatusUnauthorized, res.StatusCode, fmt.Sprintf("We expected 401 but found %d", res.StatusCode))
+	})
+
+	t.Run("Decks of other keys are forbidden", func(t *testing.T) {
+		created := execute(t, server, plainKey, `mutation { newDeck(input: {}) { id } }`, nil)
+
+		var deck struct {
+			ID string `json:"id"`
+		}
+		json.Unmarshal(created.Data["newDeck"], &deck)
+
+		other := execute(t, server, otherKey, `query($id: ID!) { deck(id: $id) { id } }`, map[string]interface{}{"id": deck.ID})
+
+		if assert.Len(t, other.Errors, 1, "We expected one error") {
+			assert.Equal(t, helper.ErrDeckForbidden.Code, other.Errors[0].Extensions["code"], fmt.Sprintf("We expected %s but found %v", helper.ErrDeckForbidden.Code, other.Errors[0].Extensions))
+		}
+	})
+
+	t.Run("Subscriptions authenticate on connection_init", func(t *testing.T) {
+		conn := connect(t, server)
+		conn.WriteJSON(wsMessage{Type: "connection_init", Payload: json.RawMessage(`{"X-API-Key":"invalid"}`)})
+
+		_, _, err := conn.ReadMessage()
+		closeErr, ok := err.(*websocket.CloseError)
+
+		if assert.True(t, ok, fmt.Sprintf("We expected the connection to be closed but found %v", err)) {
+			assert.Equal(t, 4403, closeErr.Code, fmt.Sprintf("We expected the close code 4403 but found %d", closeErr.Code))
+		}
+
+		conn = connect(t, server)
+		conn.WriteJSON(wsMessage{Type: "connection_init", Payload: json.RawMessage(`{"X-API-Key":"` + plainKey + `"}`)})
+
+		var msg wsMessage
+		conn.ReadJSON(&msg)
+		assert.Equal(t, "connection_ack", msg.Type, fmt.Sprintf("We expected the connection to be acknowledged but found %s", msg.Type))
+	})
+}
@@ -0,0 +1,280 @@
+package grpcapi_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/varadekd/card-game/auth"
+	"github.com/varadekd/card-game/controller"
+	"github.com/varadekd/card-game/deckpb"
+	"github.com/varadekd/card-game/grpcapi"
+	"github.com/varadekd/card-game/helper"
+	"github.com/varadekd/card-game/middleware"
+	"github.com/varadekd/card-game/model"
+	"github.com/varadekd/card-game/store"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// dial serves the deck service on an in-memory listener and returns a client for it.
+func dial(t *testing.T, keys *auth.KeyStore) deckpb.DeckServiceClient {
+	listener := bufconn.Listen(1024 * 1024)
+	server := grpcapi.NewServer(keys, nil)
+
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.DialContext(context.Background(), "bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+
+	if err != nil {
+		t.Fatalf("Test execution failed because the connection failed. Err: %s", err.Error())
+	}
+
+	t.Cleanup(func() { conn.Close() })
+	return deckpb.NewDeckServiceClient(conn)
+}
+
+func TestDeckService(t *testing.T) {
+	// Generating default card deck to ensure default deck is generated
+	helper.GenerateDefaultDeck()
+	helper.RegisterValidators()
+
+	client := dial(t, nil)
+	ctx := context.Background()
+
+	deck, err := client.CreateDeck(ctx, &deckpb.CreateDeckRequest{Cards: []string{"AS", "KH", "10D"}})
+
+	if err != nil {
+		t.Fatalf("Test execution failed because the deck was not generated. Err: %s", err.Error())
+	}
+
+	t.Run("Creating and opening a deck", func(t *testing.T) {
+		assert.Equal(t, int32(3), deck.GetDeckSize(), fmt.Sprintf("We expected a deck of 3 cards but found %d", deck.GetDeckSize()))
+
+		opened, err := client.OpenDeck(ctx, &deckpb.OpenDeckRequest{DeckId: deck.GetId()})
+
+		assert.Nil(t, err, "We expected the deck to be opened")
+		assert.Equal(t, deck.GetId(), opened.GetId(), "We expected the same deck to be opened")
+	})
+
+	t.Run("Watching the draws of a deck", func(t *testing.T) {
+		watchCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+
+		stream, err := client.WatchDeck(watchCtx, &deckpb.WatchDeckRequest{DeckId: deck.GetId()})
+
+		if err != nil {
+			t.Fatalf("Test execution failed because the deck could not be watched. Err: %s", err.Error())
+		}
+
+		// Waiting for the header so the watcher is subscribed before drawing
+		stream.Header()
+
+		drawn, err := client.DrawCards(ctx, &deckpb.DrawCardsRequest{DeckId: deck.GetId(), CardsToBeDrawn: 2})
+
+		if assert.Nil(t, err, "We expected the cards to be drawn") {
+			assert.Len(t, drawn.GetCards(), 2, "We expected two cards")
+			assert.Equal(t, "AS", drawn.GetCards()[0].GetCode(), "We expected the first card to be AS")
+		}
+
+		update, err := stream.Recv()
+
+		if assert.Nil(t, err, "We expected an update of the deck") {
+			assert.Equal(t, model.DeckActionDraw, update.GetEvent().GetAction(), "We expected a draw update")
+			assert.Equal(t, int32(1), update.GetCardsRemaining(), fmt.Sprintf("We expected one card remaining but found %d", update.GetCardsRemaining()))
+		}
+	})
+
+	t.Run("Errors are mapped to gRPC codes", func(t *testing.T) {
+		_, err := client.DrawCards(ctx, &deckpb.DrawCardsRequest{DeckId: deck.GetId(), CardsToBeDrawn: 5})
+		assert.Equal(t, codes.FailedPrecondition, status.Code(err), fmt.Sprintf("We expected a failed precondition but found %v", err))
+
+		_, err = client.DrawCards(ctx, &deckpb.DrawCardsRequest{DeckId: deck.GetId(), CardsToBeDrawn: 0})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err), fmt.Sprintf("We expected an invalid argument but found %v", err))
+
+		_, err = client.OpenDeck(ctx, &deckpb.OpenDeckRequest{DeckId: "invalid"})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err), fmt.Sprintf("We expected an invalid argument but found %v", err))
+
+		_, err = client.CreateDeck(ctx, &deckpb.CreateDeckRequest{Cards: []string{"1X"}})
+		assert.Contains(t, status.Convert(err).Message(), helper.ErrInvalidCardCode.Code, "We expected the error code in the message")
+	})
+
+	t.Run("Drawing from the expected version of a deck", func(t *testing.T) {
+		opened, err := client.OpenDeck(ctx, &deckpb.OpenDeckRequest{DeckId: deck.GetId()})
+
+		if err != nil {
+			t.Fatalf("Test execution failed because the deck could not be opened. Err: %s", err.Error())
+		}
+
+		// Verifying the draw made since the creation bumped the version
+		assert.Greater(t, opened.GetVersion(), deck.GetVersion(), "We expected the version of the deck to increase")
+
+		_, err = client.DrawCards(ctx, &deckpb.DrawCardsRequest{DeckId: deck.GetId(), CardsToBeDrawn: 1, ExpectedVersion: deck.GetVersion()})
+		assert.Equal(t, codes.FailedPrecondition, status.Code(err), fmt.Sprintf("We expected a failed precondition but found %v", err))
+		assert.Contains(t, status.Convert(err).Message(), helper.ErrDeckModified.Code, "We expected the error code in the message")
+
+		_, err = client.DrawCards(ctx, &deckpb.DrawCardsRequest{DeckId: deck.GetId(), CardsToBeDrawn: 1, ExpectedVersion: opened.GetVersion()})
+		assert.Nil(t, err, "We expected the card to be drawn from the current version")
+	})
+}
+
+func TestWatchDeckEnds(t *testing.T) {
+	helper.GenerateDefaultDeck()
+	helper.RegisterValidators()
+
+	// The decks are created at the current time, the store is moved forward to expire them
+	var ahead time.Duration
+	memoryStore := controller.NewMemoryStore(store.ExpiryPolicy{IdleTTL: time.Hour})
+	memoryStore.Now = func() time.Time { return time.Now().Add(ahead) }
+
+	defaultStore := controller.Store()
+	controller.UseStore(memoryStore)
+	defer controller.UseStore(defaultStore)
+
+	client := dial(t, nil)
+	ctx := context.Background()
+
+	// watch creates a deck of the game and returns its ID with a stream watching it
+	watch := func(t *testing.T, gameID string) (string, deckpb.DeckService_WatchDeckClient) {
+		deck, err := client.CreateDeck(ctx, &deckpb.CreateDeckRequest{GameId: gameID})
+
+		if err != nil {
+			t.Fatalf("Test execution failed because the deck was not generated. Err: %s", err.Error())
+		}
+
+		watchCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		t.Cleanup(cancel)
+
+		stream, err := client.WatchDeck(watchCtx, &deckpb.WatchDeckRequest{DeckId: deck.GetId()})
+
+		if err != nil {
+			t.Fatalf("Test execution failed because the deck could not be watched. Err: %s", err.Error())
+		}
+
+		// Waiting for the header so the watcher is subscribed before the deck is removed
+		stream.Header()
+		return deck.GetId(), stream
+	}
+
+	// expectLast checks the stream sends the last update with the action and then ends
+	expectLast := func(t *testing.T, stream deckpb.DeckService_WatchDeckClient, action string) {
+		update, err := stream.Recv()
+
+		if assert.Nil(t, err, "We expected the last update of the deck") {
+			assert.Equal(t, action, update.GetEvent().GetAction(), fmt.Sprintf("We expected the %s update but found %s", action, update.GetEvent().GetAction()))
+		}
+
+		_, err = stream.Recv()
+		assert.Equal(t, io.EOF, err, fmt.Sprintf("We expected the stream to end but found %v", err))
+	}
+
+	t.Run("Ending the watch when the decks of the game are deleted", func(t *testing.T) {
+		_, stream := watch(t, "bulk-delete")
+
+		deleted, err := controller.DeleteGame(controller.Caller{}, "bulk-delete")
+		assert.Nil(t, err, "We expected the decks of the game to be deleted")
+		assert.Equal(t, 1, deleted, fmt.Sprintf("We expected 1 deck to be deleted but got %d", deleted))
+
+		expectLast(t, stream, model.DeckActionDelete)
+	})
+
+	t.Run("Ending the watch when the deck is swept", func(t *testing.T) {
+		_, stream := watch(t, "swept")
+
+		ahead = 2 * time.Hour
+		defer func() { ahead = 0 }()
+		memoryStore.Sweep()
+
+		expectLast(t, stream, model.DeckActionExpire)
+	})
+
+	t.Run("Ending the watch when the deck expires on lookup", func(t *testing.T) {
+		deckID, stream := watch(t, "looked-up")
+
+		ahead = 2 * time.Hour
+		defer func() { ahead = 0 }()
+		_, err := client.OpenDeck(ctx, &deckpb.OpenDeckRequest{DeckId: deckID})
+		assert.Equal(t, codes.NotFound, status.Code(err), fmt.Sprintf("We expected the deck to be gone but found %v", err))
+
+		expectLast(t, stream, model.DeckActionExpire)
+	})
+}
+
+func TestDeckServiceAuthentication(t *testing.T) {
+	helper.GenerateDefaultDeck()
+	helper.RegisterValidators()
+
+	keys, _ := auth.LoadKeyStore(filepath.Join(t.TempDir(), "keys.json"))
+	_, plainKey, _ := keys.Mint("game-server")
+	_, otherKey, _ := keys.Mint("other-server")
+
+	client := dial(t, keys)
+	withKey := func(key string) context.Context {
+		return metadata.AppendToOutgoingContext(context.Background(), grpcapi.APIKeyMetadata, key)
+	}
+
+	t.Run("Calling without an API key", func(t *testing.T) {
+		_, err := client.CreateDeck(context.Background(), &deckpb.CreateDeckRequest{})
+		assert.Equal(t, codes.Unauthenticated, status.Code(err), fmt.Sprintf("We expected unauthenticated but found %v", err))
+	})
+
+	t.Run("Decks are owned by the API key", func(t *testing.T) {
+		deck, err := client.CreateDeck(withKey(plainKey), &deckpb.CreateDeckRequest{})
+
+		if err != nil {
+			t.Fatalf("Test execution failed because the deck was not generated. Err: %s", err.Error())
+		}
+
+		_, err = client.OpenDeck(withKey(otherKey), &deckpb.OpenDeckRequest{DeckId: deck.GetId()})
+		assert.Equal(t, codes.PermissionDenied, status.Code(err), fmt.Sprintf("We expected permission denied but found %v", err))
+	})
+}
+
+func TestDeckServiceRateLimits(t *testing.T) {
+	helper.GenerateDefaultDeck()
+	helper.RegisterValidators()
+
+	controller.UseRateLimiters(middleware.NewRateLimiter(1, 1), middleware.NewRateLimiter(1, 1))
+	defer controller.UseRateLimiters(nil, nil)
+
+	client := dial(t, nil)
+	ctx := context.Background()
+
+	t.Run("Creating decks over the rate limit", func(t *testing.T) {
+		_, err := client.CreateDeck(ctx, &deckpb.CreateDeckRequest{Cards: []string{"AS"}})
+		assert.Nil(t, err, "We expected the first deck to be created")
+
+		// Verifying the second call shares the bucket of the REST route
+		_, err = client.CreateDeck(ctx, &deckpb.CreateDeckRequest{Cards: []string{"AS"}})
+		assert.Equal(t, codes.ResourceExhausted, status.Code(err), fmt.Sprintf("We expected %s but got %s", codes.ResourceExhausted, status.Code(err)))
+		assert.Contains(t, status.Convert(err).Message(), helper.ErrRateLimited.Code, "We expected the code of the catalogue in the message")
+	})
+
+	t.Run("Drawing over the rate limit", func(t *testing.T) {
+		controller.UseRateLimiters(nil, middleware.NewRateLimiter(1, 1))
+		deck, err := client.CreateDeck(ctx, &deckpb.CreateDeckRequest{Cards: []string{"AS", "KH"}})
+
+		if err != nil {
+			t.Fatalf("Test execution failed because the deck was not generated. Err: %s", err.Error())
+		}
+
+		_, err = client.DrawCards(ctx, &deckpb.DrawCardsRequest{DeckId: deck.GetId(), CardsToBeDrawn: 1})
+		assert.Nil(t, err, "We expected the first draw to succeed")
+
+		_, err = client.DrawCards(ctx, &deckpb.DrawCardsRequest{DeckId: deck.GetId(), CardsToBeDrawn: 1})
+		assert.Equal(t, codes.ResourceExhausted, status.Code(err), fmt.Sprintf("We expected %s but got %s", codes.ResourceExhausted, status.Code(err)))
+	})
+}
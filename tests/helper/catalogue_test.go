@@ -0,0 +1,189 @@
+package helper_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/varadekd/card-game/helper"
+	"github.com/varadekd/card-game/model"
+)
+
+// writeCards writes a cards file holding the codes, the suit and value are not checked by the catalogue.
+func writeCards(t *testing.T, path string, codes ...string) {
+	cards := []model.Card{}
+
+	for _, code := range codes {
+		cards = append(cards, model.Card{Value: code[:len(code)-1], Suit: "SPADES", Code: code})
+	}
+
+	data, _ := json.Marshal(cards)
+
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("Unable to write the cards file: %v", err)
+	}
+}
+
+func codesOf(cards []model.Card) []string {
+	codes := []string{}
+
+	for _, card := range cards {
+		codes = append(codes, card.Code)
+	}
+
+	return codes
+}
+
+func TestCatalogue(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cards.json")
+	writeCards(t, path, "AS", "2S", "3S", "4S")
+
+	catalogue, err := helper.LoadCatalogue(context.Background(), path)
+
+	if err != nil {
+		t.Fatalf("Unable to load the catalogue: %v", err)
+	}
+
+	t.Run("Copying the cards", func(t *testing.T) {
+		cards := catalogue.Cards()
+		cards[0].Code = "XX"
+
+		assert.Equal(t, 4, catalogue.Len(), fmt.Sprintf("We expected 4 cards but got %d", catalogue.Len()))
+		assert.Equal(t, "AS", catalogue.Cards()[0].Code, "We expected the catalogue to be left untouched")
+	})
+
+	t.Run("Selecting cards in the order of the file", func(t *testing.T) {
+		selected := catalogue.Select([]string{"4S", "1X", "AS"})
+
+		assert.Equal(t, []string{"AS", "4S"}, codesOf(selected), "We expected the known cards in the order of the file")
+	})
+
+	t.Run("Reading the files written before the code key was fixed", func(t *testing.T) {
+		legacy := filepath.Join(t.TempDir(), "cards.json")
+		os.WriteFile(legacy, []byte(`[{"value":"A","code:":"AS","suit":"SPADES"},{"value":"K","code":"KS","suit":"SPADES"}]`), 0o600)
+
+		loaded, err := helper.LoadCatalogue(context.Background(), legacy)
+
+		// Verifying the code is read from both keys
+		if assert.Nil(t, err, "We expected the legacy file to be accepted") {
+			assert.Equal(t, []string{"AS", "KS"}, codesOf(loaded.Cards()), "We expected the codes of both keys")
+		}
+	})
+
+	t.Run("Rejecting invalid files", func(t *testing.T) {
+		invalid := filepath.Join(t.TempDir(), "cards.json")
+
+		for name, content := range map[string]string{"empty": "[]", "corrupted": "{"} {
+			os.WriteFile(invalid, []byte(content), 0o600)
+
+			_, err := helper.LoadCatalogue(context.Background(), invalid)
+			assert.NotNil(t, err, fmt.Sprintf("We expected an error for the %s file", name))
+		}
+
+		for name, codes := range map[string][]string{"duplicated": {"AS", "AS"}, "unknown": {"AS", "1S"}} {
+			writeCards(t, invalid, codes...)
+
+			_, err := helper.LoadCatalogue(context.Background(), invalid)
+			assert.NotNil(t, err, fmt.Sprintf("We expected an error for the file holding %s cards", name))
+		}
+
+		_, err := helper.LoadCatalogue(context.Background(), filepath.Join(t.TempDir(), "missing.json"))
+		assert.NotNil(t, err, "We expected an error for a missing file")
+	})
+
+	t.Run("Keeping the default catalogue in memory", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "cards.json")
+		writeCards(t, file, "AS", "KS")
+
+		helper.UseCardsFile(file)
+		defer helper.UseCardsFile("")
+
+		first, err := helper.DefaultCatalogue(context.Background())
+
+		if err != nil {
+			t.Fatalf("Unable to load the default catalogue: %v", err)
+		}
+
+		// Verifying the file is not read again
+		os.Remove(file)
+		second, err := helper.DefaultCatalogue(context.Background())
+
+		assert.Nil(t, err, fmt.Sprintf("We expected no error but got %v", err))
+		assert.Same(t, first, second, "We expected the catalogue loaded first")
+	})
+
+	t.Run("Reloading the catalogue when the file changes", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "cards.json")
+		writeCards(t, file, "AS", "KS")
+
+		helper.UseCardsFile(file)
+		defer helper.UseCardsFile("")
+
+		helper.DefaultCatalogue(context.Background())
+
+		watcher := helper.NewCatalogueWatcher(10 * time.Millisecond)
+		watcher.Start()
+		defer watcher.Stop()
+
+		writeCards(t, file, "AS", "KS", "QS")
+
+		assert.Eventually(t, func() bool {
+			current, _ := helper.DefaultCatalogue(context.Background())
+			return current.Len() == 3
+		}, time.Second, 10*time.Millisecond, "We expected the new cards to be loaded")
+
+		// Verifying an invalid file keeps the previous cards
+		os.WriteFile(file, []byte("{"), 0o600)
+		time.Sleep(50 * time.Millisecond)
+
+		current, err := helper.DefaultCatalogue(context.Background())
+
+		assert.Nil(t, err, fmt.Sprintf("We expected no error but got %v", err))
+		assert.Equal(t, 3, current.Len(), "We expected the previous cards to be kept")
+	})
+}
+
+// BenchmarkCatalogue compares reading the cards file for every deck, as decks used to be
+// generated, with copying the cards of the catalogue kept in memory.
+func BenchmarkCatalogue(b *testing.B) {
+	path := filepath.Join(b.TempDir(), "cards.json")
+	helper.UseCardsFile(path)
+	defer helper.UseCardsFile("")
+
+	if err := helper.GenerateDefaultDeck(); err != nil {
+		b.Fatalf("Unable to generate the default deck: %v", err)
+	}
+
+	b.Run("Reading the file", func(b *testing.B) {
+		b.ReportAllocs()
+
+		for i := 0; i < b.N; i++ {
+			catalogue, err := helper.LoadCatalogue(context.Background(), path)
+
+			if err != nil {
+				b.Fatalf("Unable to load the catalogue: %v", err)
+			}
+
+			catalogue.Cards()
+		}
+	})
+
+	b.Run("Copying from memory", func(b *testing.B) {
+		b.ReportAllocs()
+
+		for i := 0; i < b.N; i++ {
+			catalogue, err := helper.DefaultCatalogue(context.Background())
+
+			if err != nil {
+				b.Fatalf("Unable to load the catalogue: %v", err)
+			}
+
+			catalogue.Cards()
+		}
+	})
+}
@@ -0,0 +1,100 @@
+package metrics_test
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/varadekd/card-game/metrics"
+	"github.com/varadekd/card-game/model"
+	"github.com/varadekd/card-game/store"
+)
+
+func exposition(t *testing.T, registry *metrics.Registry) string {
+	var buffer bytes.Buffer
+
+	if _, err := registry.WriteTo(&buffer); err != nil {
+		t.Fatalf("Unable to write the metrics: %v", err)
+	}
+
+	return buffer.String()
+}
+
+func TestRegistry(t *testing.T) {
+	t.Run("Writing counters", func(t *testing.T) {
+		registry := metrics.NewRegistry()
+		counter := registry.NewCounter("decks_total", "Decks created.", "shuffled")
+
+		counter.Inc("true")
+		counter.Add(2, "false")
+
+		expected := "# HELP decks_total Decks created.\n# TYPE decks_total counter\n" +
+			"decks_total{shuffled=\"false\"} 2\ndecks_total{shuffled=\"true\"} 1\n"
+		assert.Equal(t, expected, exposition(t, registry), "We expected the series sorted by label values")
+	})
+
+	t.Run("Writing histograms with cumulative buckets", func(t *testing.T) {
+		registry := metrics.NewRegistry()
+		histogram := registry.NewHistogram("latency_seconds", "Latency.", []float64{0.1, 1}, "route")
+
+		histogram.Observe(0.05, "/deck")
+		histogram.Observe(0.5, "/deck")
+		histogram.Observe(5, "/deck")
+
+		output := exposition(t, registry)
+
+		for _, line := range []string{
+			`latency_seconds_bucket{route="/deck",le="0.1"} 1`,
+			`latency_seconds_bucket{route="/deck",le="1"} 2`,
+			`latency_seconds_bucket{route="/deck",le="+Inf"} 3`,
+			`latency_seconds_sum{route="/deck"} 5.55`,
+			`latency_seconds_count{route="/deck"} 3`,
+		} {
+			assert.Contains(t, output, line+"\n", fmt.Sprintf("We expected the line %s", line))
+		}
+	})
+
+	t.Run("Writing gauges", func(t *testing.T) {
+		registry := metrics.NewRegistry()
+		gauge := registry.NewGaugeFunc("active_decks", "Active decks.", nil)
+
+		assert.Contains(t, exposition(t, registry), "active_decks 0\n", "We expected a gauge without function to read 0")
+
+		gauge.Set(func() float64 { return 7 })
+		assert.Contains(t, exposition(t, registry), "active_decks 7\n", "We expected the value of the function")
+	})
+
+	t.Run("Escaping label values", func(t *testing.T) {
+		registry := metrics.NewRegistry()
+		registry.NewCounter("escaped_total", "Escaped.", "value").Inc("a\"b\\c\nd")
+
+		assert.Contains(t, exposition(t, registry), `escaped_total{value="a\"b\\c\nd"} 1`, "We expected the quotes, backslashes and line feeds to be escaped")
+	})
+
+	t.Run("Rejecting a wrong number of label values", func(t *testing.T) {
+		registry := metrics.NewRegistry()
+		counter := registry.NewCounter("labelled_total", "Labelled.", "a", "b")
+
+		assert.Panics(t, func() { counter.Inc("only one") }, "We expected a panic for a missing label value")
+	})
+}
+
+func TestInstrumentStore(t *testing.T) {
+	s := metrics.InstrumentStore(store.NewMemoryStore(store.ExpiryPolicy{IdleTTL: time.Hour}))
+	deck := model.Deck{ID: uuid.New(), CreatedAt: time.Now()}
+
+	creates := metrics.StoreDuration.Count("create")
+	gets := metrics.StoreDuration.Count("get")
+
+	s.Create(deck)
+	s.Get(deck.ID.String())
+	s.Get(deck.ID.String())
+
+	assert.Equal(t, creates+1, metrics.StoreDuration.Count("create"), "We expected the create to be recorded")
+	assert.Equal(t, gets+2, metrics.StoreDuration.Count("get"), "We expected both gets to be recorded")
+	assert.True(t, strings.Contains(exposition(t, metrics.Default), `card_game_store_operation_duration_seconds_count{operation="get"}`), "We expected the store latency to be exposed")
+}
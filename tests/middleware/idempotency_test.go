@@ -0,0 +1,93 @@
+package middleware_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/varadekd/card-game/middleware"
+)
+
+func TestIdempotency(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := middleware.NewIdempotencyStore(time.Hour)
+	store.Now = func() time.Time { return now }
+
+	var calls int32
+	status := http.StatusOK
+	release := make(chan struct{})
+	close(release)
+
+	router := gin.New()
+	router.POST("/run", middleware.Idempotency(store), func(c *gin.Context) {
+		<-release
+		n := atomic.AddInt32(&calls, 1)
+		c.String(status, "call %d", n)
+	})
+
+	send := func(key, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/run", strings.NewReader(body))
+		req.Header.Set(middleware.IdempotencyKeyHeader, key)
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("Responses are replayed until the TTL expires", func(t *testing.T) {
+		first := send("key-1", "payload")
+		second := send("key-1", "payload")
+
+		assert.Equal(t, "call 1", second.Body.String(), fmt.Sprintf("We expected the first call to be replayed but got %s", second.Body.String()))
+		assert.Equal(t, "", first.Header().Get(middleware.IdempotentReplayHeader), "We expected the first response not to be a replay")
+
+		now = now.Add(time.Hour)
+		third := send("key-1", "payload")
+		assert.Equal(t, "call 2", third.Body.String(), fmt.Sprintf("We expected the handler to run again after the TTL but got %s", third.Body.String()))
+	})
+
+	t.Run("Server errors are not kept", func(t *testing.T) {
+		status = http.StatusInternalServerError
+		send("key-2", "payload")
+
+		status = http.StatusOK
+		retried := send("key-2", "payload")
+		assert.Equal(t, "call 4", retried.Body.String(), fmt.Sprintf("We expected the handler to run again after an error but got %s", retried.Body.String()))
+	})
+
+	t.Run("Concurrent requests wait for the first one", func(t *testing.T) {
+		release = make(chan struct{})
+		bodies := make([]string, 3)
+
+		var wg sync.WaitGroup
+
+		for i := range bodies {
+			wg.Add(1)
+
+			go func(i int) {
+				defer wg.Done()
+				bodies[i] = send("key-3", "payload").Body.String()
+			}(i)
+		}
+
+		time.Sleep(20 * time.Millisecond)
+		close(release)
+		wg.Wait()
+
+		for i, body := range bodies {
+			assert.Equal(t, "call 5", body, fmt.Sprintf("We expected request %d to get the first response but got %s", i+1, body))
+		}
+	})
+
+	t.Run("Zero TTL disables idempotency keys", func(t *testing.T) {
+		assert.Nil(t, middleware.NewIdempotencyStore(0), "We expected no store")
+	})
+}
@@ -0,0 +1,186 @@
+package middleware_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/varadekd/card-game/auth"
+	"github.com/varadekd/card-game/helper"
+	"github.com/varadekd/card-game/logging"
+	"github.com/varadekd/card-game/middleware"
+)
+
+// captureLogs makes the default logger write JSON lines to the returned buffer for the test.
+func captureLogs(t *testing.T) *bytes.Buffer {
+	var buffer bytes.Buffer
+	previous := slog.Default()
+
+	slog.SetDefault(logging.New(&buffer, slog.LevelDebug))
+	t.Cleanup(func() { slog.SetDefault(previous) })
+
+	return &buffer
+}
+
+// logLines decodes every JSON line written to the buffer.
+func logLines(t *testing.T, buffer *bytes.Buffer) []map[string]interface{} {
+	lines := []map[string]interface{}{}
+
+	for _, line := range strings.Split(strings.TrimSpace(buffer.String()), "\n") {
+		if line == "" {
+			continue
+		}
+
+		entry := map[string]interface{}{}
+
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("We expected a JSON log line but got %s", line)
+		}
+
+		lines = append(lines, entry)
+	}
+
+	return lines
+}
+
+func newLoggingRouter() *gin.Engine {
+	router := gin.New()
+	router.Use(middleware.RequestID(), middleware.AccessLog(), middleware.Recovery())
+
+	router.GET("/deck/:id", func(c *gin.Context) {
+		middleware.Logger(c).Info("Handling the request", "deck_id", c.Param("id"))
+		c.Status(http.StatusOK)
+	})
+	router.GET("/panic", func(c *gin.Context) {
+		panic("something broke")
+	})
+
+	return router
+}
+
+func TestRequestID(t *testing.T) {
+	router := newLoggingRouter()
+
+	t.Run("Propagating the request ID of the client", func(t *testing.T) {
+		logs := captureLogs(t)
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/deck/42", nil)
+		req.Header.Set(middleware.RequestIDHeader, "req-123")
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, "req-123", w.Header().Get(middleware.RequestIDHeader), "We expected the request ID to be sent back")
+
+		lines := logLines(t, logs)
+		assert.Len(t, lines, 2, "We expected the line of the handler and the access log")
+
+		for _, line := range lines {
+			assert.Equal(t, "req-123", line["request_id"], fmt.Sprintf("We expected every line to carry the request ID but got %v", line))
+		}
+
+		assert.Equal(t, "42", lines[0]["deck_id"], "We expected the handler to log the deck ID")
+	})
+
+	t.Run("Generating a request ID", func(t *testing.T) {
+		for _, sent := range []string{"", "bad id\nwith a line feed", strings.Repeat("a", 129)} {
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", "/deck/42", nil)
+			req.Header.Set(middleware.RequestIDHeader, sent)
+
+			router.ServeHTTP(w, req)
+
+			id := w.Header().Get(middleware.RequestIDHeader)
+			assert.NotEmpty(t, id, "We expected a request ID to be generated")
+			assert.NotEqual(t, sent, id, fmt.Sprintf("We expected the request ID %q to be replaced", sent))
+		}
+	})
+}
+
+func TestAccessLog(t *testing.T) {
+	router := newLoggingRouter()
+
+	t.Run("Logging the request as JSON", func(t *testing.T) {
+		logs := captureLogs(t)
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/deck/42", nil))
+
+		lines := logLines(t, logs)
+		access := lines[len(lines)-1]
+
+		assert.Equal(t, "Request served", access["msg"], "We expected the access log to be the last line")
+		assert.Equal(t, "GET", access["method"], "We expected the method")
+		assert.Equal(t, "/deck/:id", access["route"], "We expected the route template")
+		assert.Equal(t, "/deck/42", access["path"], "We expected the path")
+		assert.EqualValues(t, http.StatusOK, access["status"], "We expected the status")
+		assert.Contains(t, access, "duration_ms", "We expected the duration")
+	})
+
+	t.Run("Recovering from a panic", func(t *testing.T) {
+		logs := captureLogs(t)
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/panic", nil))
+
+		assert.Equal(t, http.StatusInternalServerError, w.Code, fmt.Sprintf("We expected http status %d but got %d", http.StatusInternalServerError, w.Code))
+
+		lines := logLines(t, logs)
+		assert.Equal(t, "Recovered from a panic", lines[0]["msg"], "We expected the panic to be logged")
+		assert.Equal(t, "something broke", lines[0]["panic"], "We expected the value of the panic")
+		assert.Equal(t, "ERROR", lines[len(lines)-1]["level"], "We expected the access log of a 500 at error level")
+	})
+}
+
+func TestRejectionLogs(t *testing.T) {
+	keys, _ := auth.LoadKeyStore(filepath.Join(t.TempDir(), "keys.json"))
+	key, plainKey, _ := keys.Mint("game-server")
+
+	router := gin.New()
+	router.Use(middleware.RequestID(), middleware.Authenticate(keys, nil))
+	router.GET("/peek", middleware.RequireDealer("dealer-secret"), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	// rejection sends the request and returns the log line of its rejection
+	rejection := func(t *testing.T, headers map[string]string) map[string]interface{} {
+		logs := captureLogs(t)
+		req := httptest.NewRequest("GET", "/peek", nil)
+		req.Header.Set(middleware.RequestIDHeader, "rejected-1")
+
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
+
+		router.ServeHTTP(httptest.NewRecorder(), req)
+
+		lines := logLines(t, logs)
+
+		if len(lines) != 1 {
+			t.Fatalf("We expected one log line but got %d", len(lines))
+		}
+
+		return lines[0]
+	}
+
+	t.Run("Logging rejected credentials", func(t *testing.T) {
+		line := rejection(t, nil)
+
+		assert.Equal(t, "Rejected the credentials of the request", line["msg"], "We expected the rejection to be logged")
+		assert.Equal(t, helper.ErrAPIKeyMissing.Code, line["error_code"], "We expected the code of the error")
+		assert.Equal(t, "rejected-1", line["request_id"], "We expected the request ID")
+	})
+
+	t.Run("Logging callers who are not the dealer", func(t *testing.T) {
+		line := rejection(t, map[string]string{middleware.APIKeyHeader: plainKey})
+
+		assert.Equal(t, helper.ErrDealerOnly.Code, line["error_code"], "We expected the code of the error")
+		assert.Equal(t, key.ID, line["key_id"], "We expected the ID of the API key")
+		assert.Equal(t, "rejected-1", line["request_id"], "We expected the request ID")
+	})
+}
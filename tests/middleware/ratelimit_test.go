@@ -0,0 +1,43 @@
+package middleware_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/varadekd/card-game/middleware"
+)
+
+func TestRateLimiter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	limiter := middleware.NewRateLimiter(60, 3)
+	limiter.Now = func() time.Time { return now }
+
+	t.Run("Allowing a burst and then limiting", func(t *testing.T) {
+		for i := 0; i < 3; i++ {
+			allowed, remaining, _ := limiter.Allow("client-1")
+			assert.True(t, allowed, fmt.Sprintf("We expected request %d to be allowed", i+1))
+			assert.Equal(t, 2-i, remaining, fmt.Sprintf("We expected %d tokens left but got %d", 2-i, remaining))
+		}
+
+		allowed, _, retryAfter := limiter.Allow("client-1")
+		assert.False(t, allowed, "We expected the request over the burst to be limited")
+		assert.Equal(t, time.Second, retryAfter, fmt.Sprintf("We expected to retry after 1s but got %s", retryAfter))
+	})
+
+	t.Run("Clients have separate buckets", func(t *testing.T) {
+		allowed, _, _ := limiter.Allow("client-2")
+		assert.True(t, allowed, "We expected another client to be allowed")
+	})
+
+	t.Run("Tokens are refilled over time", func(t *testing.T) {
+		now = now.Add(time.Second)
+		allowed, _, _ := limiter.Allow("client-1")
+		assert.True(t, allowed, "We expected a token to be refilled after a second")
+	})
+
+	t.Run("Zero rate disables the limiter", func(t *testing.T) {
+		assert.Nil(t, middleware.NewRateLimiter(0, 10), "We expected no limiter")
+	})
+}
@@ -0,0 +1,375 @@
+package store_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/varadekd/card-game/model"
+	"github.com/varadekd/card-game/store"
+)
+
+// newTestStore returns a memory store whose clock is controlled by the returned pointer.
+func newTestStore(policy store.ExpiryPolicy) (*store.MemoryStore, *time.Time) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := store.NewMemoryStore(policy)
+	s.Now = func() time.Time { return now }
+	return s, &now
+}
+
+func TestExpiryPolicy(t *testing.T) {
+	policy := store.ExpiryPolicy{IdleTTL: time.Hour, MaxAge: 24 * time.Hour}
+	createdAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("Deck never used expires after idle TTL from creation", func(t *testing.T) {
+		deck := model.Deck{CreatedAt: createdAt}
+
+		assert.False(t, policy.Expired(deck, createdAt.Add(59*time.Minute)), "We expected the deck to be active before the idle TTL")
+		assert.True(t, policy.Expired(deck, createdAt.Add(time.Hour)), "We expected the deck to expire once the idle TTL is reached")
+	})
+
+	t.Run("Deck in use expires after max age", func(t *testing.T) {
+		deck := model.Deck{CreatedAt: createdAt, DeckLastUsed: createdAt.Add(24*time.Hour - time.Minute)}
+
+		assert.False(t, policy.Expired(deck, createdAt.Add(24*time.Hour-time.Second)), "We expected the deck to be active before max age")
+		assert.True(t, policy.Expired(deck, createdAt.Add(24*time.Hour)), "We expected the deck to expire once max age is reached")
+	})
+
+	t.Run("Zero durations disable the checks", func(t *testing.T) {
+		deck := model.Deck{CreatedAt: createdAt}
+
+		assert.False(t, store.ExpiryPolicy{}.Expired(deck, createdAt.Add(1000*time.Hour)), "We expected the deck to never expire")
+	})
+}
+
+func TestMemoryStore(t *testing.T) {
+	s, now := newTestStore(store.ExpiryPolicy{IdleTTL: time.Hour, GracePeriod: 10 * time.Minute})
+
+	deck := model.Deck{ID: uuid.New(), CreatedAt: *now, PlayingCards: []model.Card{{Value: "A", Code: "AS", Suit: "SPADES"}}}
+	id := deck.ID.String()
+
+	t.Run("Creating and fetching a deck", func(t *testing.T) {
+		assert.Nil(t, s.Create(deck), "We expected the deck to be created")
+		assert.Equal(t, store.ErrDeckExists, s.Create(deck), "We expected a duplicate ID to be rejected")
+
+		got, err := s.Get(id)
+		assert.Nil(t, err, "We expected the deck to be found")
+		assert.Equal(t, deck.ID, got.ID, fmt.Sprintf("We expected deck %s but got %s", deck.ID, got.ID))
+	})
+
+	t.Run("Failed update leaves the deck untouched", func(t *testing.T) {
+		_, err := s.Update(id, func(d *model.Deck) error {
+			d.PlayingCards = nil
+			return fmt.Errorf("failed")
+		})
+		assert.NotNil(t, err, "We expected the update error to be returned")
+
+		got, _ := s.Get(id)
+		assert.Len(t, got.PlayingCards, 1, "We expected the deck to keep its cards")
+		assert.Equal(t, 0, got.Version, fmt.Sprintf("We expected the version to stay at 0 but found %d", got.Version))
+	})
+
+	t.Run("Successful update increases the version", func(t *testing.T) {
+		updated, err := s.Update(id, func(d *model.Deck) error { return nil })
+		assert.Nil(t, err, "We expected the update to succeed")
+		assert.Equal(t, 1, updated.Version, fmt.Sprintf("We expected version 1 but found %d", updated.Version))
+	})
+
+	t.Run("Appending to the audit trail keeps the version", func(t *testing.T) {
+		audited, err := s.AppendAudit(id, func(d model.Deck) (model.DeckEvent, error) {
+			return model.DeckEvent{Action: model.DeckActionPeek}, nil
+		})
+		assert.Nil(t, err, "We expected the event to be recorded")
+		assert.Len(t, audited.AuditTrail, 1, "We expected the event in the audit trail")
+		assert.Equal(t, 1, audited.Version, fmt.Sprintf("We expected the version to stay at 1 but found %d", audited.Version))
+
+		_, err = s.AppendAudit(id, func(d model.Deck) (model.DeckEvent, error) {
+			return model.DeckEvent{}, fmt.Errorf("failed")
+		})
+		assert.NotNil(t, err, "We expected the error to be returned")
+
+		got, _ := s.Get(id)
+		assert.Len(t, got.AuditTrail, 1, "We expected a failed call to record nothing")
+	})
+
+	t.Run("Using the deck extends its idle TTL", func(t *testing.T) {
+		*now = now.Add(50 * time.Minute)
+		_, err := s.Update(id, func(d *model.Deck) error {
+			d.DeckLastUsed = *now
+			return nil
+		})
+		assert.Nil(t, err, "We expected the deck to be updated")
+
+		*now = now.Add(50 * time.Minute)
+		_, err = s.Get(id)
+		assert.Nil(t, err, "We expected the deck to be active")
+	})
+
+	t.Run("Expired deck is gone during the grace period and then not found", func(t *testing.T) {
+		*now = now.Add(time.Hour)
+		assert.Equal(t, 1, s.Sweep(), "We expected the sweep to remove one deck")
+
+		_, err := s.Get(id)
+		assert.Equal(t, store.ErrDeckExpired, err, "We expected the deck to be reported as expired")
+
+		*now = now.Add(10 * time.Minute)
+		s.Sweep()
+
+		_, err = s.Get(id)
+		assert.Equal(t, store.ErrDeckNotFound, err, "We expected the deck to be forgotten after the grace period")
+	})
+}
+
+func TestCreateWithLimit(t *testing.T) {
+	s, now := newTestStore(store.DefaultExpiryPolicy)
+
+	var created int32
+	var wg sync.WaitGroup
+
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			if s.CreateWithLimit(model.Deck{ID: uuid.New(), CreatedBy: "client-1", CreatedAt: *now}, 10) == nil {
+				atomic.AddInt32(&created, 1)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	t.Run("Concurrent creations never exceed the limit", func(t *testing.T) {
+		assert.Equal(t, int32(10), created, fmt.Sprintf("We expected 10 decks to be created but got %d", created))
+
+		active, _ := s.Count(store.DeckQuery{CreatedBy: "client-1"})
+		assert.Equal(t, 10, active, fmt.Sprintf("We expected 10 active decks but found %d", active))
+	})
+
+	t.Run("Rejecting decks over the limit", func(t *testing.T) {
+		err := s.CreateWithLimit(model.Deck{ID: uuid.New(), CreatedBy: "client-1", CreatedAt: *now}, 10)
+		assert.Equal(t, store.ErrQuotaExceeded, err, "We expected the quota error")
+	})
+
+	t.Run("Counting the decks of each client separately", func(t *testing.T) {
+		err := s.CreateWithLimit(model.Deck{ID: uuid.New(), CreatedBy: "client-2", CreatedAt: *now}, 10)
+		assert.Nil(t, err, "We expected another client to create a deck")
+	})
+
+	t.Run("Disabling the limit", func(t *testing.T) {
+		err := s.CreateWithLimit(model.Deck{ID: uuid.New(), CreatedBy: "client-1", CreatedAt: *now}, 0)
+		assert.Nil(t, err, "We expected no limit with a zero max")
+	})
+}
+
+func TestOnExpire(t *testing.T) {
+	s, now := newTestStore(store.ExpiryPolicy{IdleTTL: time.Hour, GracePeriod: 10 * time.Minute})
+
+	expired := []uuid.UUID{}
+	s.OnExpire = func(deck model.Deck) { expired = append(expired, deck.ID) }
+
+	swept := model.Deck{ID: uuid.New(), CreatedAt: *now}
+	looked := model.Deck{ID: uuid.New(), CreatedAt: *now}
+	s.Create(swept)
+	s.Create(looked)
+
+	*now = now.Add(time.Hour)
+
+	t.Run("Reporting the decks expired on lookup", func(t *testing.T) {
+		_, err := s.Get(looked.ID.String())
+		assert.Equal(t, store.ErrDeckExpired, err, "We expected the deck to be expired")
+		assert.Equal(t, []uuid.UUID{looked.ID}, expired, "We expected the expired deck to be reported")
+	})
+
+	t.Run("Reporting the swept decks", func(t *testing.T) {
+		s.Sweep()
+		assert.Equal(t, []uuid.UUID{looked.ID, swept.ID}, expired, "We expected the swept deck to be reported once")
+	})
+}
+
+// countingStore records how many times the sweeper called Sweep.
+type countingStore struct {
+	store.DeckStore
+	sweeps int32
+}
+
+func (s *countingStore) Sweep() int {
+	atomic.AddInt32(&s.sweeps, 1)
+	return 0
+}
+
+func TestSweeper(t *testing.T) {
+	s := &countingStore{DeckStore: store.NewMemoryStore(store.DefaultExpiryPolicy)}
+
+	sweeper := store.NewSweeper(s, time.Millisecond)
+	sweeper.Start()
+	assert.True(t, sweeper.Running(), "We expected the sweeper to be running")
+
+	assert.Eventually(t, func() bool { return atomic.LoadInt32(&s.sweeps) > 1 }, time.Second, 5*time.Millisecond, "We expected the sweeper to sweep the store periodically")
+
+	sweeper.Stop()
+	assert.False(t, sweeper.Running(), "We expected the sweeper to be stopped")
+
+	sweeps := atomic.LoadInt32(&s.sweeps)
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(t, sweeps, atomic.LoadInt32(&s.sweeps), "We expected no sweep after the sweeper is stopped")
+
+	// Stopping twice must not block or panic
+	sweeper.Stop()
+}
+
+func TestListDecks(t *testing.T) {
+	s, now := newTestStore(store.DefaultExpiryPolicy)
+
+	// Generating five decks a minute apart, the last two are exhausted and belong to another game
+	for i := 0; i < 5; i++ {
+		deck := model.Deck{ID: uuid.New(), GameID: "poker", CreatedAt: now.Add(time.Duration(i) * time.Minute), CardsRemaining: 52}
+		if i >= 3 {
+			deck.GameID = "bridge"
+			deck.CardsRemaining = 0
+		}
+		s.Create(deck)
+	}
+
+	t.Run("Filtering decks by game and status", func(t *testing.T) {
+		page, err := s.List(store.DeckQuery{GameID: "poker"})
+		assert.Nil(t, err, "We expected the decks to be listed")
+		assert.Len(t, page.Decks, 3, "We expected three poker decks")
+
+		page, _ = s.List(store.DeckQuery{Status: store.DeckStatusExhausted})
+		assert.Len(t, page.Decks, 2, "We expected two exhausted decks")
+	})
+
+	t.Run("Paginating decks with a cursor", func(t *testing.T) {
+		seen := []model.Deck{}
+		query := store.DeckQuery{Limit: 2, Descending: true}
+
+		for {
+			page, err := s.List(query)
+			assert.Nil(t, err, "We expected the decks to be listed")
+			seen = append(seen, page.Decks...)
+
+			if page.NextCursor == "" {
+				break
+			}
+			query.Cursor = page.NextCursor
+		}
+
+		assert.Len(t, seen, 5, "We expected every deck exactly once across the pages")
+
+		for i := 1; i < len(seen); i++ {
+			assert.True(t, seen[i-1].CreatedAt.After(seen[i].CreatedAt), "We expected the decks in descending creation order")
+		}
+	})
+
+	t.Run("Listing with an invalid cursor", func(t *testing.T) {
+		_, err := s.List(store.DeckQuery{Cursor: "not-a-cursor"})
+		assert.Equal(t, store.ErrInvalidCursor, err, "We expected the cursor to be rejected")
+	})
+
+	t.Run("Deleting decks by game", func(t *testing.T) {
+		removed, err := s.DeleteByGame("bridge", "")
+		assert.Nil(t, err, "We expected the decks to be deleted")
+		assert.Len(t, removed, 2, fmt.Sprintf("We expected 2 decks to be deleted but got %d", len(removed)))
+
+		page, _ := s.List(store.DeckQuery{})
+		assert.Len(t, page.Decks, 3, "We expected three decks to remain")
+	})
+
+	t.Run("Deleting decks by game skips expired decks", func(t *testing.T) {
+		s, now := newTestStore(store.ExpiryPolicy{IdleTTL: time.Hour, GracePeriod: 10 * time.Minute})
+
+		stale := model.Deck{ID: uuid.New(), GameID: "rummy", CreatedAt: *now}
+		s.Create(stale)
+		*now = now.Add(30 * time.Minute)
+
+		fresh := model.Deck{ID: uuid.New(), GameID: "rummy", CreatedAt: *now}
+		s.Create(fresh)
+
+		// Expiring the first deck without sweeping it
+		*now = now.Add(45 * time.Minute)
+
+		removed, err := s.DeleteByGame("rummy", "")
+
+		// Verifying only the active deck is counted
+		assert.Nil(t, err, "We expected the decks to be deleted")
+		if assert.Len(t, removed, 1, fmt.Sprintf("We expected 1 deck to be deleted but got %d", len(removed))) {
+			assert.Equal(t, fresh.ID, removed[0].ID, "We expected the active deck to be returned")
+		}
+
+		_, err = s.Get(stale.ID.String())
+		assert.Equal(t, store.ErrDeckExpired, err, "We expected the expired deck to still be reported as expired")
+
+		_, err = s.Get(fresh.ID.String())
+		assert.Equal(t, store.ErrDeckNotFound, err, "We expected the active deck to be deleted")
+	})
+}
+
+func TestSnapshot(t *testing.T) {
+	s, now := newTestStore(store.ExpiryPolicy{IdleTTL: time.Hour, GracePeriod: 10 * time.Minute})
+
+	deck := model.Deck{
+		ID:           uuid.New(),
+		CreatedAt:    *now,
+		CreatedBy:    "key:abc",
+		Version:      3,
+		PlayingCards: []model.Card{{Value: "A", Code: "AS", Suit: "SPADES"}},
+		Hands:        map[string][]model.Card{"alice": {{Value: "K", Code: "KH", Suit: "HEARTS"}}},
+		Piles:        map[string][]model.Card{"discard": {{Value: "2", Code: "2C", Suit: "CLUBS"}}},
+	}
+	expiring := model.Deck{ID: uuid.New(), CreatedAt: *now}
+
+	s.Create(deck)
+	s.Create(expiring)
+
+	*now = now.Add(59 * time.Minute)
+	s.Update(deck.ID.String(), func(deck *model.Deck) error {
+		deck.DeckLastUsed = *now
+		return nil
+	})
+	*now = now.Add(time.Minute)
+	s.Sweep()
+
+	path := filepath.Join(t.TempDir(), "decks.snapshot")
+
+	t.Run("Restoring the decks of a snapshot", func(t *testing.T) {
+		assert.Nil(t, store.SaveSnapshot(s, path), "We expected the snapshot to be saved")
+
+		restored, restoredNow := newTestStore(store.ExpiryPolicy{IdleTTL: time.Hour, GracePeriod: 10 * time.Minute})
+		*restoredNow = *now
+		assert.Nil(t, store.LoadSnapshot(restored, path), "We expected the snapshot to be loaded")
+
+		got, err := restored.Get(deck.ID.String())
+		assert.Nil(t, err, "We expected the deck to be restored")
+		// Verifying the fields never sent to clients survive too
+		assert.Equal(t, "key:abc", got.CreatedBy, fmt.Sprintf("We expected the creator key:abc but got %s", got.CreatedBy))
+		assert.Equal(t, 4, got.Version, fmt.Sprintf("We expected version 4 but got %d", got.Version))
+		assert.Equal(t, deck.Hands, got.Hands, "We expected the hands to be restored")
+		assert.Equal(t, deck.Piles, got.Piles, "We expected the piles to be restored")
+
+		_, err = restored.Get(expiring.ID.String())
+		assert.Equal(t, store.ErrDeckExpired, err, "We expected the expired deck to stay in its grace period")
+	})
+
+	t.Run("Starting empty without a snapshot", func(t *testing.T) {
+		empty, _ := newTestStore(store.ExpiryPolicy{})
+
+		assert.Nil(t, store.LoadSnapshot(empty, filepath.Join(t.TempDir(), "missing")), "We expected a missing snapshot to be ignored")
+		count, _ := empty.Count(store.DeckQuery{})
+		assert.Equal(t, 0, count, fmt.Sprintf("We expected no deck but found %d", count))
+	})
+
+	t.Run("Rejecting a corrupted snapshot", func(t *testing.T) {
+		corrupted := filepath.Join(t.TempDir(), "corrupted")
+		os.WriteFile(corrupted, []byte("not a snapshot"), 0o600)
+
+		assert.NotNil(t, store.LoadSnapshot(s, corrupted), "We expected an error for a corrupted snapshot")
+	})
+}
@@ -0,0 +1,211 @@
+package tracing_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/varadekd/card-game/config"
+	"github.com/varadekd/card-game/helper"
+	"github.com/varadekd/card-game/logging"
+	"github.com/varadekd/card-game/tracing"
+	"github.com/varadekd/card-game/util"
+	collectortrace "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// The W3C trace context sent by the client in the tests.
+const (
+	traceID     = "4bf92f3577b34da6a3ce929d0e0e4736"
+	parentID    = "00f067aa0ba902b7"
+	traceParent = "00-" + traceID + "-" + parentID + "-01"
+)
+
+// collector is an in-process stand-in for an OTLP/HTTP collector, it keeps the spans it receives.
+type collector struct {
+	server *httptest.Server
+
+	mu       sync.Mutex
+	spans    []*tracepb.Span
+	services []string
+}
+
+func newCollector(t *testing.T) *collector {
+	c := &collector{}
+
+	c.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/traces" {
+			t.Errorf("We expected the spans to be sent to /v1/traces but got %s", r.URL.Path)
+		}
+
+		body, _ := io.ReadAll(r.Body)
+		request := &collectortrace.ExportTraceServiceRequest{}
+
+		if err := proto.Unmarshal(body, request); err != nil {
+			t.Errorf("Unable to decode the exported spans: %v", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		for _, resourceSpans := range request.ResourceSpans {
+			for _, attr := range resourceSpans.Resource.Attributes {
+				if attr.Key == "service.name" {
+					c.services = append(c.services, attr.Value.GetStringValue())
+				}
+			}
+
+			for _, scopeSpans := range resourceSpans.ScopeSpans {
+				c.spans = append(c.spans, scopeSpans.Spans...)
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/x-protobuf")
+		body, _ = proto.Marshal(&collectortrace.ExportTraceServiceResponse{})
+		w.Write(body)
+	}))
+
+	t.Cleanup(c.server.Close)
+	return c
+}
+
+// span returns the first span received with the name, nil when there is none.
+func (c *collector) span(name string) *tracepb.Span {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, span := range c.spans {
+		if span.Name == name {
+			return span
+		}
+	}
+
+	return nil
+}
+
+func TestOTLPExporter(t *testing.T) {
+	stub := newCollector(t)
+
+	shutdown, err := tracing.Setup(context.Background(), tracing.Options{Exporter: tracing.ExporterOTLP, Endpoint: stub.server.URL})
+
+	if err != nil {
+		t.Fatalf("Unable to set up the tracing: %v", err)
+	}
+
+	tracingRouter := config.SetupRouter()
+
+	// The cards file is written again so the first deck loads the catalogue
+	helper.GenerateDefaultDeck()
+
+	payloadString, _ := json.Marshal(map[string]any{"shuffle": true, "cards": []string{"AS", "2S", "3S"}})
+	res, status := util.RequestWithHeadersAndDecodeResponse("POST", "/deck/new", payloadString, map[string]string{"traceparent": traceParent}, t, tracingRouter)
+
+	// Verifying the deck was created
+	assert.Equal(t, http.StatusCreated, status, fmt.Sprintf("We expected http status %d but got %d. Err: %s", http.StatusCreated, status, res.Error))
+
+	// Flushing the spans to the collector
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatalf("Unable to flush the spans: %v", err)
+	}
+
+	t.Run("Continuing the trace of the client", func(t *testing.T) {
+		server := stub.span("POST /deck/new")
+
+		if server == nil {
+			t.Fatalf("We expected a span for the handler")
+		}
+
+		assert.Equal(t, traceID, hex.EncodeToString(server.TraceId), "We expected the trace ID of the traceparent header")
+		assert.Equal(t, parentID, hex.EncodeToString(server.ParentSpanId), "We expected the span of the client as parent")
+		assert.Equal(t, tracepb.Span_SPAN_KIND_SERVER, server.Kind, "We expected a server span")
+	})
+
+	t.Run("Tracing the card file, the shuffle and the store", func(t *testing.T) {
+		server := stub.span("POST /deck/new")
+
+		if server == nil {
+			t.Fatalf("We expected a span for the handler")
+		}
+
+		for _, name := range []string{"cards.loadCatalogue", "cards.shuffle", "store.createWithLimit"} {
+			span := stub.span(name)
+
+			if span == nil {
+				t.Errorf("We expected a span named %s", name)
+				continue
+			}
+
+			assert.Equal(t, server.SpanId, span.ParentSpanId, fmt.Sprintf("We expected %s to be a child of the handler span", name))
+			assert.Equal(t, server.TraceId, span.TraceId, fmt.Sprintf("We expected %s to be in the trace of the request", name))
+		}
+	})
+
+	t.Run("Naming the service", func(t *testing.T) {
+		assert.Contains(t, stub.services, tracing.ServiceName, "We expected the service name in the resource")
+	})
+}
+
+func TestStdoutExporter(t *testing.T) {
+	var spans bytes.Buffer
+
+	shutdown, err := tracing.Setup(context.Background(), tracing.Options{Exporter: tracing.ExporterStdout, Stdout: &spans})
+
+	if err != nil {
+		t.Fatalf("Unable to set up the tracing: %v", err)
+	}
+
+	tracingRouter := config.SetupRouter()
+
+	w := httptest.NewRecorder()
+	tracingRouter.ServeHTTP(w, httptest.NewRequest("GET", "/deck/unknown", nil))
+
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatalf("Unable to flush the spans: %v", err)
+	}
+
+	// Verifying the span was written as JSON with its route and status
+	assert.True(t, strings.Contains(spans.String(), `"Name":"GET /deck/:id"`), fmt.Sprintf("We expected the span of the handler, got %s", spans.String()))
+	assert.True(t, strings.Contains(spans.String(), `"Key":"http.response.status_code"`), "We expected the status of the response")
+}
+
+func TestPropagation(t *testing.T) {
+	var logs bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(logging.New(&logs, slog.LevelInfo))
+	defer slog.SetDefault(previous)
+
+	shutdown, err := tracing.Setup(context.Background(), tracing.Options{Exporter: tracing.ExporterNone})
+
+	if err != nil {
+		t.Fatalf("Unable to set up the tracing: %v", err)
+	}
+
+	defer shutdown(context.Background())
+
+	tracingRouter := config.SetupRouter()
+	logs.Reset()
+
+	req := httptest.NewRequest("GET", "/ping", nil)
+	req.Header.Set("traceparent", traceParent)
+	w := httptest.NewRecorder()
+	tracingRouter.ServeHTTP(w, req)
+
+	entry := map[string]interface{}{}
+	json.Unmarshal(bytes.TrimSpace(logs.Bytes()), &entry)
+
+	// Verifying the trace ID of the client is logged even when no span is exported
+	assert.Equal(t, traceID, entry["trace_id"], fmt.Sprintf("We expected the trace ID of the client in the access log, got %s", logs.String()))
+}
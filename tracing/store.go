@@ -0,0 +1,115 @@
+package tracing
+
+import (
+	"context"
+
+	"github.com/varadekd/card-game/model"
+	"github.com/varadekd/card-game/store"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Attributes set on the spans of the deck operations.
+const (
+	DeckIDKey    = attribute.Key("deck.id")
+	GameIDKey    = attribute.Key("deck.game_id")
+	CardCountKey = attribute.Key("deck.cards")
+	OperationKey = attribute.Key("store.operation")
+)
+
+// tracedStore starts a span for every operation of the store it wraps, as a child of the
+// span of the request held by ctx.
+type tracedStore struct {
+	store store.DeckStore
+	ctx   context.Context
+}
+
+// Store returns a view of s starting a span named store.<operation> for every operation,
+// the spans are children of the span held by ctx. It is meant to live as long as a request.
+func Store(ctx context.Context, s store.DeckStore) store.DeckStore {
+	return &tracedStore{store: s, ctx: ctx}
+}
+
+func (s *tracedStore) start(operation string, attrs ...attribute.KeyValue) trace.Span {
+	_, span := Start(s.ctx, "store."+operation, append(attrs, OperationKey.String(operation))...)
+	return span
+}
+
+// end records err on the span, if any, and ends it.
+func end(span trace.Span, err error) {
+	if err != nil {
+		Fail(span, err)
+	}
+
+	span.End()
+}
+
+func (s *tracedStore) Create(deck model.Deck) (err error) {
+	span := s.start("create", DeckIDKey.String(deck.ID.String()), GameIDKey.String(deck.GameID))
+	defer func() { end(span, err) }()
+
+	return s.store.Create(deck)
+}
+
+func (s *tracedStore) CreateWithLimit(deck model.Deck, max int) (err error) {
+	span := s.start("createWithLimit", DeckIDKey.String(deck.ID.String()), GameIDKey.String(deck.GameID))
+	defer func() { end(span, err) }()
+
+	return s.store.CreateWithLimit(deck, max)
+}
+
+func (s *tracedStore) Get(id string) (deck model.Deck, err error) {
+	span := s.start("get", DeckIDKey.String(id))
+	defer func() { end(span, err) }()
+
+	return s.store.Get(id)
+}
+
+func (s *tracedStore) Update(id string, fn func(deck *model.Deck) error) (deck model.Deck, err error) {
+	span := s.start("update", DeckIDKey.String(id))
+	defer func() { end(span, err) }()
+
+	return s.store.Update(id, fn)
+}
+
+func (s *tracedStore) AppendAudit(id string, fn func(deck model.Deck) (model.DeckEvent, error)) (deck model.Deck, err error) {
+	span := s.start("appendAudit", DeckIDKey.String(id))
+	defer func() { end(span, err) }()
+
+	return s.store.AppendAudit(id, fn)
+}
+
+func (s *tracedStore) Delete(id string) (err error) {
+	span := s.start("delete", DeckIDKey.String(id))
+	defer func() { end(span, err) }()
+
+	return s.store.Delete(id)
+}
+
+func (s *tracedStore) DeleteByGame(gameID, ownerID string) (deleted []model.Deck, err error) {
+	span := s.start("deleteByGame", GameIDKey.String(gameID))
+	defer func() { end(span, err) }()
+
+	return s.store.DeleteByGame(gameID, ownerID)
+}
+
+func (s *tracedStore) List(query store.DeckQuery) (page store.DeckPage, err error) {
+	span := s.start("list", GameIDKey.String(query.GameID))
+	defer func() { end(span, err) }()
+
+	return s.store.List(query)
+}
+
+func (s *tracedStore) Count(query store.DeckQuery) (count int, err error) {
+	span := s.start("count")
+	defer func() { end(span, err) }()
+
+	return s.store.Count(query)
+}
+
+func (s *tracedStore) Sweep() int {
+	span := s.start("sweep")
+	defer span.End()
+
+	return s.store.Sweep()
+}
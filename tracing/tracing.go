@@ -0,0 +1,155 @@
+// The tracing package sets up OpenTelemetry tracing. Spans are started from the context of
+// the request so they end up in the trace of the client, which is read from the W3C
+// traceparent and tracestate headers.
+
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+
+	"github.com/varadekd/card-game/buildinfo"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// ScopeName is the instrumentation scope of the spans started by the application.
+const ScopeName = "github.com/varadekd/card-game"
+
+// ServiceName is the service.name resource attribute of the exported spans.
+const ServiceName = "card-game"
+
+// Exporters supported by Setup.
+const (
+	ExporterNone   = "none"
+	ExporterStdout = "stdout"
+	ExporterOTLP   = "otlp"
+)
+
+// Propagator reads and writes the W3C trace context and baggage headers.
+var Propagator = propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})
+
+// Options selects where the spans are exported. Endpoint is the base URL of an OTLP/HTTP
+// collector such as http://localhost:4318, the spans are sent to its /v1/traces path.
+// Stdout receives the spans of the stdout exporter, one JSON object per line, it defaults
+// to os.Stdout.
+type Options struct {
+	Exporter string
+	Endpoint string
+	Stdout   io.Writer
+}
+
+// Tracer returns the tracer of the application. It uses the provider installed by Setup,
+// even when it was obtained before Setup ran.
+func Tracer() trace.Tracer {
+	return otel.Tracer(ScopeName, trace.WithInstrumentationVersion(buildinfo.Version))
+}
+
+// Start starts a span as a child of the span held by ctx, the returned context holds the new span.
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return Tracer().Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// Fail records err on the span and marks the span as failed.
+func Fail(span trace.Span, err error) {
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
+
+// Setup installs the global tracer provider exporting the spans as set by options, and the
+// W3C propagator. No span is exported with the none exporter, the trace context of the
+// requests is still propagated. The returned function flushes the spans left and stops
+// the exporter, it should be called on shutdown.
+func Setup(ctx context.Context, options Options) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(Propagator)
+
+	exporter, err := newExporter(ctx, options)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if exporter == nil {
+		otel.SetTracerProvider(noop.NewTracerProvider())
+		return func(context.Context) error { return nil }, nil
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(newResource()),
+		// The decision of the client is kept so a trace is never recorded partially
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.AlwaysSample())),
+	)
+
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+func newExporter(ctx context.Context, options Options) (sdktrace.SpanExporter, error) {
+	switch options.Exporter {
+	case "", ExporterNone:
+		return nil, nil
+	case ExporterStdout:
+		if options.Stdout == nil {
+			return stdouttrace.New()
+		}
+
+		return stdouttrace.New(stdouttrace.WithWriter(options.Stdout))
+	case ExporterOTLP:
+		endpoint, err := ParseEndpoint(options.Endpoint)
+
+		if err != nil {
+			return nil, err
+		}
+
+		exporterOptions := []otlptracehttp.Option{
+			otlptracehttp.WithEndpoint(endpoint.Host),
+			otlptracehttp.WithURLPath(strings.TrimSuffix(endpoint.Path, "/") + "/v1/traces"),
+		}
+
+		if endpoint.Scheme == "http" {
+			exporterOptions = append(exporterOptions, otlptracehttp.WithInsecure())
+		}
+
+		return otlptracehttp.New(ctx, exporterOptions...)
+	}
+
+	return nil, fmt.Errorf("unknown tracing exporter %q, it should be one of none, stdout or otlp", options.Exporter)
+}
+
+// ParseEndpoint returns the URL of an OTLP/HTTP collector, it must be an http or https URL.
+func ParseEndpoint(endpoint string) (*url.URL, error) {
+	parsed, err := url.Parse(endpoint)
+
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return nil, fmt.Errorf("the OTLP endpoint should be an http or https URL like http://localhost:4318 but found %q", endpoint)
+	}
+
+	return parsed, nil
+}
+
+func newResource() *resource.Resource {
+	// The attributes of the default resource, such as the SDK version, are kept
+	merged, err := resource.Merge(resource.Default(), resource.NewSchemaless(
+		semconv.ServiceName(ServiceName),
+		semconv.ServiceVersion(buildinfo.Version),
+	))
+
+	if err != nil {
+		return resource.Default()
+	}
+
+	return merged
+}
@@ -12,9 +12,18 @@ import (
 )
 
 func RequestAndDecodeResponse(method, api string, payload []byte, t *testing.T, router *gin.Engine) (helper.ResponseJSON, int) {
+	return RequestWithHeadersAndDecodeResponse(method, api, payload, nil, t, router)
+}
+
+// RequestWithHeadersAndDecodeResponse works like RequestAndDecodeResponse and sets the headers on the request.
+func RequestWithHeadersAndDecodeResponse(method, api string, payload []byte, headers map[string]string, t *testing.T, router *gin.Engine) (helper.ResponseJSON, int) {
 	w := httptest.NewRecorder()
 	req, _ := http.NewRequest(method, api, bytes.NewBuffer(payload))
 
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
 	router.ServeHTTP(w, req)
 
 	res := helper.ResponseJSON{}